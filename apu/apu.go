@@ -43,16 +43,37 @@ const (
 // Audio settings for SDL.
 
 const (
-	SamplingRate    = 22050 // How many sample frames to send per second.
-	FramesPerBuffer = 1024  // Number of sample frames fitting the audio buffer.
-	Volume          = 63    // 25% volume for unsigned 8-bit samples.
+	DefaultSamplingRate = 22050 // How many sample frames to send per second.
+	FramesPerBuffer     = 1024  // Number of sample frames fitting the audio buffer.
+	MinFramesPerBuffer  = 256   // Smaller buffers underrun all the time.
+	MaxFramesPerBuffer  = 8192  // Larger buffers add too much lag.
+	Volume              = 63    // 25% volume for unsigned 8-bit samples.
 )
 
+// BufferFrames returns the audio buffer size to use for the requested number
+// of sample frames: the next power of two, within MinFramesPerBuffer and
+// MaxFramesPerBuffer. Zero means FramesPerBuffer.
+func BufferFrames(requested uint) uint16 {
+	if requested == 0 {
+		return FramesPerBuffer
+	}
+	frames := uint(MinFramesPerBuffer)
+	for frames < requested && frames < MaxFramesPerBuffer {
+		frames <<= 1
+	}
+	if frames != requested {
+		log.Warningf("audio buffer size %d adjusted to %d sample frames",
+			requested, frames)
+	}
+	return uint16(frames)
+}
+
 // GameBoyRate is the main CPU frequence to be used in so many divisions.
 const GameBoyRate = 4 * 1024 * 1024 // 4194304Hz or 4MiHz
 
-// SoundOutRate represents CPU cycles to wait before producing one sample frame.
-const SoundOutRate = GameBoyRate / SamplingRate
+// FrameTicks is the number of machine ticks in a full frame (154 lines of 456
+// dots), like gameboy.FrameTicks, for about 59.7 frames per second.
+const FrameTicks = 154 * 456
 
 // Audio Control register bits.
 const (
@@ -80,11 +101,25 @@ type APU struct {
 	Square2 SquareWave
 	Wave    WaveTable
 	Noise   Noise
+
+	// SamplingRate is the number of sample frames produced per emulated
+	// second, which should match the host audio device.
+	SamplingRate uint
+
+	// The Game Boy frequency is not a multiple of the sampling rate, so we
+	// accumulate the remainder to produce exactly SamplingRate samples every
+	// GameBoyRate cycles.
+	remainder uint
+	elapsed   uint // Cycles since the last sample.
 }
 
-// New APU instance. So many registers.
-func New() *APU {
-	a := APU{Wave: *NewWave()}
+// New APU instance producing samples at the given rate (or at
+// DefaultSamplingRate if zero). So many registers.
+func New(samplingRate uint) *APU {
+	if samplingRate == 0 {
+		samplingRate = DefaultSamplingRate
+	}
+	a := APU{Wave: *NewWave(), SamplingRate: samplingRate}
 
 	a.Registers = memory.Registers{
 		AddrNR10: &a.Square1.NRx0,
@@ -110,6 +145,32 @@ func New() *APU {
 	return &a
 }
 
+// ForceChannels turns all channels on (or back to normal) regardless of
+// triggers, length and DAC settings, to check waveform generation without a
+// ROM driving the registers. Forced channels that would be silent because of
+// their volume setting play at full volume instead.
+func (a *APU) ForceChannels(on bool) {
+	a.Square1.forced = on
+	a.Square2.forced = on
+	a.Wave.forced = on
+	a.Noise.forced = on
+
+	// The noise shift register only gets initialized on trigger, and would
+	// stay silent at zero.
+	if on && a.Noise.register == 0 {
+		a.Noise.register = 0x7fff
+	}
+}
+
+// Returns the volume to play a channel at, full volume if it was forced on but
+// its volume is zero.
+func forcedVolume(volume uint8, forced bool) uint8 {
+	if forced && volume == 0 {
+		return 15
+	}
+	return volume
+}
+
 // Overrides to Read/Write methods because of masks and special cases.
 func (a *APU) Write(addr uint16, value uint8) {
 	// Do write the value anyway. TODO: masks. Ugh.
@@ -129,17 +190,72 @@ func (a *APU) Write(addr uint16, value uint8) {
 	}
 }
 
-// Tick advances the state machine of all signal generators to produce a single
-// stereo sample for the sound card. Note that the number of internal cycles happening on each signal generator depends on the output frequency.
-func (a *APU) Tick() (left, right uint8) {
+// Tick is called on every machine cycle and returns true along with a stereo
+// sample whenever it is time to send one to the sound card. Signal generators
+// are then advanced by however many cycles elapsed since the previous sample.
+func (a *APU) Tick() (left, right uint8, play bool) {
+	a.elapsed++
+	if a.remainder += a.SamplingRate; a.remainder < GameBoyRate {
+		return
+	}
+	a.remainder -= GameBoyRate
+	left, right = a.sample()
+	return left, right, true
+}
+
+// TickN advances the APU n ticks, with the same result as calling Tick n
+// times. Samples produced along the way are appended to the given buffer as
+// interleaved left and right values, which is returned. Ticks between two
+// samples are counted in one go.
+func (a *APU) TickN(n int, samples []uint8) []uint8 {
+	for n > 0 {
+		// Ticks until the remainder reaches GameBoyRate again.
+		next := int((GameBoyRate - a.remainder + a.SamplingRate - 1) / a.SamplingRate)
+		if next > n {
+			a.remainder += uint(n) * a.SamplingRate
+			a.elapsed += uint(n)
+			break
+		}
+		a.remainder += uint(next)*a.SamplingRate - GameBoyRate
+		a.elapsed += uint(next)
+		n -= next
+
+		left, right := a.sample()
+		samples = append(samples, left, right)
+	}
+	return samples
+}
+
+// RunFrame advances the APU exactly one frame's worth of ticks (see FrameTicks)
+// and returns the number of samples produced, which should stay within
+// rounding of SamplingRate*FrameTicks/GameBoyRate (about SamplingRate/59.7)
+// every frame. Samples themselves are dropped.
+func (a *APU) RunFrame() (samples int) {
+	for i := 0; i < FrameTicks; i++ {
+		if _, _, play := a.Tick(); play {
+			samples++
+		}
+	}
+	return
+}
+
+// Produces a sample from all signal generators, advanced by the number of
+// cycles since the last one.
+func (a *APU) sample() (left, right uint8) {
 	// Advance all signal generators a step. Right now we only have two but
 	// if we were to implement all four, we'd actually mix all their outputs
 	// together here (with various per-generator parameters to account for).
+	cycles := a.elapsed
+	a.elapsed = 0
 
 	// TODO: mix signals here according to the relevant registers.
 	// Because we're returning unsigned ints, the silence point is at 128.
-	left = 128 + a.Square1.Tick() - a.Square2.Tick() + a.Wave.Tick()// - a.Noise.Tick()
-	right = left
+	left = 128 + a.Square1.Tick(cycles) - a.Square2.Tick(cycles) + a.Wave.Tick(cycles) // - a.Noise.Tick(cycles)
 
+	// Noise isn't mixed yet, except when forced on so that it can be heard.
+	if a.Noise.forced {
+		left -= a.Noise.Tick(cycles)
+	}
+	right = left
 	return
 }
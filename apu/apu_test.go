@@ -0,0 +1,219 @@
+package apu
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSamplingRate(t *testing.T) {
+	for _, rate := range []uint{44100, 48000} {
+		a := New(rate)
+
+		// 64Hz wave alternating between full volume and silence on each of its
+		// 32 steps per period, i.e. 2048 changes per second.
+		for i := range a.Wave.Pattern.Bytes {
+			a.Wave.Pattern.Bytes[i] = 0xf0
+		}
+		a.Write(AddrNR30, NR30SoundOn)
+		a.Write(AddrNR32, 0x20)
+		a.Write(AddrNR33, 0x00)
+		a.Write(AddrNR34, NRx4RestartSound|0x04) // 2048-1024 → 64Hz
+
+		samples, changes := 0, 0
+		var last uint8
+		for i := 0; i < GameBoyRate; i++ {
+			left, _, play := a.Tick()
+			if !play {
+				continue
+			}
+			if samples > 0 && left != last {
+				changes++
+			}
+			last = left
+			samples++
+		}
+
+		if samples != int(rate) {
+			t.Errorf("%d samples in one second at %dHz", samples, rate)
+		}
+		if changes < 2046 || changes > 2048 {
+			t.Errorf("wave changed %d times in one second at %dHz, want 2048", changes, rate)
+		}
+	}
+}
+
+func TestRunFrame(t *testing.T) {
+	const rate = 44100
+	a := New(rate)
+
+	// 44100*70224/4194304 is about 738.35 samples per frame, so frames get
+	// either 738 or 739 samples without drifting away from that average.
+	perFrame := float64(rate) * FrameTicks / GameBoyRate
+	total := 0
+	for frame := 1; frame <= 600; frame++ {
+		samples := a.RunFrame()
+		if samples != int(perFrame) && samples != int(perFrame)+1 {
+			t.Fatalf("frame %d has %d samples, want %d or %d", frame, samples,
+				int(perFrame), int(perFrame)+1)
+		}
+		total += samples
+		if want := int(perFrame * float64(frame)); total != want {
+			t.Fatalf("%d samples after %d frames, want %d", total, frame, want)
+		}
+	}
+
+	// Close enough to the usual rate/59.7 approximation.
+	if average := float64(total) / 600; math.Abs(average-rate/59.7) > 1 {
+		t.Errorf("%.2f samples per frame on average, want about %.2f", average, rate/59.7)
+	}
+}
+
+func TestWaveVolumeChange(t *testing.T) {
+	w := NewWave()
+	for i := range w.Pattern.Bytes {
+		w.Pattern.Bytes[i] = 0xff
+	}
+	w.NRx0 = NR30SoundOn
+	w.NRx2 = 0x20 // 100%
+	w.NRx3 = 0x00
+	w.NRx4 = NRx4RestartSound // 32Hz, i.e. a new sample every 4096 cycles.
+
+	// Wait for the first sample to be read.
+	for i := 0; w.Tick(1) == 0; i++ {
+		if i > 4096 {
+			t.Fatal("wave channel produced no sound")
+		}
+	}
+
+	// Volume changes apply right away, well before the next sample.
+	for _, c := range []struct{ nr32, want uint8 }{
+		{0x40, 0x7}, // 50%
+		{0x60, 0x3}, // 25%
+		{0x00, 0x0}, // Mute
+		{0x20, 0xf}, // 100% again, the sample wasn't lost
+	} {
+		w.NRx2 = c.nr32
+		if sample := w.Tick(1); sample != c.want {
+			t.Errorf("NR32=0x%02x: sample is 0x%x, want 0x%x", c.nr32, sample, c.want)
+		}
+	}
+}
+
+func TestForceChannels(t *testing.T) {
+	// Count distinct samples over a tenth of a second with registers untouched.
+	levels := func(a *APU) map[uint8]bool {
+		seen := make(map[uint8]bool)
+		for i := 0; i < GameBoyRate/10; i++ {
+			if left, _, play := a.Tick(); play {
+				seen[left] = true
+			}
+		}
+		return seen
+	}
+
+	a := New(0)
+	if seen := levels(a); len(seen) != 1 || !seen[128] {
+		t.Errorf("untouched APU produced samples %v, want silence", seen)
+	}
+
+	a.ForceChannels(true)
+	register := a.Noise.register
+	if seen := levels(a); len(seen) < 2 {
+		t.Errorf("forced APU produced samples %v, want a waveform", seen)
+	}
+	if a.Noise.register == register {
+		t.Error("forced noise channel not running")
+	}
+
+	a.ForceChannels(false)
+	if seen := levels(a); len(seen) != 1 || !seen[128] {
+		t.Errorf("APU produced samples %v after forcing off, want silence", seen)
+	}
+}
+
+func TestWaveInterpolation(t *testing.T) {
+	// Largest jump between consecutive outputs of a square-ish wave pattern
+	// alternating between silence and full amplitude.
+	maxStep := func(interpolate bool) (max int) {
+		w := NewWave()
+		for i := range w.Pattern.Bytes {
+			w.Pattern.Bytes[i] = 0x0f
+		}
+		w.Interpolate = interpolate
+		w.NRx0 = NR30SoundOn
+		w.NRx2 = 0x20 // 100%
+		w.NRx3 = 0xd0
+		w.NRx4 = NRx4RestartSound | 0x07 // About 1.4kHz
+
+		last := int(w.Tick(1))
+		for i := 0; i < GameBoyRate/100; i++ {
+			sample := int(w.Tick(1))
+			step := sample - last
+			if step < 0 {
+				step = -step
+			}
+			if step > max {
+				max = step
+			}
+			last = sample
+		}
+		return
+	}
+
+	raw, smooth := maxStep(false), maxStep(true)
+	if raw != 15 {
+		t.Errorf("largest raw step is %d, want 15", raw)
+	}
+	if smooth > 1 {
+		t.Errorf("largest interpolated step is %d, want at most 1", smooth)
+	}
+}
+
+func TestTickN(t *testing.T) {
+	single, batched := New(48000), New(48000)
+	for _, a := range []*APU{single, batched} {
+		a.ForceChannels(true)
+		a.Write(AddrNR13, 0x00)
+		a.Write(AddrNR14, NRx4RestartSound|0x06)
+	}
+
+	// Uneven batch sizes, some shorter than the time between two samples.
+	var expected, samples []uint8
+	for i := 0; i < 2000; i++ {
+		n := 1 + i*37%300
+		for j := 0; j < n; j++ {
+			if left, right, play := single.Tick(); play {
+				expected = append(expected, left, right)
+			}
+		}
+		samples = batched.TickN(n, samples)
+	}
+
+	if len(samples) != len(expected) {
+		t.Fatalf("TickN produced %d values, Tick %d", len(samples), len(expected))
+	}
+	for i := range samples {
+		if samples[i] != expected[i] {
+			t.Fatalf("value %d is %d with TickN, %d with Tick", i, samples[i], expected[i])
+		}
+	}
+}
+
+func BenchmarkTick(b *testing.B) {
+	a := New(48000)
+	a.ForceChannels(true)
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 456; j++ {
+			a.Tick()
+		}
+	}
+}
+
+func BenchmarkTickN(b *testing.B) {
+	a := New(48000)
+	a.ForceChannels(true)
+	samples := make([]uint8, 0, 16)
+	for i := 0; i < b.N; i++ {
+		samples = a.TickN(456, samples[:0])
+	}
+}
@@ -21,6 +21,7 @@ type Noise struct {
 	NRx4 uint8 // Counter/consecutive; Inital
 
 	enabled bool // Only output silence if this is false
+	forced  bool // Play regardless of triggers and volume (see ForceChannels)
 
 	register uint16 // 15-bit shift register
 
@@ -45,9 +46,10 @@ func (n *Noise) SetNRx2(value uint8) {
 }
 
 // Tick produces a sample of the signal to generate based on the current value
-// in the signal generator's registers. We use a named return value, which is
-// conveniently set to zero (silence) by default.
-func (n *Noise) Tick() (sample uint8) {
+// in the signal generator's registers, after advancing it by the given number
+// of machine cycles. We use a named return value, which is conveniently set to
+// zero (silence) by default.
+func (n *Noise) Tick(cycles uint) (sample uint8) {
 	// Enable that signal if requested. NR34 being write-only, we can reset it
 	// each time it goes to 1 without worrying.
 	if n.NRx4&NRx4RestartSound != 0 {
@@ -71,11 +73,11 @@ func (n *Noise) Tick() (sample uint8) {
 
 	}
 
-	if !n.enabled {
+	if !n.enabled && !n.forced {
 		return
 	}
 
-	n.envelope.Tick()
+	n.envelope.Tick(cycles)
 
 	// [AUDIO1] Frequency = 524288 Hz / r / 2^(s+1) ;For r=0 assume r=0.5 instead
 	// [AUDIO2] More details about divisor code.
@@ -89,33 +91,34 @@ func (n *Noise) Tick() (sample uint8) {
 		r = 1
 	}
 	rawFreq := 1048576 / (r + 1) / (1 << (s + 1))
-	if rawFreq > SamplingRate {
-		rawFreq = SamplingRate
+	if rawFreq == 0 {
+		rawFreq = 1
 	}
-	freq := uint(rawFreq / 16)
+	period := uint(GameBoyRate / rawFreq)
 
 	// Update register at the required frequency. FIXME: why is this not working?
-	if n.ticks++; n.ticks >= freq {
-		// [AUDIO2] When clocked by the frequency timer, the low two bits (0 and
-		// 1) are XORed, all bits are shifted right by one, and the result of
-		// the XOR is put into the now-empty 15th bit. If width mode is 1
-		// (NR43), the XOR result is ALSO put into bit 6 AFTER the shift,
-		// resulting in a 7-bit LFSR. The waveform output is bit 0 of the LFSR,
-		// INVERTED.
-		xor := (n.register & 1) ^ ((n.register & 2) >> 1)
-		n.register >>= 1
-		n.register &= ^uint16(1<<14) & 0x7fff // Reset bit 14 in case xor is zero
-		n.register |= xor << 14
-		if n.NRx3&NR43Width7 != 0 {
-			n.register &= ^uint16(1<<6) & 0x7fff
-			n.register |= xor << 6
+	for i := uint(0); i < cycles; i++ {
+		if n.ticks++; n.ticks >= period {
+			// [AUDIO2] When clocked by the frequency timer, the low two bits (0
+			// and 1) are XORed, all bits are shifted right by one, and the
+			// result of the XOR is put into the now-empty 15th bit. If width
+			// mode is 1 (NR43), the XOR result is ALSO put into bit 6 AFTER the
+			// shift, resulting in a 7-bit LFSR. The waveform output is bit 0 of
+			// the LFSR, INVERTED.
+			xor := (n.register & 1) ^ ((n.register & 2) >> 1)
+			n.register >>= 1
+			n.register &= ^uint16(1<<14) & 0x7fff // Reset bit 14 in case xor is zero
+			n.register |= xor << 14
+			if n.NRx3&NR43Width7 != 0 {
+				n.register &= ^uint16(1<<6) & 0x7fff
+				n.register |= xor << 6
+			}
+			n.ticks = 0
+			n.output = uint8((^n.register) & 1)
+
+			log.Desperatef("LFSR=%16b", n.register)
 		}
-		n.ticks = 0
-		n.output = uint8((^n.register) & 1)
-
-		log.Desperatef("LFSR=%16b", n.register)
-
 	}
 
-	return n.output * n.envelope.Volume()
+	return n.output * forcedVolume(n.envelope.Volume(), n.forced)
 }
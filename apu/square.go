@@ -20,6 +20,7 @@ type SquareWave struct {
 	NRx4 uint8 // Control and frequency' higher 3 bits
 
 	enabled bool // Only output silence if this is false
+	forced  bool // Play regardless of triggers and volume (see ForceChannels)
 
 	// Duty-related variables.
 	dutyStep int  // Sub-index into DutyCycles to set the signal high or low.
@@ -41,9 +42,10 @@ func (s *SquareWave) SetNRx2(value uint8) {
 }
 
 // Tick produces a sample of the signal to generate based on the current value
-// in the signal generator's registers. We use a named return value, which is
-// conveniently set to zero (silence) by default.
-func (s *SquareWave) Tick() (sample uint8) {
+// in the signal generator's registers, after advancing it by the given number
+// of machine cycles. We use a named return value, which is conveniently set to
+// zero (silence) by default.
+func (s *SquareWave) Tick(cycles uint) (sample uint8) {
 	// Enable that signal if requested. NR14 being write-only, we can reset it
 	// each time it goes to 1 without worrying.
 	if s.NRx4&NRx4RestartSound != 0 {
@@ -58,11 +60,11 @@ func (s *SquareWave) Tick() (sample uint8) {
 		s.envelope.Enable()
 	}
 
-	if !s.enabled {
+	if !s.enabled && !s.forced {
 		return
 	}
 
-	s.envelope.Tick()
+	s.envelope.Tick(cycles)
 
 	// With `x` the 11-bit value in NR13/NR14, frequency is 131072/(2048-x) Hz.
 	rawFreq := ((uint(s.NRx4) & 7) << 8) | uint(s.NRx3)
@@ -70,7 +72,7 @@ func (s *SquareWave) Tick() (sample uint8) {
 
 	// Advance duty step every 1/(8f) where f is the sound's real frequency
 	// for as many machine ticks as necessary to generate one sample.
-	for i := 0; i < SoundOutRate; i++ {
+	for i := uint(0); i < cycles; i++ {
 		if s.ticks++; s.ticks >= GameBoyRate/(freq*8) {
 			s.dutyStep = (s.dutyStep + 1) % 8
 			s.ticks = 0
@@ -78,7 +80,7 @@ func (s *SquareWave) Tick() (sample uint8) {
 	}
 
 	if DutyCycles[s.NRx1>>6][s.dutyStep] {
-		sample = s.envelope.Volume()
+		sample = forcedVolume(s.envelope.Volume(), s.forced)
 	}
 
 	return
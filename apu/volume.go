@@ -27,10 +27,10 @@ func (v *VolumeEnvelope) Disable() {
 	v.enabled = false
 }
 
-// Tick advances the volume envelope one step. It will adjust the volume value
-// every <sweep>×(1/64) seconds or <sweep>×(<sample rate>/64) APU ticks.
+// Tick advances the volume envelope by the given number of machine cycles. It
+// will adjust the volume value every <sweep>×(1/64) seconds.
 // Source: https://gbdev.gg8.se/wiki/articles/Sound_Controller about NR12.
-func (v *VolumeEnvelope) Tick() {
+func (v *VolumeEnvelope) Tick(cycles uint) {
 	if !v.enabled {
 		return
 	}
@@ -41,9 +41,9 @@ func (v *VolumeEnvelope) Tick() {
 		return
 	}
 
-	// Update volume every <sweep>×(<sample rate>/64) APU ticks.
-	v.ticks++
-	if v.ticks < uint(v.Sweep)*(SamplingRate/64) {
+	// Update volume every <sweep>×(<Game Boy rate>/64) machine cycles.
+	v.ticks += cycles
+	if v.ticks < uint(v.Sweep)*(GameBoyRate/64) {
 		return
 	}
 	v.ticks = 0
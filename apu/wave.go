@@ -24,9 +24,15 @@ type WaveTable struct {
 
 	Pattern *memory.RAM // Wave table pattern (32 4-bit samples)
 
+	// Interpolate smooths the output by blending linearly between the current
+	// sample and the next one, which reduces aliasing at high frequencies but
+	// isn't what the hardware does.
+	Interpolate bool
+
 	enabled bool // Only output silence if this is false
+	forced  bool // Play regardless of triggers, NR30 and NR32 (see ForceChannels)
 
-	sample       uint8 // Current sample to play
+	sample       uint8 // Current sample to play, before volume adjustment
 	sampleOffset int   // Sub-index of the current sample into the wave table
 	ticks        uint  // Clock ticks counter for advancing sample index
 }
@@ -41,9 +47,10 @@ func NewWave() *WaveTable {
 }
 
 // Tick produces a sample of the signal to generate based on the current value
-// in the signal generator's registers. We use a named return value, which is
-// conveniently set to zero (silence) by default.
-func (w *WaveTable) Tick() (sample uint8) {
+// in the signal generator's registers, after advancing it by the given number
+// of machine cycles. We use a named return value, which is conveniently set to
+// zero (silence) by default.
+func (w *WaveTable) Tick(cycles uint) (sample uint8) {
 	// Enable that signal if requested. NR34 being write-only, we can reset it
 	// each time it goes to 1 without worrying.
 	if w.NRx4&NRx4RestartSound != 0 {
@@ -56,11 +63,7 @@ func (w *WaveTable) Tick() (sample uint8) {
 		w.ticks = 0
 	}
 
-	if !w.enabled {
-		return
-	}
-
-	if w.NRx0&NR30SoundOn == 0 {
+	if !w.forced && (!w.enabled || w.NRx0&NR30SoundOn == 0) {
 		return
 	}
 
@@ -70,22 +73,36 @@ func (w *WaveTable) Tick() (sample uint8) {
 
 	// Advance sample index every 1/(32f) where f is the sound's real frequency.
 	// TODO: figure out minimal tick rate necessary for all updates to happen
-	// and use that instead of SoundOutRate/GameBoyRate.
-	for i := 0; i < SoundOutRate; i++ {
-		if w.ticks++; w.ticks >= GameBoyRate/(freq*32) {
+	// and use that instead of looping over every machine cycle.
+	period := GameBoyRate / (freq * 32)
+	for i := uint(0); i < cycles; i++ {
+		if w.ticks++; w.ticks >= period {
 			w.sampleOffset = (w.sampleOffset + 1) % 32
 			w.ticks = 0
+			w.sample = w.sampleAt(w.sampleOffset)
+		}
+	}
 
-			// Each byte in the wave table contains 2 samples. Read it and only
-			// output the proper nibble.
-			sampleByte := w.sampleOffset / 2
-			sampleShift := 4 - ((w.sampleOffset % 2) * 4) // Upper nibble first
-			w.sample = (w.Pattern.Bytes[sampleByte] >> sampleShift) & 0xf
+	sample = w.sample
+	if w.Interpolate {
+		// Weighted average of both samples, rounded to the nearest.
+		next := uint(w.sampleAt((w.sampleOffset + 1) % 32))
+		sample = uint8((uint(w.sample)*(period-w.ticks) + next*w.ticks + period/2) / period)
+	}
 
-			// Adjust for volume.
-			w.sample >>= OutputShift[(w.NRx2&0x60)>>5]
-		}
+	// Adjust for volume on output rather than when reading the sample, so
+	// that writes to NR32 apply to the sample currently playing.
+	level := (w.NRx2 & 0x60) >> 5
+	if w.forced && level == 0 {
+		level = 1 // Full volume instead of muted.
 	}
+	return sample >> OutputShift[level]
+}
 
-	return w.sample
+// Returns the sample at the given index in the wave table. Each byte in the
+// wave table contains 2 samples, upper nibble first.
+func (w *WaveTable) sampleAt(offset int) uint8 {
+	sampleByte := offset / 2
+	sampleShift := 4 - ((offset % 2) * 4)
+	return (w.Pattern.Bytes[sampleByte] >> sampleShift) & 0xf
 }
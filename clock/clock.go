@@ -0,0 +1,58 @@
+// Package clock defines how the emulator's subsystems are advanced in time,
+// one machine tick (at 4MiHz) after the other, so that tests can drive them by
+// an exact number of cycles.
+package clock
+
+// Ticker is a subsystem advancing one machine tick per call, e.g. the PPU.
+type Ticker interface {
+	Tick()
+}
+
+// Clock tells how many machine ticks elapsed since it started.
+type Clock interface {
+	Ticks() uint64
+}
+
+// TickerFunc adapts a function to the Ticker interface.
+type TickerFunc func()
+
+// Tick calls f.
+func (f TickerFunc) Tick() {
+	f()
+}
+
+// Manual is a clock only advancing when told to, ticking its subsystems in
+// the order they were given. The emulator advances it one tick at a time, and
+// tests can use it to check state at exact cycles.
+type Manual struct {
+	ticks   uint64
+	tickers []Ticker
+}
+
+// NewManual returns a stopped clock driving the given subsystems.
+func NewManual(tickers ...Ticker) *Manual {
+	return &Manual{tickers: tickers}
+}
+
+// Ticks returns the number of machine ticks elapsed so far.
+func (m *Manual) Ticks() uint64 {
+	return m.ticks
+}
+
+// Advance ticks all subsystems n times.
+func (m *Manual) Advance(n uint64) {
+	for ; n > 0; n-- {
+		m.ticks++
+		for _, t := range m.tickers {
+			t.Tick()
+		}
+	}
+}
+
+// AdvanceTo ticks all subsystems until the given number of ticks elapsed since
+// the clock started. It does nothing if that time is already past.
+func (m *Manual) AdvanceTo(tick uint64) {
+	if tick > m.ticks {
+		m.Advance(tick - m.ticks)
+	}
+}
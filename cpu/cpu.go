@@ -8,11 +8,20 @@ import (
 
 	"github.com/lazy-stripes/goholint/cpu/states"
 	"github.com/lazy-stripes/goholint/interrupts"
+	"github.com/lazy-stripes/goholint/logger"
 	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/timer"
 )
 
 // [GEKKIO] https://gekkio.fi/files/gb-docs/gbctr.pdf
 
+// Package-wide logger.
+var log = logger.New("cpu", "CPU-related operations")
+
+func init() {
+	log.Add("interrupts", "interrupt requests and dispatch")
+}
+
 // Flag bitfield enum
 const (
 	FlagC uint8 = 1 << (iota + 4)
@@ -33,6 +42,21 @@ type CPU struct {
 	H, L   uint8
 	SP     uint16
 	PC     uint16
+	Speed  Speed // KEY1 register, only mapped in CGB mode
+
+	// Breakpoint is called when executing LD B,B, if set, or when fetching an
+	// instruction at one of the addresses in Breakpoints. Either way, the
+	// instruction starts at PC-1.
+	Breakpoint  func(c *CPU)
+	Breakpoints map[uint16]bool
+
+	// IgnoreLDBB keeps LD B,B from calling Breakpoint, for when only address
+	// breakpoints are wanted.
+	IgnoreLDBB bool
+
+	// OAMBug is called, if set, with the value of a register pair about to be
+	// incremented or decremented, which can corrupt OAM on DMG.
+	OAMBug func(addr uint16)
 
 	instruction Instruction
 	state       int
@@ -48,6 +72,8 @@ type CPU struct {
 	debug     bool
 	startFrom uint16
 	oldPC     uint16
+
+	profile *profile // Opcode statistics, if enabled (see EnableProfile)
 }
 
 // New CPU running code in the given address space starting from 0.
@@ -61,7 +87,6 @@ func (c *CPU) Tick() {
 
 	// Handle interrupts
 	if (c.state&states.Interruptible != 0) && c.IME && (c.IF&c.IE != 0) {
-		// TODO: re-enable LCD if interrupted after STOP.
 		c.state = states.InterruptWait0
 	}
 
@@ -74,6 +99,7 @@ func (c *CPU) Tick() {
 	case states.Halted:
 		return
 	case states.Stopped:
+		// Only a joypad input wakes the CPU up (see Wake).
 		return
 	case states.FetchOpCode:
 		if !c.debug && c.PC == c.startFrom {
@@ -83,15 +109,25 @@ func (c *CPU) Tick() {
 			//fmt.Printf("PC=%04X (%02X)\n", c.PC, c.MMU.Read(c.PC))
 		}
 		opcode := c.NextByte()
+		if c.Breakpoints != nil && c.Breakpoints[c.PC-1] && c.Breakpoint != nil {
+			c.Breakpoint(c)
+		}
 
 		if opcode == 0xcb { // Extended instruction set
 			c.state = states.FetchExtendedOpcode
 		} else {
 			defer instructionError(c, false)
+			if c.profile != nil {
+				c.profile.start(&c.profile.stats[opcode])
+				c.profile.current.Cycles++
+			}
 
 			c.instruction = LR35902InstructionSet[opcode]
 			if c.instruction.Execute(c) { // Instruction is done within the first 4 cycles.
-				c.state = states.FetchOpCode
+				// Unless it halted or stopped the CPU.
+				if c.state != states.Halted && c.state != states.Stopped {
+					c.state = states.FetchOpCode
+				}
 			} else {
 				c.state = states.Execute
 			}
@@ -100,6 +136,11 @@ func (c *CPU) Tick() {
 	case states.FetchExtendedOpcode:
 		opcode := c.NextByte()
 		defer instructionError(c, true)
+		if c.profile != nil {
+			// Also count the cycle spent fetching the prefix.
+			c.profile.start(&c.profile.extended[opcode])
+			c.profile.current.Cycles += 2
+		}
 
 		c.instruction = LR35902ExtendedInstructionSet[opcode]
 		if c.instruction.Execute(c) { // Instruction is done within the first 8 cycles.
@@ -109,6 +150,9 @@ func (c *CPU) Tick() {
 		}
 
 	case states.Execute:
+		if c.profile != nil {
+			c.profile.current.Cycles++
+		}
 		if c.instruction.Tick() {
 			// Handle one-instruction delay when enabling IME [GEKKIO]
 			if c.IMEScheduled {
@@ -127,21 +171,9 @@ func (c *CPU) Tick() {
 		c.state = states.InterruptWait1
 
 	case states.InterruptWait1:
-		requested := c.IF & c.IE
-
-		// Doing this in a switch/case instead of a loop because I played too much EXAPUNKS...
-		// Unrolling is good for perfs, right?
-		switch {
-		case requested&interrupts.VBlank != 0:
-			c.interrupt = interrupts.VBlank
-		case requested&interrupts.LCDStat != 0:
-			c.interrupt = interrupts.LCDStat
-		case requested&interrupts.Timer != 0:
-			c.interrupt = interrupts.Timer
-			// TODO: all other interrupts
-		default:
-			fmt.Printf(" !!! Unimplemented interrupt requested: 0x%02x\n", requested)
-		}
+		// Dispatch the highest-priority interrupt (VBlank first).
+		c.interrupt = interrupts.Highest(c.IF & c.IE)
+		log.Sub("interrupts").Debugf("dispatching interrupt 0x%02x", c.interrupt)
 
 		c.state = states.InterruptPushPCHigh
 
@@ -243,6 +275,33 @@ func (c *CPU) NextWord() uint16 {
 	return uint16(c.NextByte()) | uint16(c.NextByte())<<8
 }
 
+// Wake leaves STOP mode, if the CPU was stopped. [PANDOCS] Only a joypad line
+// going low does that, whether the joypad interrupt is enabled or not, so this
+// is meant to be called by the joypad (see joypad.Joypad.OnPress).
+func (c *CPU) Wake() {
+	if c.state == states.Stopped {
+		c.state = states.FetchOpCode
+	}
+}
+
+// Enters STOP mode, unless a CGB speed switch was prepared in which case the
+// switch happens instead. DIV is reset either way.
+func (c *CPU) stop() {
+	c.MMU.Write(timer.AddrDIV, 0)
+	if c.Speed.Switch() {
+		return
+	}
+	c.state = states.Stopped
+}
+
+// Notify the OAM bug handler, if any, that the 16-bit increment/decrement unit
+// is about to operate on the given address.
+func (c *CPU) oamBug(addr uint16) {
+	if c.OAMBug != nil {
+		c.OAMBug(addr)
+	}
+}
+
 // Context returns a printable context to prepend to log messages. Currently,
 // it only shows the current value of PC.
 func (c *CPU) Context() string {
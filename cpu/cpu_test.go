@@ -1,9 +1,15 @@
 package cpu
 
 import (
+	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 
+	"github.com/lazy-stripes/goholint/interrupts"
+	"github.com/lazy-stripes/goholint/joypad"
 	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/timer"
 )
 
 func TestCPU(t *testing.T) {
@@ -14,3 +20,246 @@ func TestCPU(t *testing.T) {
 	cpu := New(mmu)
 	cpu.Tick()
 }
+
+func TestSpeedSwitch(t *testing.T) {
+	// STOP followed by its mandatory zero byte, then NOP.
+	code := memory.NewRAM(0, 0x10)
+	code.Write(0, 0x10)
+	tmr := timer.New()
+	tmr.DIV = 0x1234
+
+	cpu := New(memory.NewMMU([]memory.Addressable{code, tmr}))
+	cpu.Speed.Write(AddrKEY1, KEY1Prepare)
+	normal := cpu.Speed.TicksPerCycle()
+	cpu.Tick()
+
+	if !cpu.Speed.Double {
+		t.Fatal("STOP did not switch to double speed")
+	}
+	if tmr.DIV != 0 {
+		t.Errorf("DIV == 0x%04x after STOP, want 0", tmr.DIV)
+	}
+
+	// Execution resumes right away after a speed switch.
+	cpu.Tick()
+	if cpu.PC != 3 {
+		t.Errorf("PC == 0x%04x after speed switch, want 0x0003", cpu.PC)
+	}
+	if got := cpu.Speed.Read(AddrKEY1); got != 0xfe {
+		t.Errorf("KEY1 == 0x%02x after switch, want 0xfe", got)
+	}
+
+	// The PPU still ticks once per machine tick, so it now gets half as many
+	// ticks per CPU cycle.
+	if double := cpu.Speed.TicksPerCycle(); double*2 != normal {
+		t.Errorf("%d ticks per CPU cycle in double speed, want %d", double,
+			normal/2)
+	}
+}
+
+func TestStopWakeOnJoypad(t *testing.T) {
+	// STOP followed by its mandatory zero byte, then NOPs.
+	code := memory.NewRAM(0, 0x10)
+	code.Write(0, 0x10)
+	tmr := timer.New()
+	tmr.DIV = 0x1234
+
+	jpad := joypad.New()
+	cpu := New(memory.NewMMU([]memory.Addressable{code, tmr, jpad}))
+	jpad.Interrupts = interrupts.New(&cpu.IF, &cpu.IE)
+	jpad.OnPress = cpu.Wake
+
+	// Other interrupts don't wake the CPU up, even when enabled.
+	cpu.IME = true
+	cpu.IE = interrupts.Timer | interrupts.Joypad
+	cpu.MMU.Write(joypad.AddrJOYP, joypad.P14) // Select buttons.
+	cpu.Tick()
+	cpu.IF = interrupts.Timer
+	for i := 0; i < 10; i++ {
+		cpu.Tick()
+	}
+	if cpu.PC != 2 || tmr.DIV != 0 {
+		t.Fatalf("PC == 0x%04x, DIV == 0x%04x while stopped, want 0x0002 and 0", cpu.PC, tmr.DIV)
+	}
+
+	// Pressing an unselected direction doesn't either.
+	jpad.SetButton("up", true)
+	cpu.Tick()
+	if cpu.PC != 2 {
+		t.Fatal("CPU woke up on an unselected input")
+	}
+
+	// Neither does a joypad interrupt left pending from earlier input.
+	cpu.IME = false
+	cpu.IE = 0
+	cpu.IF = interrupts.Joypad
+	cpu.Tick()
+	if cpu.PC != 2 {
+		t.Fatal("CPU woke up on a stale joypad interrupt")
+	}
+
+	// A button does, even without interrupts enabled.
+	cpu.IF = 0
+	jpad.SetButton("start", true)
+	if cpu.IF != interrupts.Joypad {
+		t.Fatalf("IF == 0x%02x after pressing Start, want 0x%02x", cpu.IF, interrupts.Joypad)
+	}
+	cpu.Tick()
+	if cpu.PC != 3 {
+		t.Errorf("PC == 0x%04x after pressing Start, want 0x0003", cpu.PC)
+	}
+}
+
+func TestHalt(t *testing.T) {
+	// HALT ; NOP ; HALT ; NOP
+	code := memory.NewRAM(0, 0x100)
+	code.Write(0, 0x76)
+	code.Write(2, 0x76)
+	cpu := New(code)
+	cpu.SP = 0x100
+	cpu.IE = interrupts.Timer
+
+	// Nothing happens until an enabled interrupt is requested.
+	for i := 0; i < 10; i++ {
+		cpu.Tick()
+	}
+	if cpu.PC != 1 {
+		t.Fatalf("PC == 0x%04x while halted, want 0x0001", cpu.PC)
+	}
+	cpu.IF = interrupts.Serial
+	cpu.Tick()
+	if cpu.PC != 1 {
+		t.Fatal("CPU resumed on a disabled interrupt")
+	}
+
+	// Without IME, execution resumes after HALT and the interrupt is left
+	// pending.
+	cpu.IF = interrupts.Timer
+	cpu.Tick()
+	if cpu.PC != 2 || cpu.IF != interrupts.Timer {
+		t.Fatalf("PC=0x%04x IF=0x%02x after resuming, want PC=0x0002 IF=0x%02x",
+			cpu.PC, cpu.IF, interrupts.Timer)
+	}
+
+	// With IME, the interrupt is dispatched.
+	cpu.IF = 0
+	cpu.Tick()
+	cpu.IME = true
+	cpu.IF = interrupts.Timer
+	for i := 0; i < 5; i++ {
+		cpu.Tick()
+	}
+	if cpu.PC != interrupts.AddrTimer {
+		t.Errorf("PC == 0x%04x after interrupt, want 0x%04x", cpu.PC,
+			interrupts.AddrTimer)
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	// LD B,B ; LD B,C ; LD B,B
+	code := memory.NewRAM(0, 0x10)
+	code.Write(0, 0x40)
+	code.Write(1, 0x41)
+	code.Write(2, 0x40)
+
+	var hits []uint16
+	cpu := New(code)
+	cpu.Breakpoint = func(c *CPU) { hits = append(hits, c.PC-1) }
+	for i := 0; i < 3; i++ {
+		cpu.Tick()
+	}
+
+	if len(hits) != 2 || hits[0] != 0 || hits[1] != 2 {
+		t.Errorf("breakpoint hit at %x, want [0 2]", hits)
+	}
+
+	// Breakpoints can also be set by address.
+	hits = nil
+	cpu.PC = 0
+	cpu.Breakpoints = map[uint16]bool{1: true}
+	for i := 0; i < 2; i++ {
+		cpu.Tick()
+	}
+	if len(hits) != 2 || hits[0] != 0 || hits[1] != 1 {
+		t.Errorf("breakpoint hit at %x, want [0 1]", hits)
+	}
+
+	// Address breakpoints alone leave LD B,B alone.
+	hits = nil
+	cpu.PC = 0
+	cpu.IgnoreLDBB = true
+	for i := 0; i < 3; i++ {
+		cpu.Tick()
+	}
+	if len(hits) != 1 || hits[0] != 1 {
+		t.Errorf("breakpoint hit at %x, want [1]", hits)
+	}
+}
+
+func TestQuietInterrupts(t *testing.T) {
+	// Request joypad and serial interrupts, serial should go first.
+	code := memory.NewRAM(0, 0x100)
+	cpu := New(code)
+	cpu.SP = 0x100
+	cpu.IME = true
+	cpu.IF, cpu.IE = 0x18, 0x18
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	for i := 0; i < 5; i++ {
+		cpu.Tick()
+	}
+	os.Stdout = stdout
+	w.Close()
+
+	if cpu.PC != interrupts.AddrSerial || cpu.IF != 0x10 {
+		t.Errorf("PC=0x%04x IF=0x%02x after dispatch, want PC=0x%04x IF=0x10",
+			cpu.PC, cpu.IF, interrupts.AddrSerial)
+	}
+
+	out, _ := ioutil.ReadAll(r)
+	if len(out) > 0 {
+		t.Errorf("unexpected output at default log level: %q", out)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	code := memory.NewRAM(0, 0x10)
+	for i, b := range []uint8{
+		0x00,       // NOP
+		0x00,       // NOP
+		0x3e, 0x12, // LD A,$12
+		0xcb, 0x37, // SWAP A
+		0x18, 0xf8, // JR -8
+	} {
+		code.Write(uint16(i), b)
+	}
+	cpu := New(memory.NewMMU([]memory.Addressable{code}))
+	if cpu.Profile() != nil {
+		t.Error("profile available without enabling it")
+	}
+	cpu.EnableProfile()
+
+	// Two loops of 9 machine cycles.
+	for i := 0; i < 18; i++ {
+		cpu.Tick()
+	}
+
+	expected := map[byte]Stat{
+		0x00: {Count: 4, Cycles: 4},
+		0x3e: {Count: 2, Cycles: 4},
+		0xcb: {Count: 2, Cycles: 4},
+		0x18: {Count: 2, Cycles: 6},
+	}
+	if profile := cpu.Profile(); !reflect.DeepEqual(profile, expected) {
+		t.Errorf("profile is %v, want %v", profile, expected)
+	}
+	extended := map[byte]Stat{0x37: {Count: 2, Cycles: 4}}
+	if profile := cpu.ExtendedProfile(); !reflect.DeepEqual(profile, extended) {
+		t.Errorf("extended profile is %v, want %v", profile, extended)
+	}
+}
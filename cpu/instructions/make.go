@@ -112,7 +112,7 @@ func main() {
 		{Opcode: 0x3d, Template: "decr", Register: "A"},
 		{Opcode: 0x3e, Template: "ldrd8", Register: "A"},
 		{Opcode: 0x3f, Template: "scfccf", Instruction: "CCF"},
-		{Opcode: 0x40, Template: "ldrr", Register: "B", OtherRegister: "B"},
+		{Opcode: 0x40, Template: "ldbb"},
 		{Opcode: 0x41, Template: "ldrr", Register: "B", OtherRegister: "C"},
 		{Opcode: 0x42, Template: "ldrr", Register: "B", OtherRegister: "D"},
 		{Opcode: 0x43, Template: "ldrr", Register: "B", OtherRegister: "E"},
@@ -1,4 +1,4 @@
-// Auto-generated on 2019-05-09T20:01:43+02:00. See instructions.go
+// Auto-generated on 2026-10-17T03:42:31Z. See instructions.go
 
 package cpu
 
@@ -554,6 +554,7 @@ type op03 struct {
 }
 
 func (op *op03) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.BC())
 	if op.cpu.C == 0xff {
 		op.cpu.B++
 	}
@@ -685,6 +686,7 @@ type op0b struct {
 }
 
 func (op *op0b) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.BC())
 	if op.cpu.C == 0x00 {
 		op.cpu.B--
 	}
@@ -766,7 +768,9 @@ type op10 struct {
 func (op *op10) Execute(c *CPU) (done bool) {
 	// Source indicates a 2-byte, 4-cycle instruction but this is unclear.
 	c.PC++	// Ignore following zero
-	c.state = states.Stopped
+
+	// [PANCGB] STOP is also how CGB games switch speed after setting KEY1.
+	c.stop()
 	return true
 }
 
@@ -803,6 +807,7 @@ type op13 struct {
 }
 
 func (op *op13) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.DE())
 	if op.cpu.E == 0xff {
 		op.cpu.D++
 	}
@@ -934,6 +939,7 @@ type op1b struct {
 }
 
 func (op *op1b) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.DE())
 	if op.cpu.E == 0x00 {
 		op.cpu.D--
 	}
@@ -1067,6 +1073,7 @@ type op23 struct {
 }
 
 func (op *op23) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.HL())
 	if op.cpu.L == 0xff {
 		op.cpu.H++
 	}
@@ -1218,6 +1225,7 @@ type op2b struct {
 }
 
 func (op *op2b) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.HL())
 	if op.cpu.L == 0x00 {
 		op.cpu.H--
 	}
@@ -1342,6 +1350,7 @@ type op33 struct {
 }
 
 func (op *op33) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.SP)
 	op.cpu.SP++
 	return true
 }
@@ -1490,6 +1499,7 @@ type op3b struct {
 }
 
 func (op *op3b) Tick() (done bool) {
+	op.cpu.oamBug(op.cpu.SP)
 	op.cpu.SP--
 	return true
 }
@@ -1560,7 +1570,11 @@ type op40 struct {
 }
 
 func (op *op40) Execute(c *CPU) (done bool) {
-	c.B = c.B
+	// Loading B into itself does nothing, which makes it a popular software
+	// breakpoint for test ROMs and debuggers (BGB, for instance).
+	if c.Breakpoint != nil && !c.IgnoreLDBB {
+		c.Breakpoint(c)
+	}
 	return true
 }
 
@@ -0,0 +1,106 @@
+package cpu
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Stat holds execution statistics for an opcode (see CPU.Profile).
+type Stat struct {
+	Count  uint64 // Times the instruction was executed
+	Cycles uint64 // Machine cycles (4 clock cycles each) spent executing it
+}
+
+// Per-opcode statistics, only kept when profiling is enabled so that the CPU
+// doesn't pay for them otherwise.
+type profile struct {
+	stats    [256]Stat // Regular instructions
+	extended [256]Stat // 0xCB-prefixed instructions
+	current  *Stat     // Stats for the instruction being executed
+}
+
+// Starts accounting for a new instruction.
+func (p *profile) start(stat *Stat) {
+	p.current = stat
+	stat.Count++
+}
+
+// EnableProfile starts counting executions and machine cycles for each opcode,
+// from scratch if profiling was already enabled.
+func (c *CPU) EnableProfile() {
+	c.profile = &profile{}
+}
+
+// Profile returns execution statistics for each opcode executed since
+// profiling was enabled, or nil if it wasn't. Interrupt dispatch and time
+// spent halted aren't counted. All 0xCB-prefixed instructions are summed up
+// under 0xCB (see ExtendedProfile for details).
+func (c *CPU) Profile() map[byte]Stat {
+	if c.profile == nil {
+		return nil
+	}
+	stats := make(map[byte]Stat)
+	for opcode, stat := range c.profile.stats {
+		if stat.Count > 0 {
+			stats[byte(opcode)] = stat
+		}
+	}
+	for _, stat := range c.profile.extended {
+		if stat.Count > 0 {
+			cb := stats[0xcb]
+			cb.Count += stat.Count
+			cb.Cycles += stat.Cycles
+			stats[0xcb] = cb
+		}
+	}
+	return stats
+}
+
+// ExtendedProfile returns execution statistics for each 0xCB-prefixed opcode
+// executed since profiling was enabled, or nil if it wasn't. Cycles include
+// fetching the prefix.
+func (c *CPU) ExtendedProfile() map[byte]Stat {
+	if c.profile == nil {
+		return nil
+	}
+	stats := make(map[byte]Stat)
+	for opcode, stat := range c.profile.extended {
+		if stat.Count > 0 {
+			stats[byte(opcode)] = stat
+		}
+	}
+	return stats
+}
+
+// WriteProfile prints opcode statistics, the most expensive ones first.
+func (c *CPU) WriteProfile(w io.Writer) {
+	type line struct {
+		name string
+		Stat
+	}
+	var lines []line
+	var total uint64
+	for opcode, stat := range c.Profile() {
+		if opcode != 0xcb {
+			lines = append(lines, line{fmt.Sprintf("0x%02x", opcode), stat})
+			total += stat.Cycles
+		}
+	}
+	for opcode, stat := range c.ExtendedProfile() {
+		lines = append(lines, line{fmt.Sprintf("0xcb 0x%02x", opcode), stat})
+		total += stat.Cycles
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Cycles != lines[j].Cycles {
+			return lines[i].Cycles > lines[j].Cycles
+		}
+		return lines[i].name < lines[j].name
+	})
+
+	fmt.Fprintf(w, "%-9s %12s %12s %6s\n", "Opcode", "Count", "Cycles", "%")
+	for _, l := range lines {
+		fmt.Fprintf(w, "%-9s %12d %12d %6.2f\n", l.name, l.Count, l.Cycles,
+			float64(l.Cycles)*100/float64(total))
+	}
+}
@@ -0,0 +1,68 @@
+package cpu
+
+// CGB speed switch. Source:
+// [PANCGB] https://gbdev.io/pandocs/CGB_Registers.html#ff4d---key1---cgb-mode-only---prepare-speed-switch
+
+// AddrKEY1 is the address of the CGB speed switch register.
+const AddrKEY1 = 0xff4d
+
+// KEY1 register bits.
+const (
+	KEY1Prepare     uint8 = 1 << 0 // Bit 0 - Prepare Speed Switch (0=No, 1=Prepare)
+	KEY1DoubleSpeed uint8 = 1 << 7 // Bit 7 - Current Speed (0=Normal, 1=Double) (Read Only)
+)
+
+// Speed address space for the KEY1 register. The actual switch happens when
+// the CPU executes STOP after a speed switch was prepared. It should only be
+// mapped in CGB mode.
+type Speed struct {
+	Prepared bool
+	Double   bool
+}
+
+// Contains returns true if the requested address is the KEY1 register.
+func (s *Speed) Contains(addr uint16) bool {
+	return addr == AddrKEY1
+}
+
+// Read returns the value of KEY1 with unused bits set.
+func (s *Speed) Read(addr uint16) (value uint8) {
+	value = 0x7e
+	if s.Double {
+		value |= KEY1DoubleSpeed
+	}
+	if s.Prepared {
+		value |= KEY1Prepare
+	}
+	return value
+}
+
+// Write only updates the prepare bit.
+func (s *Speed) Write(addr uint16, value uint8) {
+	s.Prepared = value&KEY1Prepare != 0
+}
+
+// Switch toggles between normal and double speed if a switch was prepared,
+// and returns whether it did.
+func (s *Speed) Switch() bool {
+	if !s.Prepared {
+		return false
+	}
+	s.Prepared = false
+	s.Double = !s.Double
+	if s.Double {
+		log.Info("switched to double speed")
+	} else {
+		log.Info("switched to normal speed")
+	}
+	return true
+}
+
+// TicksPerCycle returns how many machine ticks happen for each CPU cycle: 4
+// at normal speed, 2 at double speed. The PPU and APU are not affected.
+func (s *Speed) TicksPerCycle() uint64 {
+	if s.Double {
+		return 2
+	}
+	return 4
+}
@@ -14,6 +14,6 @@ const (
 	InterruptCall
 
 	// Useful combinations
-	Interruptible     = FetchOpCode | Halted | Stopped
+	Interruptible     = FetchOpCode | Halted
 	HandlingInterrupt = InterruptWait0 | InterruptWait1 | InterruptPushPCHigh | InterruptPushPCLow | InterruptCall
 )
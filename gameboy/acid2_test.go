@@ -0,0 +1,118 @@
+package gameboy
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/cpu"
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// Test ROM and reference image (see bin/tests/acid2/README.md).
+const (
+	acid2ROM       = "../bin/tests/acid2/dmg-acid2.gb"
+	acid2Reference = "../bin/tests/acid2/reference-dmg.png"
+)
+
+// Give up after this many frames if the test ROM didn't signal completion.
+const acid2MaxFrames = 120
+
+// Pixel differing from the reference image.
+type pixelDiff struct {
+	X, Y      int
+	Got, Want uint8
+}
+
+func (d pixelDiff) String() string {
+	return fmt.Sprintf("(%d,%d): got color %d, want %d", d.X, d.Y, d.Got, d.Want)
+}
+
+// runUntilBreakpoint runs a ROM headless until it executes LD B,B (which test
+// ROMs commonly use to signal they're done) or maxFrames frames elapsed.
+// Returns the display holding the last complete frame.
+func runUntilBreakpoint(t *testing.T, romPath string, maxFrames uint) *screen.Headless {
+	display := screen.NewHeadless()
+	g := NewWithDisplay(&options.Options{ROMPath: romPath, FastBoot: true}, display, nil)
+
+	done := false
+	g.CPU.Breakpoint = func(c *cpu.CPU) { done = true }
+	for !done {
+		if display.Frames > maxFrames {
+			t.Fatalf("%s did not complete within %d frames", romPath, maxFrames)
+		}
+		g.Tick()
+	}
+	return display
+}
+
+// loadReference converts a grayscale reference image to DMG color indices,
+// from 0 (white) to 3 (black).
+func loadReference(t *testing.T, path string) []uint8 {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := image.Rect(0, 0, screen.ScreenWidth, screen.ScreenHeight)
+	if img.Bounds() != want {
+		t.Fatalf("reference image is %v, want %v", img.Bounds(), want)
+	}
+
+	pixels := make([]uint8, 0, screen.ScreenWidth*screen.ScreenHeight)
+	for y := 0; y < screen.ScreenHeight; y++ {
+		for x := 0; x < screen.ScreenWidth; x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			// Map 0xffff, 0xaaaa, 0x5555, 0 to 0, 1, 2, 3.
+			pixels = append(pixels, 3-uint8((r+0x2aaa)/0x5555))
+		}
+	}
+	return pixels
+}
+
+// compareFrame returns the list of pixels differing between a frame and its
+// reference.
+func compareFrame(got, want []uint8) (diffs []pixelDiff) {
+	for i := range want {
+		if got[i] != want[i] {
+			diffs = append(diffs, pixelDiff{
+				X:    i % screen.ScreenWidth,
+				Y:    i / screen.ScreenWidth,
+				Got:  got[i],
+				Want: want[i],
+			})
+		}
+	}
+	return
+}
+
+func TestAcid2(t *testing.T) {
+	for _, path := range []string{acid2ROM, acid2Reference} {
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("%s not found, see bin/tests/acid2/README.md", path)
+		}
+	}
+
+	display := runUntilBreakpoint(t, acid2ROM, acid2MaxFrames)
+	diffs := compareFrame(display.Frame[:], loadReference(t, acid2Reference))
+	if len(diffs) > 0 {
+		const maxReported = 20
+		for i, d := range diffs {
+			if i == maxReported {
+				t.Errorf("... and %d more", len(diffs)-maxReported)
+				break
+			}
+			t.Error(d)
+		}
+		t.Fatalf("%d pixels differ from reference (frame hash %016x)",
+			len(diffs), display.Hash())
+	}
+}
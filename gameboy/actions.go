@@ -2,75 +2,141 @@ package gameboy
 
 import (
 	"fmt"
-	"time"
+	"image"
+	"os"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/lazy-stripes/goholint/ppu"
+	"github.com/lazy-stripes/goholint/screen"
 )
 
 // Action type for user interactions. This might move to a ui package someday.
 type Action func(eventType uint32)
 
+// Event types passed to actions. They have the same values as SDL's so that
+// the SDL frontend can pass its events along as is.
+const (
+	KeyDown uint32 = 0x300
+	KeyUp   uint32 = 0x301
+)
+
 // JoypadUp updates the Joypad's registers for the Up direction.
 func (g *GameBoy) JoypadUp(eventType uint32) {
-	g.JPad.Up.State = (eventType == sdl.KEYDOWN)
+	g.setButton("up", eventType == KeyDown)
 }
 
 // JoypadDown updates the Joypad's registers for the Down direction.
 func (g *GameBoy) JoypadDown(eventType uint32) {
-	g.JPad.Down.State = (eventType == sdl.KEYDOWN)
+	g.setButton("down", eventType == KeyDown)
 }
 
 // JoypadLeft updates the Joypad's registers for the Left direction.
 func (g *GameBoy) JoypadLeft(eventType uint32) {
-	g.JPad.Left.State = (eventType == sdl.KEYDOWN)
+	g.setButton("left", eventType == KeyDown)
 }
 
 // JoypadRight updates the Joypad's registers for the Right direction.
 func (g *GameBoy) JoypadRight(eventType uint32) {
-	g.JPad.Right.State = (eventType == sdl.KEYDOWN)
+	g.setButton("right", eventType == KeyDown)
 }
 
 // JoypadA updates the Joypad's registers for the A button.
 func (g *GameBoy) JoypadA(eventType uint32) {
-	g.JPad.A.State = (eventType == sdl.KEYDOWN)
+	g.setButton("a", eventType == KeyDown)
 }
 
 // JoypadB updates the Joypad's registers for the B button.
 func (g *GameBoy) JoypadB(eventType uint32) {
-	g.JPad.B.State = (eventType == sdl.KEYDOWN)
+	g.setButton("b", eventType == KeyDown)
 }
 
 // JoypadSelect updates the Joypad's registers for the Select button.
 func (g *GameBoy) JoypadSelect(eventType uint32) {
-	g.JPad.Select.State = (eventType == sdl.KEYDOWN)
+	g.setButton("select", eventType == KeyDown)
 }
 
 // JoypadStart updates the Joypad's registers for the Start button.
 func (g *GameBoy) JoypadStart(eventType uint32) {
-	g.JPad.Start.State = (eventType == sdl.KEYDOWN)
+	g.setButton("start", eventType == KeyDown)
 }
 
-// Screenshot saves the current frame to disk as a PNG file.
-// TODO: configurable folder, obviously.
+// Screenshot saves the current frame to disk in the format set with
+// -screenshot (PNG by default), named after the -filename template.
 func (g *GameBoy) Screenshot(eventType uint32) {
-	if eventType != sdl.KEYDOWN {
+	if eventType != KeyDown {
 		return
 	}
 
-	// Build a nice enough filename. TODO: configurable path.
-	filename := fmt.Sprintf("goholint-%s-%d.png", time.Now().Format(DateFormat),
-		g.CPU.Cycle)
+	format := g.args.Screenshots
+	if format == "" {
+		format = "png"
+	}
+
+	filename := g.captureName("", "."+format) + "." + format
 
 	// Saving the current frame should really be up to the display (so it can
 	// wait until VBlank for instance.)
 	g.Display.Screenshot(filename)
 }
 
-// StartStopRecord starts recording video output to GIF and closes the file
-// when done. Defined as a single action to toggle between the two and avoid
-// opening several GIFs at once.
+// DumpState writes the emulator's state to a text file along with a
+// screenshot of the next frame, both named alike after the -filename template,
+// so they can be attached to bug reports.
+func (g *GameBoy) DumpState(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+
+	base := g.captureName("", ".txt", ".png")
+
+	f, err := os.Create(base + ".txt")
+	if err != nil {
+		log.Warningf("can't dump state: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := g.WriteState(f); err != nil {
+		log.Warningf("can't dump state: %v", err)
+		return
+	}
+
+	g.Display.Screenshot(base + ".png")
+	g.Display.Message("State dumped", 2, screen.PriorityInfo)
+	log.Infof("state dumped to %s.txt and %s.png", base, base)
+}
+
+// DumpVRAM saves all tiles and the current background map to PNG files in
+// the save directory, named after the -filename template, shaded with the
+// current palette.
+func (g *GameBoy) DumpVRAM(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+
+	base := g.captureName(g.args.SaveDir, "-tiles.png", "-bg.png")
+	palette := g.palette
+	if palette == nil {
+		palette = screen.DefaultPalette
+	}
+
+	for suffix, img := range map[string]image.Image{
+		"-tiles.png": g.PPU.DumpTiles(palette),
+		"-bg.png":    g.PPU.DumpBackground(g.PPU.BGMap(), palette),
+	} {
+		if err := savePNG(base+suffix, img); err != nil {
+			log.Warningf("can't dump VRAM: %v", err)
+			g.Display.Message("VRAM dump failed", 2, screen.PriorityError)
+			return
+		}
+	}
+	g.Display.Message("VRAM dumped", 2, screen.PriorityInfo)
+	log.Infof("VRAM dumped to %s-tiles.png and %s-bg.png", base, base)
+}
+
+// StartStopRecord starts recording video output to GIF (named after the
+// -filename template) and closes the file when done. Defined as a single
+// action to toggle between the two and avoid opening several GIFs at once.
 func (g *GameBoy) StartStopRecord(eventType uint32) {
-	if eventType != sdl.KEYDOWN {
+	if eventType != KeyDown {
 		return
 	}
 
@@ -78,12 +144,153 @@ func (g *GameBoy) StartStopRecord(eventType uint32) {
 		g.Display.StopRecord()
 		g.recording = false
 	} else {
-		// Build a nice enough filename. TODO: configurable path.
-		filename := fmt.Sprintf("goholint-%s-%d.gif", time.Now().Format(DateFormat),
-			g.CPU.Cycle)
+		filename := g.captureName("", ".gif") + ".gif"
 		g.recording = true
 		g.Display.Record(filename)
 	}
 }
 
+// ToggleUI hides or shows the UI overlay, e.g. for clean recordings.
+func (g *GameBoy) ToggleUI(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+	g.Display.ToggleUI()
+}
+
+// ToggleFullscreen switches between windowed and fullscreen display.
+func (g *GameBoy) ToggleFullscreen(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+	g.Display.ToggleFullscreen()
+}
+
+// TogglePause suspends or resumes emulation.
+func (g *GameBoy) TogglePause(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+	g.paused = !g.paused
+	g.focusPaused = false
+	g.stepping = false
+	if g.paused {
+		g.Display.Text("Paused")
+	} else {
+		g.Display.Text("")
+	}
+}
+
+// CyclePalette switches to the next built-in palette (see
+// screen.PalettePresets), wrapping around after the last one.
+func (g *GameBoy) CyclePalette(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+	g.preset = (g.preset + 1) % len(screen.PalettePresets)
+	preset := screen.PalettePresets[g.preset]
+
+	g.setPalette(preset.Palette)
+	g.Display.Message("Palette: "+preset.Name, 2, screen.PriorityInfo)
+}
+
+// SpeedUp makes emulation faster by one step (see SpeedStep), up to MaxSpeed.
+func (g *GameBoy) SpeedUp(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+	g.SetSpeed(g.Speed() + SpeedStep)
+	g.Display.Message(fmt.Sprintf("Speed: %gx", g.Speed()), 2, screen.PriorityInfo)
+}
+
+// SpeedDown makes emulation slower by one step (see SpeedStep), down to
+// MinSpeed.
+func (g *GameBoy) SpeedDown(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+	g.SetSpeed(g.Speed() - SpeedStep)
+	g.Display.Message(fmt.Sprintf("Speed: %gx", g.Speed()), 2, screen.PriorityInfo)
+}
+
+// StepFrame runs emulation until the next VBlank then pauses again. Only
+// available while paused.
+func (g *GameBoy) StepFrame(eventType uint32) {
+	if eventType != KeyDown || !g.paused {
+		return
+	}
+	g.paused = false
+	g.stepping = true
+}
+
+// OpenROM pauses emulation and lists ROM files from the folder set with
+// -romdir (or the current ROM's folder) in the UI overlay, so another ROM can
+// be picked with the arrow keys and loaded with Enter. Escape cancels.
+func (g *GameBoy) OpenROM(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+
+	dir := g.romDir()
+	names, err := ListROMs(dir)
+	if err != nil {
+		log.Warningf("can't list ROMs: %v", err)
+		g.Display.Message("Can't list ROMs", 2, screen.PriorityError)
+		return
+	}
+	if len(names) == 0 {
+		g.Display.Message("No ROM in "+dir, 2, screen.PriorityWarning)
+		return
+	}
+
+	g.browser = &romBrowser{dir: dir, names: names, paused: g.paused}
+	g.paused = true
+	g.stepping = false
+	g.Display.Text(g.browser.String())
+}
+
+// Reboot restarts the current ROM from the boot ROM (as selected with -boot),
+// even when running with -fastboot, e.g. to watch the logo scroll. Cartridge
+// RAM is saved first. Input scripts and movies start over from the first frame.
+func (g *GameBoy) Reboot(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+
+	g.saveCartridge()
+	g.saveMovie()
+
+	g.reboot = true
+	g.restart()
+	g.reboot = false
+	g.Display.Message("Rebooting", 2, screen.PriorityInfo)
+}
+
 // TODO: so many things! Save states, toggle features...
+
+// ToggleBackground hides or shows the background layer, for debugging.
+func (g *GameBoy) ToggleBackground(eventType uint32) {
+	g.toggleLayer(eventType, ppu.LayerBackground, "Background")
+}
+
+// ToggleWindow hides or shows the window layer, for debugging.
+func (g *GameBoy) ToggleWindow(eventType uint32) {
+	g.toggleLayer(eventType, ppu.LayerWindow, "Window")
+}
+
+// ToggleSprites hides or shows the sprite layer, for debugging.
+func (g *GameBoy) ToggleSprites(eventType uint32) {
+	g.toggleLayer(eventType, ppu.LayerSprites, "Sprites")
+}
+
+// Hides or shows a PPU layer and says which.
+func (g *GameBoy) toggleLayer(eventType uint32, layer ppu.Layer, name string) {
+	if eventType != KeyDown {
+		return
+	}
+	if g.PPU.ToggleLayer(layer) {
+		g.Display.Message(name+" shown", 2, screen.PriorityInfo)
+	} else {
+		g.Display.Message(name+" hidden", 2, screen.PriorityInfo)
+	}
+}
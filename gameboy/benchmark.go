@@ -0,0 +1,58 @@
+package gameboy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lazy-stripes/goholint/options"
+)
+
+// BenchmarkResult holds emulation throughput measured by Benchmark.
+type BenchmarkResult struct {
+	Frames  uint64        // Frames actually emulated
+	Ticks   uint64        // Machine ticks actually emulated
+	Batch   uint          // PPU dots per batch (see -batch)
+	Elapsed time.Duration // Real time it took
+}
+
+// FPS returns the number of frames emulated per second of real time.
+func (r BenchmarkResult) FPS() float64 {
+	return float64(r.Frames) / r.Elapsed.Seconds()
+}
+
+// MHz returns the emulated machine clock rate, in millions of ticks per second
+// of real time (about 4.19 when running at 1x).
+func (r BenchmarkResult) MHz() float64 {
+	return float64(r.Ticks) / r.Elapsed.Seconds() / 1e6
+}
+
+// String returns a one-line summary of the results.
+func (r BenchmarkResult) String() string {
+	return fmt.Sprintf("%d frames in %v (batch %d): %.1f frames/s, %.2f emulated MHz",
+		r.Frames, r.Elapsed.Round(time.Millisecond), r.Batch, r.FPS(), r.MHz())
+}
+
+// Benchmark runs the ROM set in the given options for that many frames (see
+// FrameTicks) as fast as possible, with a headless display and audio going to
+// a NullAudio sink, and reports how long it took. PPU dots are batched as set
+// with -batch. It stops early if the emulator quits (e.g. see -cycles).
+func Benchmark(args *options.Options, frames uint) BenchmarkResult {
+	g, _ := NewHeadless(args)
+	g.Serial.Record = false // Nobody's going to look at it
+
+	res := BenchmarkResult{Batch: args.Batch}
+	if res.Batch == 0 {
+		res.Batch = 1
+	}
+
+	start := time.Now()
+	for target := uint64(frames) * FrameTicks; g.Ticks() < target; {
+		if g.Tick().Quit {
+			break
+		}
+	}
+	res.Elapsed = time.Since(start)
+	res.Ticks = g.Ticks()
+	res.Frames = g.Ticks() / FrameTicks
+	return res
+}
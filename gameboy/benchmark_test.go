@@ -0,0 +1,27 @@
+package gameboy
+
+import "testing"
+
+func TestBenchmark(t *testing.T) {
+	args, done := testOptions(t, []byte{0x18, 0xfe}) // JR -2
+	defer done()
+
+	for _, batch := range []uint{0, 8} {
+		args.Batch = batch
+		res := Benchmark(args, 30)
+		if res.Frames != 30 || res.Ticks != 30*FrameTicks {
+			t.Errorf("batch %d: benchmark ran %d frames (%d ticks), want 30 (%d)",
+				batch, res.Frames, res.Ticks, 30*FrameTicks)
+		}
+		if res.Elapsed <= 0 || res.FPS() <= 0 || res.MHz() <= 0 {
+			t.Errorf("batch %d: no throughput reported: %s", batch, res)
+		}
+	}
+
+	// Emulation stopping early is reported as is.
+	args.Batch = 0
+	args.Duration = 10 * FrameTicks
+	if res := Benchmark(args, 30); res.Frames != 10 {
+		t.Errorf("benchmark stopped after %d frames, want 10", res.Frames)
+	}
+}
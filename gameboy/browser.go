@@ -0,0 +1,147 @@
+package gameboy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// Number of ROM names shown at once in the browser.
+const browserRows = 8
+
+// ListROMs returns the names of ROM files found in the given folder, sorted
+// regardless of case. Sub-folders, hidden files and files without a ROM
+// extension (see memory.ROMExtensions) are skipped.
+func ListROMs(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		for _, romExt := range memory.ROMExtensions {
+			if ext == romExt {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names, nil
+}
+
+// Keyboard-navigable list of ROM files, shown in the UI overlay.
+type romBrowser struct {
+	dir      string
+	names    []string
+	selected int
+	paused   bool // Whether emulation was paused before opening the browser
+}
+
+// Moves the selection by the given amount, stopping at either end of the
+// list.
+func (b *romBrowser) move(delta int) {
+	b.selected += delta
+	if b.selected >= len(b.names) {
+		b.selected = len(b.names) - 1
+	}
+	if b.selected < 0 {
+		b.selected = 0
+	}
+}
+
+// Returns the path to the selected ROM.
+func (b *romBrowser) path() string {
+	return filepath.Join(b.dir, b.names[b.selected])
+}
+
+// String returns the names around the current selection, one per line, with
+// the selected one marked.
+func (b *romBrowser) String() string {
+	first := b.selected - browserRows/2
+	if first > len(b.names)-browserRows {
+		first = len(b.names) - browserRows
+	}
+	if first < 0 {
+		first = 0
+	}
+	last := first + browserRows
+	if last > len(b.names) {
+		last = len(b.names)
+	}
+
+	lines := []string{fmt.Sprintf("Open ROM (%d/%d)", b.selected+1, len(b.names))}
+	for i := first; i < last; i++ {
+		marker := "  "
+		if i == b.selected {
+			marker = "> "
+		}
+		lines = append(lines, marker+b.names[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the folder to browse: the one set with -romdir, or the current
+// ROM's folder, or the current folder.
+func (g *GameBoy) romDir() string {
+	if g.args.ROMDir != "" {
+		return g.args.ROMDir
+	}
+	if g.args.ROMPath != "" {
+		return filepath.Dir(g.args.ROMPath)
+	}
+	return "."
+}
+
+// Handles key presses while the ROM browser is open, given the key's name in
+// lowercase: arrows move the selection, Enter loads the selected ROM and Escape
+// closes the browser.
+func (g *GameBoy) browseKey(key string) {
+	switch key {
+	case "up":
+		g.browser.move(-1)
+	case "down":
+		g.browser.move(1)
+	case "pageup":
+		g.browser.move(-browserRows)
+	case "pagedown":
+		g.browser.move(browserRows)
+	case "return":
+		path := g.browser.path()
+		g.closeBrowser(false)
+		g.LoadROM(path)
+		g.Display.Message("Loaded "+filepath.Base(path), 2, screen.PriorityInfo)
+		return
+	case "escape":
+		g.closeBrowser(g.browser.paused)
+		return
+	default:
+		return
+	}
+	g.Display.Text(g.browser.String())
+}
+
+// Closes the ROM browser and resumes emulation unless told to stay paused.
+func (g *GameBoy) closeBrowser(paused bool) {
+	g.browser = nil
+	g.paused = paused
+	if paused {
+		g.Display.Text("Paused")
+	} else {
+		g.Display.Text("")
+	}
+}
@@ -0,0 +1,110 @@
+package gameboy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListROMs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"tetris.gb", "Zelda.GBC", "alleyway.gb",
+		"tetris.gb.sav", "notes.txt", ".hidden.gb", "README"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "folder.gb"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := ListROMs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"alleyway.gb", "tetris.gb", "Zelda.GBC"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("listed %q, want %q", names, expected)
+	}
+
+	if _, err := ListROMs(filepath.Join(dir, "nope")); err == nil {
+		t.Error("no error listing a missing folder")
+	}
+}
+
+func TestROMBrowser(t *testing.T) {
+	b := romBrowser{dir: "roms"}
+	for i := 0; i < 20; i++ {
+		b.names = append(b.names, string(rune('a'+i))+".gb")
+	}
+
+	b.move(-1)
+	if b.selected != 0 {
+		t.Errorf("selected #%d after moving up from the top, want #0", b.selected)
+	}
+	b.move(25)
+	if b.selected != 19 {
+		t.Errorf("selected #%d after moving past the bottom, want #19", b.selected)
+	}
+	if b.path() != filepath.Join("roms", "t.gb") {
+		t.Errorf("selected path is %s", b.path())
+	}
+
+	// The list scrolls to keep the selection visible.
+	b.move(-10)
+	lines := strings.Split(b.String(), "\n")
+	if len(lines) != browserRows+1 {
+		t.Fatalf("browser shows %d lines, want %d", len(lines), browserRows+1)
+	}
+	if lines[0] != "Open ROM (10/20)" || lines[1] != "  f.gb" || lines[5] != "> j.gb" {
+		t.Errorf("unexpected browser text:\n%s", b.String())
+	}
+}
+
+func TestLoadROM(t *testing.T) {
+	// The first ROM loops forever, the second one sets A to 0x42 first.
+	args, done := testOptions(t, []byte{0x18, 0xfe}) // JR -2
+	defer done()
+	dir := args.SaveDir
+	first := filepath.Join(dir, "first.gb")
+	if err := os.Rename(args.ROMPath, first); err != nil {
+		t.Fatal(err)
+	}
+	args.ROMPath = first
+	code := []byte{0x3e, 0x42, 0x18, 0xfe} // LD A,$42; JR -2
+	second := writeTestROM(t, dir, code, 0)
+
+	g := NewWithDisplay(args, &nullDisplay{}, nil)
+	for i := 0; i < FrameTicks; i++ {
+		g.Tick()
+	}
+
+	// Open the browser, move to the second ROM and load it.
+	g.OpenROM(KeyDown)
+	if g.browser == nil || !g.paused {
+		t.Fatal("browser not open or emulation not paused")
+	}
+	g.browseKey("down")
+	g.browseKey("return")
+	if g.browser != nil || g.paused {
+		t.Fatal("browser still open or emulation still paused")
+	}
+	if g.Ticks() != 0 || args.ROMPath != second {
+		t.Fatalf("emulator not restarted with %s", second)
+	}
+
+	for i := 0; i < FrameTicks; i++ {
+		g.Tick()
+	}
+	if g.CPU.A != 0x42 {
+		t.Errorf("A == 0x%02x after loading second ROM, want 0x42", g.CPU.A)
+	}
+}
@@ -0,0 +1,58 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// Display keeping every frame drawn, as a program embedding the emulator would.
+type recordingDisplay struct {
+	nullDisplay
+	frame  []uint8
+	frames [][]uint8
+}
+
+func (d *recordingDisplay) Write(colorIndex uint8) {
+	d.frame = append(d.frame, colorIndex)
+}
+
+func (d *recordingDisplay) VBlank() {
+	if len(d.frame) > 0 {
+		d.frames = append(d.frames, d.frame)
+	}
+	d.frame = nil
+}
+
+// Audio sink counting samples.
+type countingSink struct{ samples int }
+
+func (s *countingSink) Play(left, right uint8) { s.samples++ }
+
+func TestEmbedding(t *testing.T) {
+	args, done := testOptions(t, []byte{0x18, 0xfe}) // JR -2
+	defer done()
+
+	args.SamplingRate = 22050
+	display := &recordingDisplay{}
+	sink := &countingSink{}
+	g := NewWithDisplay(args, display, sink)
+
+	for i := 0; i < 3*FrameTicks; i++ {
+		g.Tick()
+	}
+
+	if len(display.frames) == 0 {
+		t.Fatal("no frame drawn to the custom display")
+	}
+	for i, frame := range display.frames {
+		if len(frame) != screen.ScreenWidth*screen.ScreenHeight {
+			t.Errorf("frame %d has %d pixels", i, len(frame))
+		}
+	}
+
+	// About 3/60th of a second of audio.
+	if expected := 22050 * 3 / 60; sink.samples < expected-50 || sink.samples > expected+50 {
+		t.Errorf("audio sink got %d samples, expected about %d", sink.samples, expected)
+	}
+}
@@ -0,0 +1,88 @@
+package gameboy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lazy-stripes/goholint/memory"
+)
+
+// DefaultFilename is the template used to name screenshots, recordings and
+// dumps when none is set with -filename.
+const DefaultFilename = "goholint-{date}-{cycle}"
+
+// ExpandFilename replaces placeholders such as {title} or {date} in a file
+// name template with their value in fields. The {n} placeholder is replaced
+// with the smallest number from 1 such that none of the files named after the
+// result and each of the given suffixes (e.g. ".png") already exist, so that
+// captures are never overwritten. Unknown placeholders are left as is.
+func ExpandFilename(template string, fields map[string]string, suffixes ...string) string {
+	name := template
+	for field, value := range fields {
+		name = strings.Replace(name, "{"+field+"}", value, -1)
+	}
+	if !strings.Contains(name, "{n}") {
+		return name
+	}
+
+	for n := 1; ; n++ {
+		numbered := strings.Replace(name, "{n}", strconv.Itoa(n), -1)
+		if !anyExists(numbered, suffixes) {
+			return numbered
+		}
+	}
+}
+
+// Returns whether a file named after base and one of the suffixes exists.
+func anyExists(base string, suffixes []string) bool {
+	if len(suffixes) == 0 {
+		suffixes = []string{""}
+	}
+	for _, suffix := range suffixes {
+		if _, err := os.Stat(base + suffix); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the file name template's value for the cartridge's title: letters,
+// digits, dashes and underscores only, or "untitled" if there is none.
+func safeTitle(cart memory.Addressable) string {
+	if cart == nil {
+		return "untitled"
+	}
+	title := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, memory.ReadHeader(cart).Title)
+	if title == "" {
+		return "untitled"
+	}
+	return title
+}
+
+// Returns the base name for a capture made of files with the given suffixes,
+// built from the -filename template and placed in dir unless the template
+// holds an absolute path.
+func (g *GameBoy) captureName(dir string, suffixes ...string) string {
+	template := g.args.Filename
+	if template == "" {
+		template = DefaultFilename
+	}
+	if dir != "" && !filepath.IsAbs(template) {
+		template = filepath.Join(dir, template)
+	}
+	return ExpandFilename(template, map[string]string{
+		"title": safeTitle(g.cart),
+		"date":  time.Now().Format(DateFormat),
+		"cycle": strconv.FormatUint(uint64(g.CPU.Cycle), 10),
+	}, suffixes...)
+}
@@ -0,0 +1,57 @@
+package gameboy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/options"
+)
+
+func TestExpandFilename(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fields := map[string]string{"title": "TETRIS", "date": "2020-01-02-03-04-05"}
+	template := filepath.Join(dir, "{title}-{date}-{n}{other}")
+	want := filepath.Join(dir, "TETRIS-2020-01-02-03-04-05-1{other}")
+	if name := ExpandFilename(template, fields, ".png"); name != want {
+		t.Errorf("ExpandFilename() == %q, want %q", name, want)
+	}
+
+	// Numbers already taken by any of the suffixes are skipped.
+	for _, file := range []string{"TETRIS-1.png", "TETRIS-2.txt", "TETRIS-4.png"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	template = filepath.Join(dir, "{title}-{n}")
+	if name := ExpandFilename(template, fields, ".txt", ".png"); name != filepath.Join(dir, "TETRIS-3") {
+		t.Errorf("ExpandFilename() == %q, want TETRIS-3", filepath.Base(name))
+	}
+	if name := ExpandFilename(template, fields, ".png"); name != filepath.Join(dir, "TETRIS-2") {
+		t.Errorf("ExpandFilename() == %q, want TETRIS-2", filepath.Base(name))
+	}
+}
+
+func TestCaptureName(t *testing.T) {
+	code := []byte{0x18, 0xfe} // JR -2
+	g, _, done := startTestGameBoy(t, code, func(args *options.Options) {
+		args.Filename = "{title}-{n}"
+	})
+	defer done()
+	dir := g.args.SaveDir
+
+	g.DumpVRAM(KeyDown)
+	g.DumpVRAM(KeyDown)
+
+	for _, file := range []string{"untitled-1-tiles.png", "untitled-2-bg.png"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("capture not saved: %v", err)
+		}
+	}
+}
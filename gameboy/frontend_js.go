@@ -0,0 +1,25 @@
+//go:build js && wasm
+// +build js,wasm
+
+package gameboy
+
+// Browser frontends drive the emulator through NewWithDisplay, Tick and JPad
+// (see joypad.ListenKeyboard), so there is nothing to poll or close here.
+type frontend struct{}
+
+// Nothing to poll, events reach the page instead.
+func (g *GameBoy) pollEvents() (quit bool) {
+	return false
+}
+
+// ToggleVRAMViewer is not available without SDL.
+func (g *GameBoy) ToggleVRAMViewer(eventType uint32) {
+	if eventType == KeyDown {
+		log.Warning("VRAM viewer not available in this build")
+	}
+}
+
+// No debug windows or audio callback to deal with.
+func (g *GameBoy) updateViewer() {}
+func (g *GameBoy) stopAudio()    {}
+func (g *GameBoy) closeWindows() {}
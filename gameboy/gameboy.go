@@ -1,10 +1,18 @@
 package gameboy
 
 import (
+	"bytes"
 	"fmt"
+	"image/color"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/lazy-stripes/goholint/apu"
+	"github.com/lazy-stripes/goholint/clock"
 	"github.com/lazy-stripes/goholint/cpu"
 	"github.com/lazy-stripes/goholint/interrupts"
 	"github.com/lazy-stripes/goholint/joypad"
@@ -14,8 +22,8 @@ import (
 	"github.com/lazy-stripes/goholint/ppu"
 	"github.com/lazy-stripes/goholint/screen"
 	"github.com/lazy-stripes/goholint/serial"
+	"github.com/lazy-stripes/goholint/symbols"
 	"github.com/lazy-stripes/goholint/timer"
-	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Package-wide logger.
@@ -24,125 +32,412 @@ var log = logger.New("gameboy", "interface-related logs")
 // DateFormat layout for generated file names.
 const DateFormat = "2006-01-02-15-04-05"
 
+// FrameTicks is the number of machine ticks in a full frame (154 lines of 456
+// dots each).
+const FrameTicks = 154 * 456
+
 // TickResult type to group return values from Tick.
 type TickResult struct {
 	Left, Right uint8
 	Play, Quit  bool
 }
 
+// AudioSink receives audio samples as the emulator produces them, for programs
+// embedding it without an SDL audio callback (see NewWithDisplay). Samples are
+// unsigned, with silence at 128.
+type AudioSink interface {
+	Play(left, right uint8)
+}
+
 // GameBoy structure grouping all our state machines to tick them together.
 type GameBoy struct {
 	args *options.Options
 
-	ticks   uint64
+	Mode    Mode
+	clock   *clock.Manual // Ticks subsystems, counting machine ticks (see newClock)
 	APU     *apu.APU
 	CPU     *cpu.CPU
 	PPU     *ppu.PPU
 	Display screen.Display // Interface, not pointer.
+	Audio   AudioSink      // Optional, fed by Tick.
 	DMA     *memory.DMA
+	HDMA    *memory.HDMA // CGB only, nil otherwise.
 	Serial  *serial.Serial
 	Timer   *timer.Timer
 	JPad    *joypad.Joypad
 
-	Controls map[sdl.Keycode]Action
+	// Inputs to replay, indexed by frame (see FrameTicks), if any.
+	Script joypad.Script
+
+	// OnFrame, if set, is called after each frame with timing telemetry.
+	OnFrame func(FrameStats)
+
+	// Cartridge address space (nil if none), kept to flush saves on shutdown.
+	cart memory.Addressable
+
+	// Makes sure Shutdown only runs once.
+	shutdown sync.Once
+
+	// Movie being recorded and button changes to record at the next frame.
+	movie   *Movie
+	pending map[string]bool
+
+	// Actions by key name, in lowercase (see SetControls).
+	Controls map[string]Action
 
 	// For GIF record toggle.
 	recording bool
+
+	// Set when hitting a breakpoint configured to stop the emulator.
+	halted bool
+
+	// Set while restarting with the reboot action, to run the boot ROM even
+	// with -fastboot.
+	reboot bool
+
+	// Whether to poll SDL events in Tick (false when running headless).
+	events bool
+
+	// Colors for the shades used in debug views (nil for default colors).
+	palette color.Palette
+
+	// Corrections applied to palettes (see -brightness, -contrast, -tint).
+	adjust screen.Adjust
+
+	// Saves every Nth frame to a PNG file when set (see -timelapse).
+	timeLapse *timeLapse
+
+	// Streams frames to a file or pipe when set (see -rawframes).
+	rawFrames *rawFrames
+
+	// Labels from the ROM's symbol file, if any (see -sym), and breakpoints
+	// set by address or label (see -breakat).
+	symbols     *symbols.Table
+	breakpoints map[uint16][]symbols.Symbol
+
+	// Emulation speed as a multiple of real time (zero for 1x, see SetSpeed).
+	speed float64
+
+	// Turbo buttons, if any (see -turbo).
+	autofire *autofire
+
+	// Index of the last palette selected with the cyclepalette action.
+	preset int
+
+	// Frontend-specific state, such as debug windows.
+	frontend
+
+	// ROM browser, only set while open.
+	browser *romBrowser
+
+	// Emulation is suspended while paused, except for event polling. When
+	// stepping, emulation runs until the next VBlank then pauses again.
+	paused    bool
+	stepping  bool
+	idleTicks uint64
+
+	// Set when emulation was paused because the window lost focus, so it only
+	// resumes by itself in that case.
+	focusPaused bool
+
+	// PPU dots not yet ticked when batching them (see -batch).
+	pendingDots uint
+
+	// Frame telemetry (see OnFrame).
+	frames     uint64
+	frameStart time.Time
+	renderTime time.Duration
 }
 
-// SetControls validates and sets the given control map for the emulator.
+// SetControls sets the given control map for the emulator. Key names are
+// matched regardless of case, and it's up to the frontend to give key events
+// the same names (see pollEvents).
 func (g *GameBoy) SetControls(keymap options.Keymap) (err error) {
 	// Intermediate mapping between labels and actual actions. This feels
 	// unnecessarily complicated, but should make sense when I start translating
 	// these from a config file. I hope.
 	actions := map[string]Action{
-		"up":         g.JoypadUp,
-		"down":       g.JoypadDown,
-		"left":       g.JoypadLeft,
-		"right":      g.JoypadRight,
-		"a":          g.JoypadA,
-		"b":          g.JoypadB,
-		"select":     g.JoypadSelect,
-		"start":      g.JoypadStart,
-		"screenshot": g.Screenshot,
-		"recordgif":  g.StartStopRecord,
-	}
-
-	g.Controls = make(map[sdl.Keycode]Action)
-	for label, keyCode := range keymap {
-		g.Controls[keyCode] = actions[label]
+		"up":           g.JoypadUp,
+		"down":         g.JoypadDown,
+		"left":         g.JoypadLeft,
+		"right":        g.JoypadRight,
+		"a":            g.JoypadA,
+		"b":            g.JoypadB,
+		"select":       g.JoypadSelect,
+		"start":        g.JoypadStart,
+		"screenshot":   g.Screenshot,
+		"recordgif":    g.StartStopRecord,
+		"toggleui":     g.ToggleUI,
+		"fullscreen":   g.ToggleFullscreen,
+		"pause":        g.TogglePause,
+		"stepframe":    g.StepFrame,
+		"vramviewer":   g.ToggleVRAMViewer,
+		"dumpstate":    g.DumpState,
+		"openrom":      g.OpenROM,
+		"cyclepalette": g.CyclePalette,
+		"speedup":      g.SpeedUp,
+		"speeddown":    g.SpeedDown,
+		"turbo":        g.ToggleTurbo,
+		"togglebg":     g.ToggleBackground,
+		"togglewindow": g.ToggleWindow,
+		"togglesprite": g.ToggleSprites,
+		"dumpvram":     g.DumpVRAM,
+		"reboot":       g.Reboot,
+	}
+
+	g.Controls = make(map[string]Action)
+	for label, keyName := range keymap {
+		g.Controls[strings.ToLower(keyName)] = actions[label]
 	}
 	return nil
 }
 
-// New just instantiates most of the emulator. No biggie.
-func New(args *options.Options) *GameBoy {
-	g := GameBoy{args: args}
+// NewHeadless instantiates the emulator with a display rendering to memory
+// only and without polling SDL events, for tests and tools that don't need a
+// window. Audio goes to a NullAudio sink. The serial port keeps a copy of all
+// transferred bytes.
+func NewHeadless(args *options.Options) (*GameBoy, *screen.Headless) {
+	display := screen.NewHeadless()
+	display.SetRecordSkip(args.GIFSkip)
+	display.SetGhosting(ghostDecay(args))
+	display.SetFlip(outputFlip(args))
+	if blank := blankScreen(args, screen.DefaultPalette); blank != nil {
+		display.SetBlank(blank)
+	}
+	audio := &NullAudio{}
+	g := NewWithDisplay(args, display, audio)
+	audio.Rate = g.APU.SamplingRate
+	g.Serial.Record = true
+	return g, display
+}
 
+// Returns the disabled screen generator set with -blankscreen, or nil to keep
+// the display's default.
+func blankScreen(args *options.Options, palette color.Palette) screen.BlankFrame {
+	if args.BlankScreen == "" {
+		return nil
+	}
+	blank, err := screen.ParseBlank(args.BlankScreen, palette)
+	if err != nil {
+		log.Warningf("%v", err)
+		return nil
+	}
+	return blank
+}
+
+// Returns the fraction of each frame kept in the next in GIF recordings, as set
+// with -ghosting.
+func ghostDecay(args *options.Options) float64 {
+	if args.Ghosting > 100 {
+		log.Warningf("ghosting %d%% is more than 100%%, using 100%%", args.Ghosting)
+		return 1
+	}
+	return float64(args.Ghosting) / 100
+}
+
+// Returns the color corrections set with -brightness, -contrast and -tint.
+func colorAdjust(args *options.Options) screen.Adjust {
+	tint, err := screen.ParseTint(args.Tint)
+	if err != nil {
+		log.Warningf("%v, not tinting", err)
+	}
+	return screen.Adjust{Brightness: args.Brightness, Contrast: args.Contrast, Tint: tint}
+}
+
+// Applies color corrections to the given palette, then uses it for the display
+// (if it has actual colors) and debug views.
+func (g *GameBoy) setPalette(palette color.Palette) {
+	if !g.adjust.IsZero() {
+		palette = g.adjust.Palette(palette)
+	}
+	if display, ok := g.Display.(interface{ SetPalette(color.Palette) }); ok {
+		display.SetPalette(palette)
+	}
+	g.palette = palette
+}
+
+// Returns the output transform set with -flip, or none if it's invalid.
+func outputFlip(args *options.Options) screen.Flip {
+	flip, err := screen.ParseFlip(args.Flip)
+	if err != nil {
+		log.Warningf("%v", err)
+	}
+	return flip
+}
+
+// NewWithDisplay instantiates the emulator with the given display and audio
+// sink (which can be nil), without polling SDL events. This is how goholint
+// can be embedded in other programs, which are then in charge of calling Tick
+// at the right pace and handling inputs through JPad.
+func NewWithDisplay(args *options.Options, display screen.Display, audio AudioSink) *GameBoy {
+	g := GameBoy{args: args, Display: display, Audio: audio}
 	g.SetControls(args.Keymap)
+	g.setup()
+	return &g
+}
+
+// Builds the whole machine from scratch, using the ROM and settings from the
+// emulator's options. Called again to restart with another ROM.
+func (g *GameBoy) setup() {
+	args := g.args
+
+	// Power-up RAM contents, set before any RAM is created.
+	pattern, err := memory.ParseFill(args.RAMFill)
+	if err != nil {
+		log.Warningf("%v, using default", err)
+	}
+	fill := memory.NewFill(pattern, args.Seed)
 
 	// Create CPU and interrupts first so other components can access them too.
 	g.CPU = cpu.New(nil)
+	if args.Breakpoint != "" {
+		g.CPU.Breakpoint = g.Breakpoint
+	}
+	if args.OpProfile {
+		g.CPU.EnableProfile()
+	}
 	ints := interrupts.New(&g.CPU.IF, &g.CPU.IE)
 
-	g.APU = apu.New()
+	g.APU = apu.New(args.SamplingRate)
+	g.SetSpeed(g.Speed())
+	g.APU.Wave.Interpolate = args.WaveInterp
+	if args.ForceAudio {
+		g.APU.ForceChannels(true)
+	}
 
-	// TODO: merge GIF encoder in UI/Screen instance.
-	g.Display = screen.NewSDL(args.ZoomFactor, args.VSync)
 	if args.GIFPath != "" {
 		//g.Display.Record(args.GIFPath)
-		fmt.Printf("Saving GIF to %s\n", args.GIFPath)
+		log.Infof("Saving GIF to %s", args.GIFPath)
 	}
 
-	g.PPU = ppu.New(g.Display)
+	var display screen.Display = timedDisplay{g.Display, &g.renderTime}
+	if args.TimeLapse > 0 {
+		if g.timeLapse != nil {
+			g.timeLapse.wait()
+		}
+		g.timeLapse = newTimeLapse(display, args.TimeLapse, args.SaveDir, &g.palette, outputFlip(args))
+		display = g.timeLapse
+	}
+	if args.RawFrames != "" {
+		// Keep streaming to the same output when loading another ROM.
+		if g.rawFrames != nil {
+			g.rawFrames.Display = display
+			display = g.rawFrames
+		} else if raw, err := newRawFrames(display, args.RawFrames, args.RawFormat, &g.palette, outputFlip(args)); err == nil {
+			g.rawFrames = raw
+			display = raw
+		} else {
+			log.Warningf("can't stream raw frames: %v", err)
+		}
+	}
+	g.PPU = ppu.New(display, fill)
+	g.pendingDots = 0
 	g.PPU.Interrupts = ints
+	g.PPU.OnVBlank = g.vblank
 
 	g.Serial = serial.New()
+	g.Serial.Record = args.ExitCode == "serial"
+	g.Serial.Interrupts = ints
 	g.Timer = timer.New()
 	g.Timer.Interrupts = ints
 
+	// Load cartridge first, we'll need its header to set things up.
+	var cart memory.Addressable
+	var cgbFlag uint8
+	if args.ROMPath != "" {
+		// Build save path in case the cartridge uses one. Or use one
+		// specified by the user.
+		savePath := args.SavePath
+		if savePath == "" {
+			// The user could also just specify a path to a save folder.
+			prefix := args.SaveDir
+			if prefix == "" {
+				prefix = filepath.Dir(args.ROMPath)
+			}
+			suffix := filepath.Base(args.ROMPath)
+			if args.ROMPath == memory.StdinPath {
+				suffix = "stdin" // Saved in the current folder by default.
+			}
+			savePath = prefix + "/" + suffix + ".sav"
+		}
+		// TODO: save-related error management.
+		if args.MBC != "" {
+			var err error
+			if cart, err = memory.NewForcedCartridge(args.ROMPath, savePath, args.MBC); err != nil {
+				log.Warningf("%v, using the header's cartridge type", err)
+			}
+		}
+		if cart == nil {
+			cart = memory.NewCartridge(args.ROMPath, savePath)
+		}
+
+		if camera, ok := cart.(*memory.Camera); ok {
+			pattern, err := memory.ParseCameraPattern(args.Camera, args.Seed)
+			if err != nil {
+				log.Warningf("%v, using default", err)
+			} else {
+				camera.Pattern = pattern
+			}
+		}
+
+		header := memory.ReadHeader(cart)
+		log.Infof("Cartridge: %s", header)
+		cgbFlag = header.CGBFlag
+	}
+
+	// Skip the boot ROM if requested or if there is none to run.
+	bootROM := SelectBootROM(args.BootROM, cgbFlag, args.ForceDMG)
+	fastBoot := args.FastBoot && !g.reboot
+	if !fastBoot {
+		if _, err := os.Stat(bootROM); err != nil {
+			log.Warningf("no boot ROM (%v), skipping boot sequence", err)
+			fastBoot = true
+		}
+	}
+
 	var boot memory.Addressable
-	if args.FastBoot {
+	bootSize := 0
+	if fastBoot {
 		// TODO: just implement save states, at this point.
-
-		// XXX: What the BootROM does RAM-wise:
-		// - Zero out/write logo tiles to 0x8000->0x9fff
-		// - Write to audio registers
-		// - Write to PPU registers
-		// - Write to stack
+		// XXX: The boot ROM also zeroes out VRAM and writes logo tiles there.
 		boot = memory.NewRAM(memory.BootAddr, 1)
 		boot.Write(memory.BootAddr, 0x01)
-
-		// Values below are what the CPU contains after booting the DMG ROM.
-		g.CPU.A = 0x01
-		g.CPU.F = 0xb0
-		g.CPU.B = 0x00
-		g.CPU.C = 0x13
-		g.CPU.D = 0x00
-		g.CPU.E = 0xd8
-		g.CPU.H = 0x01
-		g.CPU.L = 0x4d
-		g.CPU.PC = 0x0100
-		g.CPU.SP = 0xfffe
-
-		// FIXME: properly pre-initialize PPU.
-		//g.ppu.LCDC = 0x91
-		//g.ppu.LY = 0x96
-		//g.ppu.BGP = 0xfc
-
-		for addr := 0x8000; addr <= 0x9fff; addr++ {
-			// TODO: set RAM/VRAM
-		}
 	} else {
-		boot = memory.NewBoot(args.BootROM)
+		log.Debugf("Boot ROM: %s", bootROM)
+		b := memory.NewBoot(bootROM)
+		bootSize = len(b.ROM.Bytes)
+		boot = b
 	}
 
-	wram := memory.NewRAM(0xc000, 0x2000)
-	hram := memory.NewRAM(0xff80, 0x7e)
-	g.JPad = joypad.New() // TODO: interrupts
+	g.Mode = DetectMode(cgbFlag, bootSize, args.ForceDMG)
+	log.Infof("Running in %s mode", g.Mode)
+
+	wram := memory.NewWRAM(g.Mode == ModeCGB, fill)
+	hram := memory.NewFilledRAM(0xff80, 0x7e, fill)
+	g.JPad = joypad.New()
+	g.JPad.Interrupts = ints
+	g.JPad.OnPress = g.CPU.Wake
+	if args.SOCD != "" {
+		socd, err := joypad.ParseSOCD(args.SOCD)
+		if err != nil {
+			log.Warningf("%v, using raw inputs", err)
+		}
+		g.JPad.SOCD = socd
+	}
+	if args.InputScript != "" {
+		script, err := joypad.LoadScript(args.InputScript)
+		if err != nil {
+			log.Warningf("can't load input script: %v", err)
+		}
+		g.Script = script
+	}
+	if args.MoviePath != "" || args.RecordMovie != "" {
+		g.setupMovie()
+	}
+	g.autofire = newAutofire(args.Turbo, args.TurboRate)
 	g.DMA = &memory.DMA{}
-	mmu := memory.NewMMU([]memory.Addressable{
-		boot,
+	mmu := memory.NewEmptyMMU()
+	for _, space := range []memory.Addressable{
 		g.APU,
 		g.APU.Wave.Pattern,
 		g.PPU,
@@ -153,106 +448,351 @@ func New(args *options.Options) *GameBoy {
 		g.Timer,
 		g.DMA,
 		hram,
-	})
+	} {
+		mmu.Add(space)
+	}
 	g.DMA.MMU = mmu
 	g.CPU.MMU = mmu
+	if args.DMAStrict {
+		g.CPU.MMU = &memory.DMAGuard{Addressable: mmu, DMA: g.DMA}
+	}
+	if args.IOTrace != "" {
+		if filter, err := memory.ParseIOFilter(args.IOTrace); err == nil {
+			g.CPU.MMU = &memory.IOTrace{Addressable: g.CPU.MMU, PC: &g.CPU.PC,
+				Filter: filter, Output: os.Stdout}
+		} else {
+			log.Warningf("%v, not tracing I/O", err)
+		}
+	}
 
-	if args.ROMPath != "" {
-		// Build save path in case the cartridge uses one. Or use one
-		// specified by the user.
-		savePath := args.SavePath
-		if savePath == "" {
-			// The user could also just specify a path to a save folder.
-			prefix := args.SaveDir
-			if prefix == "" {
-				prefix = filepath.Dir(args.ROMPath)
-			}
-			suffix := filepath.Base(args.ROMPath)
-			savePath = prefix + "/" + suffix + ".sav"
+	// CGB-only registers.
+	g.HDMA = nil
+	if g.Mode == ModeCGB {
+		g.HDMA = memory.NewHDMA(mmu)
+		g.PPU.OnHBlank = func(uint8) { g.HDMA.HBlank() }
+		mmu.Add(g.HDMA)
+		mmu.Add(&g.CPU.Speed)
+		mmu.Add(&g.PPU.Priority)
+		g.PPU.Priority.OAMOrder = true
+		g.Serial.CGB = true
+	}
+
+	// The OAM corruption bug was fixed in the CGB.
+	if args.OAMBug && g.Mode == ModeDMG {
+		g.PPU.OAMBug = true
+		g.CPU.OAMBug = g.PPU.CorruptOAM
+	}
+
+	// So was the STAT write bug.
+	if args.STATBug && g.Mode == ModeDMG {
+		g.PPU.STATBug = true
+	}
+
+	if args.PaletteWrite != "" {
+		mode, err := ppu.ParsePaletteWrites(args.PaletteWrite)
+		if err != nil {
+			log.Warningf("%v, latching palette writes", err)
 		}
-		// TODO: save-related error management.
-		mmu.Add(memory.NewCartridge(args.ROMPath, savePath))
+		g.PPU.PaletteWrites = mode
 	}
 
-	return &g
+	g.PPU.WatchTileWrites(args.TileWatch)
+
+	if cart != nil {
+		mmu.Add(cart)
+		g.cart = cart
+	}
+
+	// Labels in switchable banks resolve against the cartridge's current bank.
+	g.loadSymbols()
+	if args.BreakAt != "" {
+		g.setBreakpoints()
+	}
+
+	// The boot ROM hides the start of the cartridge until it's disabled.
+	mmu.Overlay(boot)
+
+	// CPU and I/O registers as the boot ROM would leave them.
+	if fastBoot {
+		g.skipBoot(mmu)
+	}
+
+	g.clock = g.newClock()
+}
+
+// Returns a clock driving subsystems in order every machine tick: CPU and DMA,
+// PPU, then timer and serial. The APU is ticked separately since it returns
+// samples.
+func (g *GameBoy) newClock() *clock.Manual {
+	var video clock.Ticker = g.PPU
+	if g.args.Batch > 1 {
+		video = clock.TickerFunc(g.tickPPUBatch)
+	}
+	return clock.NewManual(clock.TickerFunc(g.tickCPU), video,
+		clock.TickerFunc(g.tickTimers))
+}
+
+// CPU ticks occur every 4 machine ticks (or 2 in CGB double-speed mode). DMA
+// ticks occur at the same rate.
+func (g *GameBoy) tickCPU() {
+	if g.clock.Ticks()%g.CPU.Speed.TicksPerCycle() == 0 {
+		g.CPU.Tick()
+		g.DMA.Tick()
+	}
+}
+
+// PPU ticks occur every machine tick, regardless of CPU speed. They can be
+// batched for speed, at the cost of accuracy (see -batch).
+func (g *GameBoy) tickPPUBatch() {
+	if g.pendingDots++; g.pendingDots == g.args.Batch {
+		g.PPU.TickN(int(g.args.Batch))
+		g.pendingDots = 0
+	}
+}
+
+// Timer and serial ticks occur every machine tick, twice as fast in
+// double-speed mode.
+func (g *GameBoy) tickTimers() {
+	g.Timer.Tick()
+	g.Serial.Tick()
+	if g.CPU.Speed.Double {
+		g.Timer.Tick()
+		g.Serial.Tick()
+	}
 }
 
 // Tick advances the whole emulator one step at a theoretical 4MHz. Since we're
 // using SDL audio for timing this, we also return the current value of audio
 // samples for each stereo channel as well as whether they should be played now.
+// Samples to be played are also passed to the audio sink, if any.
 func (g *GameBoy) Tick() (res TickResult) {
-	g.ticks++
+	res = g.tick()
+	if res.Play && g.Audio != nil {
+		g.Audio.Play(res.Left, res.Right)
+	}
+	return
+}
 
-	// Poll events 1000 times per second.
-	if g.ticks%4000 == 0 {
-		sdl.Do(func() {
-			for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-				eventType := event.GetType()
-				switch eventType {
-
-				// Button presses and UI keys
-				case sdl.KEYDOWN, sdl.KEYUP:
-					keyEvent := event.(*sdl.KeyboardEvent)
-					keyCode := keyEvent.Keysym.Sym
-
-					if action := g.Controls[keyCode]; action != nil {
-						action(eventType)
-					} else {
-						log.Infof("unknown key code %v", keyCode)
-					}
-
-				// Window-closing event
-				case sdl.QUIT:
-					res.Quit = true
-				}
-			}
-		})
+// Ticks returns the number of machine ticks emulated so far, making the
+// emulator a clock.Clock its subsystems can share.
+func (g *GameBoy) Ticks() uint64 {
+	return g.clock.Ticks()
+}
+
+// Actual Tick implementation.
+func (g *GameBoy) tick() (res TickResult) {
+	// Stop after a given number of machine ticks if requested.
+	if g.halted || g.args.Duration > 0 && g.Ticks() >= uint64(g.args.Duration) {
+		res.Quit = true
+		return
 	}
 
-	// CPU ticks occur every 4 machine ticks.
-	if g.ticks%4 == 0 {
-		g.CPU.Tick()
+	// Only keep handling events and feeding the sound card while paused.
+	if g.paused {
+		return g.idle()
 	}
 
-	// DMA ticks occur every 4 machine ticks.
-	if g.ticks%4 == 0 {
-		g.DMA.Tick()
+	// Replay or record inputs at the start of each frame.
+	if g.Ticks()%FrameTicks == 0 {
+		frame := g.Ticks() / FrameTicks
+		if g.Script != nil {
+			changed := g.args.PauseOnInput && g.Script.Changes(g.JPad, frame)
+			g.Script.Apply(g.JPad, frame)
+			if changed {
+				// Resuming starts this frame over, with no change this time.
+				g.paused = true
+				g.stepping = false
+				g.Display.Text(fmt.Sprintf("Paused (input at frame %d)", frame))
+				return
+			}
+		}
+		g.applyAutofire(frame)
+		if g.movie != nil {
+			g.recordInputs(frame)
+		}
 	}
 
-	// PPU ticks occur every machine tick.
-	g.PPU.Tick()
+	// CPU, PPU and everything else in between (see newClock).
+	g.clock.Advance(1)
 
-	// Timer tick occur every machine tick.
-	g.Timer.Tick()
+	// Poll events 1000 times per second.
+	if g.events && g.Ticks()%4000 == 0 {
+		res.Quit = g.pollEvents()
+	}
+
+	// APU ticks occur every machine tick, but only produce a sample when the
+	// sound card needs one (see apu.APU.SamplingRate).
+	res.Left, res.Right, res.Play = g.APU.Tick()
 
-	// APU ticks occur only when we need to generate the next sample.
-	// Note that the Gameboy machine frequency is not an exact multiple of the
-	// sound output frequency, so this is in fact an approximation. So long as
-	// no one can hear the difference, let's call it good enough.
-	if g.ticks%apu.SoundOutRate == 0 {
-		res.Left, res.Right = g.APU.Tick()
-		res.Play = true
+	return
+}
+
+// Pauses emulation when the window loses focus and resumes it when focus is
+// back, if enabled with -focuspause. Emulation paused by other means is left
+// alone. The sound card only gets silence while paused.
+func (g *GameBoy) windowFocus(focused bool) {
+	if !g.args.FocusPause {
+		return
 	}
+	switch {
+	case !focused && !g.paused:
+		g.paused = true
+		g.focusPaused = true
+		g.stepping = false
+		g.Display.Text("Paused")
+	case focused && g.focusPaused:
+		g.paused = false
+		g.focusPaused = false
+		g.Display.Text("")
+	}
+}
+
+// Tick replacement while paused: time still passes for event polling and the
+// sound card is fed silence at the usual rate.
+func (g *GameBoy) idle() (res TickResult) {
+	g.idleTicks++
 
+	if g.events && g.idleTicks%4000 == 0 {
+		res.Quit = g.pollEvents()
+	}
+
+	// Unsigned samples, the silence point is at 128.
+	if g.idleTicks%uint64(apu.GameBoyRate/g.APU.SamplingRate) == 0 {
+		res.Left, res.Right, res.Play = 128, 128, true
+	}
 	return
 }
 
-// Stop should be called before quitting the program and will close all needed
-// resources.
-func (g *GameBoy) Stop() {
-	// Make sure GIF file is written to disk.
-	g.Display.Close()
+// Called by the PPU on VBlank to report telemetry, refresh debug views and
+// pause again after stepping a single frame.
+func (g *GameBoy) vblank() {
+	g.reportFrame()
+
+	g.updateViewer()
+
+	if g.stepping {
+		g.stepping = false
+		g.paused = true
+	}
+}
+
+// Breakpoint is called by the CPU upon executing LD B,B and takes the action
+// set with -breakpoint.
+func (g *GameBoy) Breakpoint(c *cpu.CPU) {
+	pc := c.PC - 1
+	if !g.breakpointBank(pc) {
+		return
+	}
 
-	// If debugging at all, dump debug info.
-	if len(g.args.DebugModules) > 0 {
+	if label := g.label(pc); label != "" {
+		log.Infof("breakpoint at 0x%04x (%s)", pc, label)
+	} else {
+		log.Infof("breakpoint at 0x%04x", pc)
+	}
+	switch g.args.Breakpoint {
+	case "", "dump":
 		fmt.Println(g.CPU)
 		fmt.Println(g.PPU)
+	case "screenshot":
+		g.Screenshot(KeyDown)
+	case "halt":
+		g.halted = true
+	default:
+		log.Warningf("unknown breakpoint action %s", g.args.Breakpoint)
+	}
+}
 
-		// Dump memory
-		//g.CPU.DumpRAM()
+// ExitCode returns the process exit code requested via -exitcode: either the
+// value of a byte in memory, or whether a test ROM reported success over the
+// serial port (0) or not (1). Defaults to 0.
+func (g *GameBoy) ExitCode() int {
+	switch g.args.ExitCode {
+	case "":
+		return 0
+	case "serial":
+		if bytes.Contains(g.Serial.Output.Bytes(), []byte("Passed")) {
+			return 0
+		}
+		return 1
+	default:
+		addr, err := strconv.ParseUint(g.args.ExitCode, 0, 16)
+		if err != nil {
+			log.Warningf("invalid exit code address %s", g.args.ExitCode)
+			return 255
+		}
+		return int(g.CPU.MMU.Read(uint16(addr)))
 	}
 }
 
+// Shutdown should be called before quitting the program (including on
+// signals) and will stop audio, flush battery-backed RAM, and close recordings
+// and display resources. Only the first call does anything.
+func (g *GameBoy) Shutdown() {
+	g.shutdown.Do(func() {
+		// Make sure the emulator isn't running while we clean up.
+		g.stopAudio()
+
+		g.saveCartridge()
+		g.saveMovie()
+
+		// Make sure GIF file is written to disk and release display resources.
+		if g.timeLapse != nil {
+			g.timeLapse.wait()
+		}
+		if g.rawFrames != nil {
+			g.rawFrames.close()
+		}
+		g.Display.Close()
+		g.closeWindows()
+
+		if g.args.OpProfile {
+			g.CPU.WriteProfile(os.Stdout)
+		}
+
+		// If debugging at all, dump debug info.
+		if len(g.args.DebugModules) > 0 {
+			fmt.Println(g.CPU)
+			fmt.Println(g.PPU)
+
+			// Dump memory
+			//g.CPU.DumpRAM()
+		}
+	})
+}
+
+// Flushes battery-backed RAM, if any.
+func (g *GameBoy) saveCartridge() {
+	if saver, ok := g.cart.(memory.Saver); ok {
+		if err := saver.Save(); err != nil {
+			log.Warningf("saving cartridge RAM failed: %v", err)
+		}
+	}
+}
+
+// LoadROM restarts the emulator with the given ROM file, after saving the
+// current cartridge's RAM. Input scripts, movies and save paths given on the
+// command line were meant for the previous ROM and are dropped.
+func (g *GameBoy) LoadROM(path string) {
+	g.saveCartridge()
+	g.saveMovie()
+
+	g.args.ROMPath = path
+	g.args.SavePath = ""
+	g.args.InputScript, g.args.MoviePath, g.args.RecordMovie = "", "", ""
+	g.restart()
+	log.Infof("Loaded %s", path)
+}
+
+// Rebuilds the machine from the current options, forgetting about inputs and
+// movies in progress.
+func (g *GameBoy) restart() {
+	g.Script, g.movie, g.pending = nil, nil, nil
+	g.cart = nil
+	g.halted = false
+
+	g.setup()
+}
+
 // Recover should be called at the end of each Tick. If the program panics, it
 // should then display some useful debug info before crashing.
 func (g *GameBoy) Recover() {
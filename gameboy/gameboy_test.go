@@ -0,0 +1,382 @@
+package gameboy
+
+import (
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazy-stripes/goholint/apu"
+	"github.com/lazy-stripes/goholint/cpu"
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/lazy-stripes/goholint/ppu"
+	"github.com/lazy-stripes/goholint/screen"
+	"github.com/lazy-stripes/goholint/serial"
+	"github.com/lazy-stripes/goholint/timer"
+)
+
+// Display doing nothing, for tests that don't care about output.
+type nullDisplay struct{ enabled bool }
+
+func (d *nullDisplay) Enable()                                                  { d.enabled = true }
+func (d *nullDisplay) Enabled() bool                                            { return d.enabled }
+func (d *nullDisplay) Disable()                                                 { d.enabled = false }
+func (d *nullDisplay) Close()                                                   {}
+func (d *nullDisplay) Write(colorIndex uint8)                                   {}
+func (d *nullDisplay) HBlank()                                                  {}
+func (d *nullDisplay) VBlank()                                                  {}
+func (d *nullDisplay) Text(text string)                                         {}
+func (d *nullDisplay) Message(text string, d2 time.Duration, p screen.Priority) {}
+func (d *nullDisplay) ToggleUI()                                                {}
+func (d *nullDisplay) ToggleFullscreen()                                        {}
+func (d *nullDisplay) Resize()                                                  {}
+func (d *nullDisplay) Screenshot(filename string)                               {}
+func (d *nullDisplay) Record(filename string)                                   {}
+func (d *nullDisplay) StopRecord()                                              {}
+
+// Returns a minimal GameBoy executing NOPs from RAM, without SDL.
+func newTestGameBoy(args *options.Options) *GameBoy {
+	g := GameBoy{args: args}
+	g.CPU = cpu.New(memory.NewRAM(0, 0x8000))
+	g.APU = apu.New(0)
+	g.Display = &nullDisplay{}
+	g.PPU = ppu.New(g.Display, nil)
+	g.DMA = &memory.DMA{}
+	g.Serial = serial.New()
+	g.Timer = timer.New()
+	g.clock = g.newClock()
+	return &g
+}
+
+// Writes a ROM-only cartridge jumping to the given code at 0x150 to the given
+// folder, and returns its path.
+func writeTestROM(t *testing.T, dir string, code []byte, cgbFlag uint8) string {
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xc3, 0x50, 0x01}) // JP $0150
+	rom[memory.AddrCGBFlag] = cgbFlag
+	copy(rom[0x150:], code)
+
+	path := filepath.Join(dir, "test.gb")
+	if err := ioutil.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Writes a ROM-only cartridge running the given code to a new temporary folder
+// and returns options booting it quickly, with files saved to that folder (see
+// SaveDir). Calling done removes the folder.
+func testOptions(t *testing.T, code []byte) (args *options.Options, done func()) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args = &options.Options{
+		ROMPath:  writeTestROM(t, dir, code, 0),
+		FastBoot: true,
+		SaveDir:  dir,
+	}
+	return args, func() { os.RemoveAll(dir) }
+}
+
+// Same as testOptions, then starts a headless emulator with those options once
+// setup (if not nil) has changed them.
+func startTestGameBoy(t *testing.T, code []byte, setup func(*options.Options)) (*GameBoy, *screen.Headless, func()) {
+	args, done := testOptions(t, code)
+	if setup != nil {
+		setup(args)
+	}
+	g, display := NewHeadless(args)
+	return g, display, done
+}
+
+func TestDuration(t *testing.T) {
+	g := newTestGameBoy(&options.Options{Duration: 1234})
+
+	for i := 0; i < 2000; i++ {
+		res := g.Tick()
+		if res.Quit != (i >= 1234) {
+			t.Fatalf("Tick() #%d returned Quit=%t", i, res.Quit)
+		}
+	}
+
+	if g.Ticks() != 1234 {
+		t.Errorf("emulator ran for %d ticks, want 1234", g.Ticks())
+	}
+	if g.CPU.Cycle != 1234/4 {
+		t.Errorf("CPU ran for %d cycles, want %d", g.CPU.Cycle, 1234/4)
+	}
+}
+
+func TestClock(t *testing.T) {
+	// JR -2, with the LCD left on after boot.
+	g, _, done := startTestGameBoy(t, []byte{0x18, 0xfe}, nil)
+	defer done()
+
+	// The PPU is ticked by the emulator's clock, starting on the first tick.
+	for _, c := range []struct {
+		tick uint64
+		ly   uint8
+	}{{455, 0}, {456, 1}, {144*456 - 1, 143}, {144 * 456, 144}, {FrameTicks, 0}} {
+		for g.Ticks() < c.tick {
+			g.Tick()
+		}
+		if g.PPU.LY != c.ly {
+			t.Errorf("LY=%d at tick %d, want %d", g.PPU.LY, g.Ticks(), c.ly)
+		}
+	}
+	if g.CPU.Cycle != FrameTicks/4 {
+		t.Errorf("CPU ran for %d cycles in a frame, want %d", g.CPU.Cycle, FrameTicks/4)
+	}
+}
+
+func TestStepFrame(t *testing.T) {
+	code := []byte{0x18, 0xfe} // JR -2
+	g, _, done := startTestGameBoy(t, code, nil)
+	defer done()
+
+	// Nothing happens while paused, and stepping is ignored otherwise.
+	g.StepFrame(KeyDown)
+	g.TogglePause(KeyDown)
+	ticks, ly := g.Ticks(), g.PPU.LY
+	for i := 0; i < FrameTicks; i++ {
+		g.Tick()
+	}
+	if g.Ticks() != ticks || g.PPU.LY != ly {
+		t.Fatalf("emulation ran while paused (LY=%d)", g.PPU.LY)
+	}
+
+	// Step a first time to sync with VBlank, then step one frame and check LY
+	// went through all lines once.
+	g.StepFrame(KeyDown)
+	for !g.paused {
+		g.Tick()
+	}
+	g.StepFrame(KeyDown)
+	ticks, ly = g.Ticks(), g.PPU.LY
+	lines := []uint8{ly}
+	for !g.paused {
+		g.Tick()
+		if g.PPU.LY != lines[len(lines)-1] {
+			lines = append(lines, g.PPU.LY)
+		}
+	}
+
+	if g.Ticks()-ticks != FrameTicks {
+		t.Errorf("stepped %d ticks, want %d", g.Ticks()-ticks, FrameTicks)
+	}
+	if len(lines) != 155 {
+		t.Fatalf("went through %d lines, want 155", len(lines))
+	}
+	for i, line := range lines {
+		if want := uint8((int(ly) + i) % 154); line != want {
+			t.Fatalf("line #%d is %d, want %d", i, line, want)
+		}
+	}
+}
+
+func TestFocusPause(t *testing.T) {
+	g := newTestGameBoy(&options.Options{})
+
+	// Disabled by default.
+	g.windowFocus(false)
+	if g.paused {
+		t.Fatal("paused on focus loss without -focuspause")
+	}
+
+	g.args.FocusPause = true
+	g.windowFocus(false)
+	if !g.paused {
+		t.Fatal("not paused on focus loss")
+	}
+	g.windowFocus(true)
+	if g.paused {
+		t.Fatal("not resumed on focus gain")
+	}
+
+	// Emulation paused by the user stays paused.
+	g.TogglePause(KeyDown)
+	g.windowFocus(false)
+	g.windowFocus(true)
+	if !g.paused {
+		t.Fatal("resumed on focus gain after a manual pause")
+	}
+
+	// Unpausing manually while out of focus is also left alone.
+	g.TogglePause(KeyDown)
+	g.windowFocus(false)
+	g.TogglePause(KeyDown)
+	g.TogglePause(KeyDown)
+	g.windowFocus(true)
+	if !g.paused {
+		t.Fatal("resumed on focus gain after pausing manually again")
+	}
+}
+
+func TestCyclePalette(t *testing.T) {
+	g := newTestGameBoy(&options.Options{})
+
+	for i := 1; i <= len(screen.PalettePresets); i++ {
+		g.CyclePalette(KeyDown)
+		want := i % len(screen.PalettePresets)
+		if g.preset != want {
+			t.Fatalf("preset #%d after cycling %d times, want #%d", g.preset, i, want)
+		}
+		if g.palette[3] != screen.PalettePresets[want].Palette[3] {
+			t.Fatalf("palette not switched to %s", screen.PalettePresets[want].Name)
+		}
+	}
+
+	g.CyclePalette(KeyUp)
+	if g.preset != 0 {
+		t.Error("palette switched on key release")
+	}
+}
+
+func TestSpeedKeys(t *testing.T) {
+	g := newTestGameBoy(&options.Options{SamplingRate: 22050})
+
+	for i := 0; i < 20; i++ {
+		g.SpeedUp(KeyDown)
+	}
+	if g.Speed() != MaxSpeed {
+		t.Errorf("speed is %gx after speeding up, want %gx", g.Speed(), MaxSpeed)
+	}
+	if g.APU.SamplingRate != 22050/4 {
+		t.Errorf("APU produces %d samples per second at 4x, want %d",
+			g.APU.SamplingRate, 22050/4)
+	}
+
+	for i := 0; i < 20; i++ {
+		g.SpeedDown(KeyDown)
+	}
+	if g.Speed() != MinSpeed {
+		t.Errorf("speed is %gx after slowing down, want %gx", g.Speed(), MinSpeed)
+	}
+
+	g.SpeedUp(KeyDown)
+	g.SpeedUp(KeyUp)
+	if g.Speed() != MinSpeed+SpeedStep {
+		t.Errorf("speed is %gx after one step up, want %gx", g.Speed(), MinSpeed+SpeedStep)
+	}
+}
+
+func TestOnFrame(t *testing.T) {
+	g, display, done := startTestGameBoy(t, startTestCode, nil)
+	defer done()
+
+	var reported []FrameStats
+	g.OnFrame = func(stats FrameStats) { reported = append(reported, stats) }
+	for i := 0; i < 10*FrameTicks; i++ {
+		g.Tick()
+	}
+
+	if len(reported) != int(display.Frames) || len(reported) < 9 {
+		t.Fatalf("%d frames reported, %d displayed", len(reported), display.Frames)
+	}
+	for i, stats := range reported {
+		if stats.Frame != uint64(i+1) {
+			t.Errorf("frame #%d reported as frame %d", i+1, stats.Frame)
+		}
+		if stats.Emulation < 0 || stats.Render < 0 || (i > 0 && stats.FPS <= 0) {
+			t.Errorf("frame #%d has invalid timings %+v", i+1, stats)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	// Returns VRAM and WRAM contents at power-up with the given seed.
+	powerUp := func(seed int64) []byte {
+		args := &options.Options{FastBoot: true, RAMFill: "random", Seed: seed}
+		g := NewWithDisplay(args, &nullDisplay{}, nil)
+		var contents []byte
+		for addr := 0x8000; addr < 0xe000; addr++ {
+			if addr < 0xa000 || addr >= 0xc000 {
+				contents = append(contents, g.CPU.MMU.Read(uint16(addr)))
+			}
+		}
+		return contents
+	}
+
+	if !bytes.Equal(powerUp(42), powerUp(42)) {
+		t.Error("RAM contents differ between runs with the same seed")
+	}
+	if bytes.Equal(powerUp(42), powerUp(43)) {
+		t.Error("RAM contents identical with different seeds")
+	}
+}
+
+func TestNullAudio(t *testing.T) {
+	code := []byte{0x18, 0xfe} // JR -2
+	for _, rate := range []uint{0, 22050, 48000} {
+		g, _, done := startTestGameBoy(t, code, func(args *options.Options) {
+			args.SamplingRate = rate
+		})
+		defer done()
+		sink, ok := g.Audio.(*NullAudio)
+		if !ok {
+			t.Fatalf("headless audio sink is %T, want *NullAudio", g.Audio)
+		}
+
+		// A quarter of a second's worth of ticks.
+		ticks := apu.GameBoyRate / 4
+		played := uint64(0)
+		for i := 0; i < ticks; i++ {
+			if g.Tick().Play {
+				played++
+			}
+		}
+
+		want := uint64(g.APU.SamplingRate / 4)
+		if sink.Samples != want || played != want {
+			t.Errorf("%dHz: sink got %d samples, %d played, want %d",
+				g.APU.SamplingRate, sink.Samples, played, want)
+		}
+		elapsed := time.Duration(want) * time.Second / time.Duration(g.APU.SamplingRate)
+		if sink.Elapsed() != elapsed {
+			t.Errorf("%dHz: samples last %v, want %v", g.APU.SamplingRate,
+				sink.Elapsed(), elapsed)
+		}
+	}
+}
+
+func TestDumpVRAM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := newTestGameBoy(&options.Options{SaveDir: dir})
+	g.DumpVRAM(KeyUp)
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("%d files written on key release", len(files))
+	}
+
+	g.DumpVRAM(KeyDown)
+	for suffix, width := range map[string]int{
+		"-tiles.png": ppu.TileColumns * 8,
+		"-bg.png":    ppu.MapSize,
+	} {
+		files, err := filepath.Glob(filepath.Join(dir, "goholint-*"+suffix))
+		if err != nil || len(files) != 1 {
+			t.Errorf("found %v for *%s, want a single file (%v)", files, suffix, err)
+			continue
+		}
+		f, err := os.Open(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", files[0], err)
+		} else if img.Bounds().Dx() != width {
+			t.Errorf("%s is %d pixels wide, want %d", files[0], img.Bounds().Dx(), width)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package gameboy
+
+import (
+	"strings"
+
+	"github.com/lazy-stripes/goholint/memory"
+)
+
+// Mode the emulator runs in, deciding which hardware features are available
+// (palettes, VRAM and WRAM banking, double-speed...)
+type Mode uint8
+
+// Supported modes.
+const (
+	ModeDMG Mode = iota // Original GameBoy
+	ModeCGB             // GameBoy Color
+)
+
+// DMGBootROMSize is the size of the DMG boot ROM. Anything larger is assumed
+// to be a CGB boot ROM.
+const DMGBootROMSize = 0x100
+
+// String returns the usual name for the hardware emulated in a given mode.
+func (m Mode) String() string {
+	if m == ModeCGB {
+		return "CGB"
+	}
+	return "DMG"
+}
+
+// DetectMode returns the mode to run a cartridge in, given its header's CGB
+// flag and the size of the boot ROM (0 if there is none). Setting forceDMG
+// runs CGB-enhanced games in DMG mode (e.g. to keep classic palettes).
+func DetectMode(cgbFlag uint8, bootSize int, forceDMG bool) Mode {
+	if cgbFlag&memory.CGBEnhanced == 0 {
+		return ModeDMG
+	}
+
+	// A real DMG would run CGB-enhanced games in DMG mode as well.
+	if bootSize > 0 && bootSize <= DMGBootROMSize {
+		if cgbFlag == memory.CGBOnly {
+			log.Warning("CGB-only game running with a DMG boot ROM")
+		}
+		return ModeDMG
+	}
+
+	if forceDMG {
+		if cgbFlag == memory.CGBOnly {
+			log.Warning("can't force DMG mode for a CGB-only game")
+			return ModeCGB
+		}
+		return ModeDMG
+	}
+
+	return ModeCGB
+}
+
+// SelectBootROM returns the boot ROM to run given the -boot option, which holds
+// either a single path or a DMG and a CGB boot ROM separated by a comma. In the
+// latter case, the CGB one is picked for games that would run in CGB mode
+// without a boot ROM (see DetectMode), the DMG one otherwise. If either path of
+// the pair is empty, the other one is used for all games.
+func SelectBootROM(boot string, cgbFlag uint8, forceDMG bool) string {
+	paths := strings.SplitN(boot, ",", 2)
+	if len(paths) == 1 {
+		return boot
+	}
+
+	dmg, cgb := strings.TrimSpace(paths[0]), strings.TrimSpace(paths[1])
+	wantCGB := cgbFlag&memory.CGBEnhanced != 0 && (!forceDMG || cgbFlag == memory.CGBOnly)
+	if (wantCGB && cgb != "") || dmg == "" {
+		return cgb
+	}
+	return dmg
+}
@@ -0,0 +1,53 @@
+package gameboy
+
+import "testing"
+
+func TestDetectMode(t *testing.T) {
+	cases := []struct {
+		flag     uint8
+		bootSize int
+		forceDMG bool
+		want     Mode
+	}{
+		{0x00, 0, false, ModeDMG},
+		{0x80, 0, false, ModeCGB},
+		{0xc0, 0, false, ModeCGB},
+		{0x00, 0x900, false, ModeDMG},
+		{0x80, 0x900, false, ModeCGB},
+		{0x80, 0x100, false, ModeDMG}, // DMG boot ROM
+		{0x80, 0, true, ModeDMG},      // Forced
+		{0xc0, 0, true, ModeCGB},      // Can't force CGB-only games
+	}
+
+	for _, c := range cases {
+		if got := DetectMode(c.flag, c.bootSize, c.forceDMG); got != c.want {
+			t.Errorf("DetectMode(0x%02x, %d, %t) == %s, want %s", c.flag,
+				c.bootSize, c.forceDMG, got, c.want)
+		}
+	}
+}
+
+func TestSelectBootROM(t *testing.T) {
+	cases := []struct {
+		boot     string
+		flag     uint8
+		forceDMG bool
+		want     string
+	}{
+		{"dmg.bin", 0x80, false, "dmg.bin"}, // Single path, used as is
+		{"dmg.bin,cgb.bin", 0x00, false, "dmg.bin"},
+		{"dmg.bin,cgb.bin", 0x80, false, "cgb.bin"},
+		{"dmg.bin, cgb.bin", 0xc0, false, "cgb.bin"},
+		{"dmg.bin,cgb.bin", 0x80, true, "dmg.bin"}, // Forced
+		{"dmg.bin,cgb.bin", 0xc0, true, "cgb.bin"}, // Can't force CGB-only games
+		{"dmg.bin,", 0x80, false, "dmg.bin"},
+		{",cgb.bin", 0x00, false, "cgb.bin"},
+	}
+
+	for _, c := range cases {
+		if got := SelectBootROM(c.boot, c.flag, c.forceDMG); got != c.want {
+			t.Errorf("SelectBootROM(%q, 0x%02x, %t) == %q, want %q", c.boot,
+				c.flag, c.forceDMG, got, c.want)
+		}
+	}
+}
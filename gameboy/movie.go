@@ -0,0 +1,135 @@
+package gameboy
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lazy-stripes/goholint/joypad"
+	"github.com/lazy-stripes/goholint/memory"
+)
+
+// First word of a movie file, followed by the hash of the ROM it was recorded
+// with (see memory.ROMHash).
+const movieMagic = "goholint-movie"
+
+// Movie is a recording of joypad inputs, indexed by frame from a fresh boot.
+// The file format is a header line with the ROM hash, followed by an input
+// script (see joypad.ParseScript).
+type Movie struct {
+	ROMHash string
+	Inputs  joypad.Script
+}
+
+// NewMovie returns an empty movie for the ROM with the given hash.
+func NewMovie(romHash string) *Movie {
+	return &Movie{ROMHash: romHash, Inputs: make(joypad.Script)}
+}
+
+// LoadMovie reads a movie file.
+func LoadMovie(filename string) (*Movie, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("truncated movie file %s", filename)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 2 || fields[0] != movieMagic {
+		return nil, fmt.Errorf("%s is not a movie file", filename)
+	}
+
+	inputs, err := joypad.ParseScript(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid movie file %s (%s)", filename, err)
+	}
+	return &Movie{ROMHash: fields[1], Inputs: inputs}, nil
+}
+
+// Save writes the movie to the given file.
+func (m *Movie) Save(filename string) error {
+	content := fmt.Sprintf("%s %s\n%s", movieMagic, m.ROMHash, m.Inputs)
+	return ioutil.WriteFile(filename, []byte(content), 0644)
+}
+
+// Loads the movie to replay and/or prepares the one to record, as requested.
+func (g *GameBoy) setupMovie() {
+	data, err := memory.ReadROMFile(g.args.ROMPath)
+	if err != nil {
+		log.Warningf("can't use movies without a ROM: %v", err)
+		return
+	}
+	hash := memory.ROMHash(data)
+
+	if g.args.MoviePath != "" {
+		movie, err := LoadMovie(g.args.MoviePath)
+		switch {
+		case err != nil:
+			log.Warningf("can't load movie: %v", err)
+		case movie.ROMHash != hash:
+			log.Warningf("movie %s was recorded with another ROM (%s), not replaying it",
+				g.args.MoviePath, movie.ROMHash)
+		default:
+			g.Script = movie.Inputs
+		}
+	}
+
+	if g.args.RecordMovie != "" {
+		g.movie = NewMovie(hash)
+		g.pending = make(map[string]bool)
+	}
+}
+
+// Sets a button state from user input, keeping track of turbo buttons.
+func (g *GameBoy) setButton(name string, pressed bool) {
+	g.holdButton(name, pressed)
+	g.applyButton(name, pressed)
+}
+
+// Sets a button state. While recording a movie, changes are delayed to the
+// start of the next frame so they can be replayed exactly.
+func (g *GameBoy) applyButton(name string, pressed bool) {
+	if g.movie == nil {
+		g.JPad.SetButton(name, pressed)
+		return
+	}
+	g.pending[name] = pressed
+}
+
+// Applies and records button changes that occurred during the last frame.
+func (g *GameBoy) recordInputs(frame uint64) {
+	names := make([]string, 0, len(g.pending))
+	for name := range g.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Keep movie files stable.
+
+	for _, name := range names {
+		pressed := g.pending[name]
+		if g.JPad.Button(name).State != pressed {
+			g.JPad.SetButton(name, pressed)
+			g.movie.Inputs.Add(frame, name, pressed)
+		}
+		delete(g.pending, name)
+	}
+}
+
+// Writes the movie being recorded to disk, if any.
+func (g *GameBoy) saveMovie() {
+	if g.movie == nil {
+		return
+	}
+	if err := g.movie.Save(g.args.RecordMovie); err != nil {
+		log.Warningf("can't save movie: %v", err)
+	} else {
+		log.Infof("Movie saved to %s", g.args.RecordMovie)
+	}
+}
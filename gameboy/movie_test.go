@@ -0,0 +1,114 @@
+package gameboy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// Program turning the screen black while Start is held, white otherwise.
+// Background palette is set before the first pixel so that random VRAM
+// contents never show.
+var startTestCode = []byte{
+	0x3e, 0x00, // LD A,$00
+	0xe0, 0x47, // LDH [BGP],A
+	0x3e, 0x91, // LD A,$91
+	0xe0, 0x40, // LDH [LCDC],A  ; Turn LCD on.
+	0x3e, 0x10, // LD A,$10      ; .loop
+	0xe0, 0x00, // LDH [JOYP],A  ; Select buttons.
+	0xf0, 0x00, // LDH A,[JOYP]
+	0xe6, 0x08, // AND $08       ; Start (0=pressed).
+	0x28, 0x04, // JR Z,.pressed
+	0x3e, 0x00, // LD A,$00
+	0x18, 0x02, // JR .store
+	0x3e, 0xff, // LD A,$ff      ; .pressed
+	0xe0, 0x47, // LDH [BGP],A   ; .store
+	0x18, 0xec, // JR .loop
+}
+
+// Runs the emulator headless for the given number of frames, calling input()
+// before each tick, and returns the hash of each frame.
+func runFrames(g *GameBoy, display *screen.Headless, frames uint, input func()) (hashes []uint64) {
+	for display.Frames < frames {
+		input()
+		seen := display.Frames
+		g.Tick()
+		if display.Frames != seen {
+			hashes = append(hashes, display.Hash())
+		}
+	}
+	return
+}
+
+func TestMovie(t *testing.T) {
+	// Record a run where the user holds Start from the middle of a frame for
+	// a couple of frames.
+	var moviePath string
+	g, display, done := startTestGameBoy(t, startTestCode, func(args *options.Options) {
+		moviePath = filepath.Join(args.SaveDir, "start.movie")
+		args.RecordMovie = moviePath
+	})
+	defer done()
+	recorded := runFrames(g, display, 10, func() {
+		switch g.Ticks() {
+		case FrameTicks*3 + 1000:
+			g.setButton("start", true)
+		case FrameTicks*5 + 2000:
+			g.setButton("start", false)
+		}
+	})
+	g.Shutdown()
+
+	// Replay it from a fresh boot.
+	args := *g.args
+	args.RecordMovie, args.MoviePath = "", moviePath
+	g, display = NewHeadless(&args)
+	if g.Script == nil {
+		t.Fatal("movie was not loaded")
+	}
+	replayed := runFrames(g, display, 10, func() {})
+
+	different := false
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Errorf("frame %d: hash %016x, want %016x", i, replayed[i], recorded[i])
+		}
+		different = different || recorded[i] != recorded[0]
+	}
+	if !different {
+		t.Error("pressing Start had no visible effect")
+	}
+}
+
+func TestPauseOnInput(t *testing.T) {
+	args, done := testOptions(t, startTestCode)
+	defer done()
+
+	// Pressing Start again at frame 5 doesn't change anything.
+	script := "3 start press\n5 start press\n7 start release\n"
+	args.InputScript = filepath.Join(args.SaveDir, "inputs.txt")
+	if err := ioutil.WriteFile(args.InputScript, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args.PauseOnInput = true
+	g, display := NewHeadless(args)
+
+	var pauses []uint64
+	runFrames(g, display, 10, func() {
+		if g.paused {
+			pauses = append(pauses, g.Ticks()/FrameTicks)
+			if g.Ticks()%FrameTicks != 0 {
+				t.Fatalf("paused %d ticks into frame %d", g.Ticks()%FrameTicks,
+					g.Ticks()/FrameTicks)
+			}
+			g.paused = false
+		}
+	})
+
+	if len(pauses) != 2 || pauses[0] != 3 || pauses[1] != 7 {
+		t.Errorf("paused at frames %v, want [3 7]", pauses)
+	}
+}
@@ -0,0 +1,39 @@
+package gameboy
+
+import "time"
+
+// NullAudio is an audio sink discarding samples, for running without audio
+// hardware (see NewHeadless and -noaudio). The APU still runs for timing, and
+// samples are counted so that emulation can be paced from them.
+type NullAudio struct {
+	Samples uint64 // Stereo samples received so far
+	Rate    uint   // Samples per second, for Elapsed
+
+	start time.Time
+}
+
+// Play counts a sample and drops it.
+func (n *NullAudio) Play(left, right uint8) {
+	n.Samples++
+}
+
+// Elapsed returns how long the samples received so far would have taken to
+// play at the sink's rate.
+func (n *NullAudio) Elapsed() time.Duration {
+	if n.Rate == 0 {
+		return 0
+	}
+	return time.Duration(n.Samples) * time.Second / time.Duration(n.Rate)
+}
+
+// Wait sleeps until the samples received so far would have been played since
+// the first call, so that emulation runs in real time without an audio device
+// calling back for more.
+func (n *NullAudio) Wait() {
+	if n.start.IsZero() {
+		n.start = time.Now()
+	}
+	if ahead := n.Elapsed() - time.Since(n.start); ahead > 0 {
+		time.Sleep(ahead)
+	}
+}
@@ -0,0 +1,85 @@
+package gameboy
+
+import (
+	"github.com/lazy-stripes/goholint/apu"
+	"github.com/lazy-stripes/goholint/interrupts"
+	"github.com/lazy-stripes/goholint/joypad"
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/ppu"
+	"github.com/lazy-stripes/goholint/serial"
+	"github.com/lazy-stripes/goholint/timer"
+)
+
+// Source: [PANDOCS] https://gbdev.io/pandocs/Power_Up_Sequence.html
+
+// CPU registers as left by the boot ROM when handing over to the cartridge.
+type cpuState struct {
+	A, F, B, C, D, E, H, L uint8
+	SP, PC                 uint16
+}
+
+// Register value to write when skipping the boot ROM.
+type ioState struct {
+	Addr  uint16
+	Value uint8
+}
+
+// Post-boot CPU registers for each mode. DMG flags H and C depend on the
+// header checksum, but they're set for any valid cartridge.
+var postBootCPU = map[Mode]cpuState{
+	ModeDMG: {A: 0x01, F: 0xb0, B: 0x00, C: 0x13, D: 0x00, E: 0xd8, H: 0x01, L: 0x4d, SP: 0xfffe, PC: 0x0100},
+	ModeCGB: {A: 0x11, F: 0x80, B: 0x00, C: 0x00, D: 0xff, E: 0x56, H: 0x00, L: 0x0d, SP: 0xfffe, PC: 0x0100},
+}
+
+// Post-boot I/O registers common to both modes, in write order (sound must be
+// turned on before other sound registers can be written to). Write-only
+// registers that would trigger sound channels are left alone, and so are DIV
+// and LY which don't take writes as-is.
+var postBootIO = []ioState{
+	{apu.AddrNR52, 0xf1},
+	{apu.AddrNR10, 0x80},
+	{apu.AddrNR11, 0xbf},
+	{apu.AddrNR12, 0xf3},
+	{apu.AddrNR21, 0x3f},
+	{apu.AddrNR22, 0x00},
+	{apu.AddrNR30, 0x7f},
+	{apu.AddrNR31, 0xff},
+	{apu.AddrNR32, 0x9f},
+	{apu.AddrNR42, 0x00},
+	{apu.AddrNR43, 0x00},
+	{apu.AddrNR50, 0x77},
+	{apu.AddrNR51, 0xf3},
+	{joypad.AddrJOYP, 0xcf},
+	{timer.AddrTAC, 0xf8},
+	{interrupts.AddrIF, 0xe1},
+	{ppu.AddrLCDC, 0x91},
+	{ppu.AddrSTAT, 0x85},
+	{ppu.AddrSCY, 0x00},
+	{ppu.AddrSCX, 0x00},
+	{ppu.AddrLYC, 0x00},
+	{ppu.AddrBGP, 0xfc},
+	{ppu.AddrWY, 0x00},
+	{ppu.AddrWX, 0x00},
+	{interrupts.AddrIE, 0x00},
+}
+
+// Post-boot I/O registers that differ between modes.
+var postBootModeIO = map[Mode][]ioState{
+	ModeDMG: {{serial.AddrSC, 0x7e}},
+	ModeCGB: {{serial.AddrSC, 0x7f}},
+}
+
+// Sets CPU and I/O registers to the values they'd have after running the boot
+// ROM for the current mode.
+func (g *GameBoy) skipBoot(mmu memory.Addressable) {
+	state := postBootCPU[g.Mode]
+	g.CPU.A, g.CPU.F = state.A, state.F
+	g.CPU.B, g.CPU.C = state.B, state.C
+	g.CPU.D, g.CPU.E = state.D, state.E
+	g.CPU.H, g.CPU.L = state.H, state.L
+	g.CPU.SP, g.CPU.PC = state.SP, state.PC
+
+	for _, reg := range append(postBootIO, postBootModeIO[g.Mode]...) {
+		mmu.Write(reg.Addr, reg.Value)
+	}
+}
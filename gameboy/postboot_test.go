@@ -0,0 +1,43 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/ppu"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+func TestFastBoot(t *testing.T) {
+	args, done := testOptions(t, nil)
+	defer done()
+
+	testCases := []struct {
+		cgbFlag            uint8
+		af, bc, de, hl, sp uint16
+	}{
+		{0x00, 0x01b0, 0x0013, 0x00d8, 0x014d, 0xfffe},
+		{memory.CGBEnhanced, 0x1180, 0x0000, 0xff56, 0x000d, 0xfffe},
+	}
+
+	for _, tc := range testCases {
+		args.ROMPath = writeTestROM(t, args.SaveDir, nil, tc.cgbFlag)
+		g := NewWithDisplay(args, screen.NewHeadless(), nil)
+
+		if g.CPU.AF() != tc.af || g.CPU.BC() != tc.bc || g.CPU.DE() != tc.de ||
+			g.CPU.HL() != tc.hl || g.CPU.SP != tc.sp {
+			t.Errorf("%s: AF=%04x BC=%04x DE=%04x HL=%04x SP=%04x, want %04x %04x %04x %04x %04x",
+				g.Mode, g.CPU.AF(), g.CPU.BC(), g.CPU.DE(), g.CPU.HL(), g.CPU.SP,
+				tc.af, tc.bc, tc.de, tc.hl, tc.sp)
+		}
+		if g.CPU.PC != 0x100 {
+			t.Errorf("%s: PC=%04x, want 0100", g.Mode, g.CPU.PC)
+		}
+		if lcdc := g.CPU.MMU.Read(ppu.AddrLCDC); lcdc != 0x91 {
+			t.Errorf("%s: LCDC=%02x, want 91", g.Mode, lcdc)
+		}
+		if bgp := g.CPU.MMU.Read(ppu.AddrBGP); bgp != 0xfc {
+			t.Errorf("%s: BGP=%02x, want fc", g.Mode, bgp)
+		}
+	}
+}
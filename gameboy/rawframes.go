@@ -0,0 +1,144 @@
+package gameboy
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+
+	"github.com/lazy-stripes/goholint/apu"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// RawHeaderSize is the length of the text header preceding raw frames (see
+// -rawframes), padded with spaces so that encoders can easily skip it, e.g.
+// with ffmpeg's -skip_initial_bytes option.
+const RawHeaderSize = 64
+
+// Standard output as it was before ReserveStdout, where raw frames go for "-".
+var rawStdout = os.Stdout
+
+// ReserveStdout keeps standard output for raw frames streamed with -rawframes
+// set to "-". Everything else printed there (logs, -iotrace, -opprofile, debug
+// dumps) goes to standard error instead. Call it before anything is printed.
+func ReserveStdout() {
+	if os.Stdout == os.Stderr {
+		return // Already reserved
+	}
+	rawStdout = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// Display wrapper streaming every frame as raw bytes, one pixel after the
+// other line by line, for external encoders. Pixels are either RGBA or color
+// indices from 0 to 3. A header describes the stream, e.g.:
+//
+//	GOHOLINT 160x144 rgba 59.7275
+type rawFrames struct {
+	screen.Display
+
+	out     io.WriteCloser
+	indexed bool           // Color indices instead of RGBA
+	palette *color.Palette // Colors for shades 0-3 (nil for default colors)
+	flip    screen.Flip    // Mirroring applied to frames
+
+	pixels []uint8
+	offset int
+	frame  []byte
+	failed bool // Stop writing after the first error
+}
+
+// Returns a display wrapper writing frames to the given file, or to standard
+// output for "-" (see ReserveStdout). The format is either "rgba" or "indexed".
+func newRawFrames(display screen.Display, path, format string, palette *color.Palette, flip screen.Flip) (*rawFrames, error) {
+	bytesPerPixel := 4
+	switch format {
+	case "", "rgba":
+		format = "rgba"
+	case "indexed":
+		bytesPerPixel = 1
+	default:
+		return nil, fmt.Errorf("unknown raw frame format %q", format)
+	}
+
+	var out io.WriteCloser = rawStdout
+	if path != "-" {
+		// Works with named pipes too, which block until a reader shows up.
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+
+	fps := float64(apu.GameBoyRate) / FrameTicks
+	header := fmt.Sprintf("GOHOLINT %dx%d %s %.4f", screen.ScreenWidth,
+		screen.ScreenHeight, format, fps)
+	if _, err := fmt.Fprintf(out, "%-*s\n", RawHeaderSize-1, header); err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	return &rawFrames{
+		Display: display,
+		out:     out,
+		indexed: bytesPerPixel == 1,
+		palette: palette,
+		flip:    flip,
+		pixels:  make([]uint8, screen.ScreenWidth*screen.ScreenHeight),
+		frame:   make([]byte, screen.ScreenWidth*screen.ScreenHeight*bytesPerPixel),
+	}, nil
+}
+
+// Write keeps a copy of the pixel before passing it on.
+func (r *rawFrames) Write(colorIndex uint8) {
+	if r.Enabled() && r.offset < len(r.pixels) {
+		r.pixels[r.offset] = colorIndex
+		r.offset++
+	}
+	r.Display.Write(colorIndex)
+}
+
+// VBlank shows the frame and writes it out. Frames where the screen was off
+// are written blank.
+func (r *rawFrames) VBlank() {
+	r.Display.VBlank()
+	if r.offset == 0 {
+		for i := range r.pixels {
+			r.pixels[i] = 0
+		}
+	}
+	r.offset = 0
+	if r.failed {
+		return
+	}
+
+	if r.indexed {
+		copy(r.frame, r.pixels)
+		screen.FlipFrame(r.frame, 1, r.flip)
+	} else {
+		palette := screen.DefaultPalette
+		if *r.palette != nil {
+			palette = *r.palette
+		}
+		for i, colorIndex := range r.pixels {
+			c := color.RGBAModel.Convert(palette[colorIndex]).(color.RGBA)
+			r.frame[i*4], r.frame[i*4+1], r.frame[i*4+2], r.frame[i*4+3] = c.R, c.G, c.B, c.A
+		}
+		screen.FlipFrame(r.frame, 4, r.flip)
+	}
+
+	if _, err := r.out.Write(r.frame); err != nil {
+		log.Warningf("writing raw frame failed, stopping: %v", err)
+		r.failed = true
+	}
+}
+
+// Closes the output, unless it's standard output.
+func (r *rawFrames) close() {
+	if r.out != rawStdout {
+		if err := r.out.Close(); err != nil {
+			log.Warningf("closing raw frame output failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gameboy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+func TestRawFrames(t *testing.T) {
+	for format, bytesPerPixel := range map[string]int{"rgba": 4, "indexed": 1} {
+		var path string
+		g, display, done := startTestGameBoy(t, startTestCode, func(args *options.Options) {
+			path = filepath.Join(args.SaveDir, format+".raw")
+			args.RawFrames = path
+			args.RawFormat = format
+		})
+		defer done()
+		runFrames(g, display, 2, func() {})
+		g.rawFrames.close()
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frameSize := screen.ScreenWidth * screen.ScreenHeight * bytesPerPixel
+		if len(raw) != RawHeaderSize+2*frameSize {
+			t.Errorf("%s: wrote %d bytes, want a %d-byte header and two %d-byte frames",
+				format, len(raw), RawHeaderSize, frameSize)
+			continue
+		}
+		header := string(raw[:RawHeaderSize])
+		if !strings.HasPrefix(header, "GOHOLINT 160x144 "+format+" ") ||
+			!strings.HasSuffix(header, "\n") {
+			t.Errorf("%s: unexpected header %q", format, header)
+		}
+	}
+}
+
+func TestReserveStdout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr, rawStdout = stdout, stderr, stdout }()
+	os.Stdout, os.Stderr = w, null
+
+	// Only the header and frames go to the reserved output, logs don't.
+	ReserveStdout()
+	raw, err := newRawFrames(screen.NewHeadless(), "-", "indexed", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Warning("not in the stream")
+	raw.close()
+	w.Close()
+
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != RawHeaderSize || !strings.HasPrefix(string(got), "GOHOLINT ") {
+		t.Errorf("standard output got %q, want only the raw frame header", got)
+	}
+}
@@ -0,0 +1,172 @@
+//go:build !js
+// +build !js
+
+package gameboy
+
+import (
+	"strings"
+
+	"github.com/lazy-stripes/goholint/apu"
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/lazy-stripes/goholint/ppu"
+	"github.com/lazy-stripes/goholint/screen"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// SDL frontend state.
+type frontend struct {
+	// VRAM viewer window, created the first time it's toggled on.
+	viewer *screen.Viewer
+}
+
+// New just instantiates most of the emulator. No biggie.
+func New(args *options.Options) *GameBoy {
+	// TODO: merge GIF encoder in UI/Screen instance.
+	display := screen.NewSDL(args.ZoomFactor, args.VSync)
+	display.JPEGQuality = int(args.JPEGQuality)
+	if args.PalettePath != "" {
+		if palette, err := screen.LoadPalette(args.PalettePath); err == nil {
+			display.SetPalette(palette)
+		} else {
+			log.Warningf("can't load palette %s: %v", args.PalettePath, err)
+		}
+	}
+
+	if blank := blankScreen(args, display.Palette); blank != nil {
+		display.SetBlank(blank)
+	}
+
+	display.SetRecordSkip(args.GIFSkip)
+	display.SetGhosting(ghostDecay(args))
+	display.SetFlip(outputFlip(args))
+
+	checkKeymap(args.Keymap)
+	g := NewWithDisplay(args, display, nil)
+	g.events = true
+	g.adjust = colorAdjust(args)
+	g.setPalette(display.Palette)
+	return g
+}
+
+// Warns about key names SDL doesn't know, whose actions can't be triggered.
+func checkKeymap(keymap options.Keymap) {
+	for action, keyName := range keymap {
+		if sdl.GetKeyFromName(keyName) == sdl.K_UNKNOWN {
+			log.Warningf("unknown key %q for action %s", keyName, action)
+		}
+	}
+}
+
+// AudioSpec returns SDL audio parameters matching the emulator's output, with
+// the buffer size set with -audiobuffer (see apu.BufferFrames). The caller
+// provides the callback.
+func (g *GameBoy) AudioSpec() sdl.AudioSpec {
+	return sdl.AudioSpec{
+		Freq:     int32(g.APU.SamplingRate),
+		Format:   sdl.AUDIO_U8,
+		Channels: 2,
+		Samples:  apu.BufferFrames(g.args.AudioBuffer),
+	}
+}
+
+// Handles pending SDL events, returns true if the window was closed.
+func (g *GameBoy) pollEvents() (quit bool) {
+	sdl.Do(func() {
+		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			eventType := event.GetType()
+			switch eventType {
+
+			// Button presses and UI keys
+			case sdl.KEYDOWN, sdl.KEYUP:
+				keyEvent := event.(*sdl.KeyboardEvent)
+				key := strings.ToLower(sdl.GetKeyName(keyEvent.Keysym.Sym))
+
+				// The ROM browser takes over the keyboard while open.
+				if g.browser != nil {
+					if eventType == sdl.KEYDOWN {
+						g.browseKey(key)
+					}
+					continue
+				}
+
+				if action := g.Controls[key]; action != nil {
+					action(eventType)
+				} else {
+					log.Infof("unknown key %q", key)
+				}
+
+			// Keep the screen fitted to the window.
+			case sdl.WINDOWEVENT:
+				windowEvent := event.(*sdl.WindowEvent)
+				switch {
+				case g.viewer != nil && windowEvent.WindowID == g.viewer.ID():
+					// Closing the viewer only hides it until next toggle.
+					// Focusing it doesn't count as leaving the emulator.
+					switch windowEvent.Event {
+					case sdl.WINDOWEVENT_CLOSE:
+						g.viewer.Hide()
+					case sdl.WINDOWEVENT_FOCUS_GAINED:
+						g.windowFocus(true)
+					}
+				case windowEvent.Event == sdl.WINDOWEVENT_SIZE_CHANGED:
+					g.Display.Resize()
+				case windowEvent.Event == sdl.WINDOWEVENT_FOCUS_LOST:
+					g.windowFocus(false)
+				case windowEvent.Event == sdl.WINDOWEVENT_FOCUS_GAINED:
+					g.windowFocus(true)
+				}
+
+			// Window-closing event
+			case sdl.QUIT:
+				quit = true
+			}
+		}
+	})
+	return
+}
+
+// ToggleVRAMViewer opens, hides or shows a window displaying both tile maps
+// and all tiles in VRAM, updated every frame.
+func (g *GameBoy) ToggleVRAMViewer(eventType uint32) {
+	if eventType != KeyDown {
+		return
+	}
+
+	if g.viewer != nil {
+		g.viewer.Toggle()
+		return
+	}
+
+	viewer, err := screen.NewViewer("Goholint VRAM", ppu.VRAMViewWidth,
+		ppu.VRAMViewHeight, 2)
+	if err != nil {
+		log.Warningf("can't open VRAM viewer: %v", err)
+		return
+	}
+	g.viewer = viewer
+}
+
+// Refreshes the VRAM viewer, if visible.
+func (g *GameBoy) updateViewer() {
+	if g.viewer != nil && g.viewer.Visible() {
+		palette := g.palette
+		if palette == nil {
+			palette = screen.DefaultPalette
+		}
+		g.viewer.Update(g.PPU.VRAMView(palette))
+	}
+}
+
+// Stops the SDL audio callback, and with it emulation, unless headless.
+func (g *GameBoy) stopAudio() {
+	if g.events {
+		sdl.CloseAudio()
+	}
+}
+
+// Closes debug windows, if any.
+func (g *GameBoy) closeWindows() {
+	if g.viewer != nil {
+		g.viewer.Close()
+	}
+}
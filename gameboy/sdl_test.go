@@ -0,0 +1,50 @@
+//go:build !js
+// +build !js
+
+package gameboy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestAudioSpec(t *testing.T) {
+	for requested, expected := range map[uint]uint16{
+		0:      1024, // Default
+		1024:   1024,
+		1000:   1024,
+		3000:   4096,
+		100:    256,
+		100000: 8192,
+	} {
+		g := newTestGameBoy(&options.Options{AudioBuffer: requested})
+		spec := g.AudioSpec()
+		if spec.Samples != expected {
+			t.Errorf("-audiobuffer %d gave %d sample frames, want %d", requested,
+				spec.Samples, expected)
+		}
+		if spec.Freq != int32(g.APU.SamplingRate) || spec.Channels != 2 {
+			t.Errorf("audio spec for %dHz stereo is %dHz with %d channels",
+				g.APU.SamplingRate, spec.Freq, spec.Channels)
+		}
+	}
+}
+
+func TestSDLKeys(t *testing.T) {
+	if KeyDown != sdl.KEYDOWN || KeyUp != sdl.KEYUP {
+		t.Fatal("key event types don't match SDL's")
+	}
+
+	// Key names from the config file match SDL's regardless of case.
+	g := newTestGameBoy(&options.Options{})
+	g.SetControls(options.DefaultKeymap)
+	for _, key := range []sdl.Keycode{sdl.K_RETURN, sdl.K_s, sdl.K_F12, sdl.K_RIGHTBRACKET} {
+		name := sdl.GetKeyName(key)
+		if g.Controls[strings.ToLower(name)] == nil {
+			t.Errorf("no action for key %q", name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package gameboy
+
+import (
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShutdownClosesGIF(t *testing.T) {
+	g, display, done := startTestGameBoy(t, startTestCode, nil)
+	defer done()
+
+	gifPath := filepath.Join(g.args.SaveDir, "test.gif")
+	display.Record(gifPath)
+	for display.Frames < 3 {
+		g.Tick()
+	}
+
+	g.Shutdown()
+	g.Shutdown() // Should be harmless.
+
+	f, err := os.Open(gifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatalf("GIF not properly written on shutdown: %v", err)
+	}
+	if len(anim.Image) == 0 {
+		t.Error("GIF has no frames")
+	}
+}
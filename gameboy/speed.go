@@ -0,0 +1,39 @@
+package gameboy
+
+import "github.com/lazy-stripes/goholint/apu"
+
+// Emulation speed range and increments for the speedup and speeddown actions,
+// as multiples of real time.
+const (
+	MinSpeed  = 0.25
+	MaxSpeed  = 4.0
+	SpeedStep = 0.25
+)
+
+// Speed returns the current emulation speed as a multiple of real time.
+func (g *GameBoy) Speed() float64 {
+	if g.speed == 0 {
+		return 1
+	}
+	return g.speed
+}
+
+// SetSpeed sets the emulation speed as a multiple of real time, clamped
+// between MinSpeed and MaxSpeed. Emulation is paced by the sound card, so
+// this works by producing fewer (or more) samples per emulated second, which
+// also changes the pitch of the sound.
+func (g *GameBoy) SetSpeed(multiplier float64) {
+	if multiplier < MinSpeed {
+		multiplier = MinSpeed
+	}
+	if multiplier > MaxSpeed {
+		multiplier = MaxSpeed
+	}
+	g.speed = multiplier
+
+	rate := g.args.SamplingRate
+	if rate == 0 {
+		rate = apu.DefaultSamplingRate
+	}
+	g.APU.SamplingRate = uint(float64(rate) / multiplier)
+}
@@ -0,0 +1,42 @@
+package gameboy
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lazy-stripes/goholint/memory"
+)
+
+// WriteState writes a human-readable snapshot of the emulator's state (CPU,
+// interrupts, PPU and cartridge mapper) to be attached to bug reports.
+func (g *GameBoy) WriteState(w io.Writer) error {
+	mapper := "none"
+	header := "none"
+	if g.cart != nil {
+		header = memory.ReadHeader(g.cart).String()
+		if stringer, ok := g.cart.(fmt.Stringer); ok {
+			mapper = stringer.String()
+		} else {
+			mapper = "ROM only"
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `Goholint state dump (%s)
+ROM: %s
+Cartridge: %s
+Mapper: %s
+Mode: %s
+Ticks: %d
+
+[CPU]
+%s
+[Interrupts]
+IME: %t - IF: %#02x - IE: %#02x
+
+[PPU]
+%s`,
+		time.Now().Format(time.RFC3339), g.args.ROMPath, header, mapper, g.Mode,
+		g.Ticks(), g.CPU, g.CPU.IME, g.CPU.IF, g.CPU.IE, g.PPU)
+	return err
+}
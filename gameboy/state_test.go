@@ -0,0 +1,55 @@
+package gameboy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/memory/chips"
+)
+
+func TestWriteState(t *testing.T) {
+	args, done := testOptions(t, []byte{0x18, 0xfe}) // JR -2
+	defer done()
+
+	// Turn the test ROM into an MBC1 cartridge to get mapper info.
+	romPath := args.ROMPath
+	rom, err := ioutil.ReadFile(romPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom[memory.AddrCartridgeType] = chips.MBC1
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewWithDisplay(args, &nullDisplay{}, nil)
+	for i := 0; i < 1000; i++ {
+		g.Tick()
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	state := buf.String()
+
+	for _, field := range []string{
+		"ROM: " + romPath,
+		"Mode: DMG",
+		"Ticks: 1000",
+		"PC: 0x0150",
+		"SP: 0xfffe",
+		"IME: false",
+		"IE: 0x00",
+		"LCDC: 0x91",
+		"LY:",
+		"Mapper: MBC1 - ROM bank: 1",
+	} {
+		if !strings.Contains(state, field) {
+			t.Errorf("state dump doesn't contain %q:\n%s", field, state)
+		}
+	}
+}
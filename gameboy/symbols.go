@@ -0,0 +1,90 @@
+package gameboy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/symbols"
+)
+
+// Loads labels from the symbol file given with -sym, or from the one next to
+// the ROM with the same name and a .sym extension if it exists.
+func (g *GameBoy) loadSymbols() {
+	g.symbols = nil
+	path := g.args.Symbols
+	if path == "" && g.args.ROMPath != "" {
+		path = strings.TrimSuffix(g.args.ROMPath, filepath.Ext(g.args.ROMPath)) + ".sym"
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+	}
+	if path == "" {
+		return
+	}
+
+	table, err := symbols.Load(path)
+	if err != nil {
+		log.Warningf("can't load symbols: %v", err)
+		return
+	}
+	log.Infof("loaded %d symbols from %s", table.Len(), path)
+	g.symbols = table
+}
+
+// Sets breakpoints from the comma-separated list given with -breakat. Each one
+// is a label, a bank:address pair, or an address in the current bank.
+func (g *GameBoy) setBreakpoints() {
+	g.breakpoints = make(map[uint16][]symbols.Symbol)
+	g.CPU.Breakpoints = make(map[uint16]bool)
+	for _, name := range strings.Split(g.args.BreakAt, ",") {
+		sym, err := g.symbols.Resolve(strings.TrimSpace(name), g.romBank())
+		if err != nil {
+			log.Warningf("can't set breakpoint: %v", err)
+			continue
+		}
+		log.Infof("breakpoint set at %s", sym)
+		g.breakpoints[sym.Addr] = append(g.breakpoints[sym.Addr], sym)
+		g.CPU.Breakpoints[sym.Addr] = true
+	}
+	g.CPU.Breakpoint = g.Breakpoint
+
+	// LD B,B is only a breakpoint if an action was set for it.
+	g.CPU.IgnoreLDBB = g.args.Breakpoint == ""
+}
+
+// Returns whether one of the breakpoints at the given address is in the ROM
+// bank that's currently mapped, which is only checked for breakpoints in
+// switchable banks. Several banks may have a breakpoint at the same address.
+func (g *GameBoy) breakpointBank(addr uint16) bool {
+	syms, ok := g.breakpoints[addr]
+	if !ok {
+		return true
+	}
+	bank := g.romBank()
+	for _, sym := range syms {
+		if !sym.Banked() || sym.Bank == bank {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the ROM bank mapped at 4000-7FFF, which may have been forced (see
+// memory.BankForcer), defaulting to 1.
+func (g *GameBoy) romBank() uint16 {
+	if cart, ok := g.cart.(memory.BankForcer); ok {
+		return uint16(cart.MappedBank())
+	}
+	return 1
+}
+
+// Returns the label at the given address in the current bank, if any.
+func (g *GameBoy) label(addr uint16) string {
+	if g.symbols == nil {
+		return ""
+	}
+	label, _ := g.symbols.Label(g.romBank(), addr)
+	return label
+}
@@ -0,0 +1,54 @@
+package gameboy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/memory"
+	"github.com/lazy-stripes/goholint/options"
+	"github.com/lazy-stripes/goholint/symbols"
+)
+
+func TestROMBank(t *testing.T) {
+	g := newTestGameBoy(&options.Options{})
+	if bank := g.romBank(); bank != 1 {
+		t.Errorf("ROM bank %d without a cartridge, want 1", bank)
+	}
+
+	cart := memory.NewMBC1(&memory.ROM{RAM: memory.RAM{Bytes: make([]byte, 8*0x4000)}},
+		8, 0, false, "")
+	g.cart = cart
+	cart.Write(0x2000, 2)
+	if bank := g.romBank(); bank != 2 {
+		t.Errorf("ROM bank %d, want 2", bank)
+	}
+
+	// Breakpoints and labels follow the forced bank.
+	if err := cart.ForceBank(5); err != nil {
+		t.Fatal(err)
+	}
+	if bank := g.romBank(); bank != 5 {
+		t.Errorf("ROM bank %d, want forced bank 5", bank)
+	}
+}
+
+func TestBreakpointBanks(t *testing.T) {
+	table, err := symbols.Parse(strings.NewReader("02:4000 Two\n03:4000 Three\n04:4000 Four\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newTestGameBoy(&options.Options{BreakAt: "Two,Three"})
+	g.symbols = table
+	cart := memory.NewMBC1(&memory.ROM{RAM: memory.RAM{Bytes: make([]byte, 8*0x4000)}},
+		8, 0, false, "")
+	g.cart = cart
+	g.setBreakpoints()
+
+	// Labels at the same address in different banks don't replace each other.
+	for bank, expected := range map[uint8]bool{2: true, 3: true, 4: false} {
+		cart.Write(0x2000, bank)
+		if hit := g.breakpointBank(0x4000); hit != expected {
+			t.Errorf("breakpoint hit in bank %d: %t, want %t", bank, hit, expected)
+		}
+	}
+}
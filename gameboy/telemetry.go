@@ -0,0 +1,59 @@
+package gameboy
+
+import (
+	"time"
+
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// FrameStats holds timing telemetry for a single frame, as passed to
+// GameBoy.OnFrame.
+type FrameStats struct {
+	// Frame is the number of frames completed so far, this one included.
+	Frame uint64
+
+	// Emulation is the wall-clock time elapsed since the previous frame, minus
+	// rendering. When running in real time, this includes time spent waiting
+	// for the sound card to ask for more samples.
+	Emulation time.Duration
+
+	// Render is the time the display took to show the frame.
+	Render time.Duration
+
+	// FPS is the frame rate achieved over the last frame.
+	FPS float64
+}
+
+// Display wrapper timing VBlank (i.e. rendering) for frame telemetry.
+type timedDisplay struct {
+	screen.Display
+	render *time.Duration
+}
+
+// VBlank renders the frame and records how long it took.
+func (d timedDisplay) VBlank() {
+	start := time.Now()
+	d.Display.VBlank()
+	*d.render = time.Since(start)
+}
+
+// Reports telemetry for the frame that just ended, if anyone's listening.
+func (g *GameBoy) reportFrame() {
+	now := time.Now()
+	elapsed := now.Sub(g.frameStart)
+	first := g.frameStart.IsZero()
+	g.frameStart = now
+	g.frames++
+
+	if g.OnFrame == nil {
+		return
+	}
+	stats := FrameStats{Frame: g.frames, Render: g.renderTime}
+	if !first {
+		stats.Emulation = elapsed - g.renderTime
+		if elapsed > 0 {
+			stats.FPS = float64(time.Second) / float64(elapsed)
+		}
+	}
+	g.OnFrame(stats)
+}
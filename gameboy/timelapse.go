@@ -0,0 +1,105 @@
+package gameboy
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// Display wrapper keeping a copy of the pixels drawn so that every Nth frame
+// can be saved as a numbered PNG file (see -timelapse). Files are written in
+// the background so emulation doesn't wait on the encoder.
+type timeLapse struct {
+	screen.Display
+
+	every   uint64         // Save one frame out of that many
+	base    string         // Path and prefix for files, numbered by frame
+	palette *color.Palette // Colors for shades 0-3 (nil for default colors)
+	flip    screen.Flip    // Mirroring applied to saved frames
+
+	pixels []uint8
+	offset int
+	frames uint64
+
+	pending sync.WaitGroup
+}
+
+// Returns a time-lapse display wrapper saving every Nth frame in the given
+// folder, in files named after the current time and frame number.
+func newTimeLapse(display screen.Display, every uint, dir string, palette *color.Palette, flip screen.Flip) *timeLapse {
+	if dir == "" {
+		dir = "."
+	}
+	base := fmt.Sprintf("goholint-%s", time.Now().Format(DateFormat))
+	return &timeLapse{
+		Display: display,
+		every:   uint64(every),
+		base:    filepath.Join(dir, base),
+		palette: palette,
+		flip:    flip,
+		pixels:  make([]uint8, screen.ScreenWidth*screen.ScreenHeight),
+	}
+}
+
+// Write keeps a copy of the pixel before passing it on.
+func (t *timeLapse) Write(colorIndex uint8) {
+	if t.Enabled() && t.offset < len(t.pixels) {
+		t.pixels[t.offset] = colorIndex
+		t.offset++
+	}
+	t.Display.Write(colorIndex)
+}
+
+// VBlank shows the frame and starts saving it if its number is a multiple of
+// the time-lapse interval. Frames where the screen was off are saved blank.
+func (t *timeLapse) VBlank() {
+	t.Display.VBlank()
+	t.frames++
+
+	if t.frames%t.every == 0 {
+		palette := screen.DefaultPalette
+		if *t.palette != nil {
+			palette = *t.palette
+		}
+		img := image.NewPaletted(screen.FrameBounds, palette)
+		if t.offset != 0 {
+			copy(img.Pix, t.pixels)
+			screen.FlipFrame(img.Pix, 1, t.flip)
+		}
+		filename := fmt.Sprintf("%s-%06d.png", t.base, t.frames)
+
+		t.pending.Add(1)
+		go func() {
+			defer t.pending.Done()
+			if err := savePNG(filename, img); err != nil {
+				log.Warningf("saving time-lapse frame failed: %v", err)
+			}
+		}()
+	}
+	t.offset = 0
+}
+
+// Waits for frames still being saved.
+func (t *timeLapse) wait() {
+	t.pending.Wait()
+}
+
+// Writes an image to a PNG file.
+func savePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,39 @@
+package gameboy
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/options"
+)
+
+func TestTimeLapse(t *testing.T) {
+	code := []byte{0x18, 0xfe} // JR -2
+	g, display, done := startTestGameBoy(t, code, func(args *options.Options) {
+		args.TimeLapse = 3
+	})
+	defer done()
+	dir := g.args.SaveDir
+
+	runFrames(g, display, 10, func() {})
+	g.timeLapse.wait()
+
+	files, err := filepath.Glob(filepath.Join(dir, "goholint-*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frames []string
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".png")
+		frames = append(frames, name[strings.LastIndex(name, "-")+1:])
+	}
+	sort.Strings(frames)
+
+	expected := []string{"000003", "000006", "000009"}
+	if !reflect.DeepEqual(frames, expected) {
+		t.Errorf("saved frames %v, want %v", frames, expected)
+	}
+}
@@ -0,0 +1,91 @@
+package gameboy
+
+import (
+	"strings"
+
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// DefaultTurboRate is the number of frames between autofire press and release
+// when not set with -turborate, i.e. 15 presses per second.
+const DefaultTurboRate = 2
+
+// Autofire settings and state. While a turbo button is held, it alternates
+// between pressed and released every few frames (see -turbo and -turborate).
+type autofire struct {
+	buttons map[string]bool   // Buttons with autofire
+	held    map[string]uint64 // Buttons held down, with the frame they were pressed on
+	rate    uint64            // Frames between state changes
+	enabled bool
+}
+
+// Returns autofire settings for the given comma-separated button names, or nil
+// if there are none.
+func newAutofire(buttons string, rate uint) *autofire {
+	if buttons == "" {
+		return nil
+	}
+	if rate == 0 {
+		rate = DefaultTurboRate
+	}
+	a := &autofire{
+		buttons: make(map[string]bool),
+		held:    make(map[string]uint64),
+		rate:    uint64(rate),
+		enabled: true,
+	}
+	for _, name := range strings.Split(buttons, ",") {
+		a.buttons[strings.TrimSpace(name)] = true
+	}
+	return a
+}
+
+// Returns whether a held turbo button should be pressed at the given frame.
+func (a *autofire) pressed(name string, frame uint64) bool {
+	return (frame-a.held[name])/a.rate%2 == 0
+}
+
+// Keeps track of turbo buttons being held or released from user input, before
+// they are applied to the joypad.
+func (g *GameBoy) holdButton(name string, pressed bool) {
+	a := g.autofire
+	if a == nil || !a.buttons[name] {
+		return
+	}
+	if pressed {
+		a.held[name] = g.Ticks() / FrameTicks
+	} else {
+		delete(a.held, name)
+	}
+}
+
+// Updates held turbo buttons at the start of each frame.
+func (g *GameBoy) applyAutofire(frame uint64) {
+	a := g.autofire
+	if a == nil || !a.enabled {
+		return
+	}
+	for name := range a.held {
+		if pressed := a.pressed(name, frame); g.JPad.Button(name).State != pressed {
+			g.applyButton(name, pressed)
+		}
+	}
+}
+
+// ToggleTurbo turns autofire on or off for the buttons set with -turbo.
+// Buttons held while turning it off stay pressed.
+func (g *GameBoy) ToggleTurbo(eventType uint32) {
+	if eventType != KeyDown || g.autofire == nil {
+		return
+	}
+	a := g.autofire
+	a.enabled = !a.enabled
+	if a.enabled {
+		g.Display.Message("Autofire on", 2, screen.PriorityInfo)
+	} else {
+		for name := range a.held {
+			g.applyButton(name, true)
+		}
+		g.Display.Message("Autofire off", 2, screen.PriorityInfo)
+	}
+}
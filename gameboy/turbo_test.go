@@ -0,0 +1,52 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/lazy-stripes/goholint/options"
+)
+
+func TestAutofire(t *testing.T) {
+	g, _, done := startTestGameBoy(t, startTestCode, func(args *options.Options) {
+		args.Turbo = "start"
+		args.TurboRate = 3
+	})
+	defer done()
+
+	// Returns the state of Start at the beginning of each of the next frames.
+	states := func(frames int) (pressed []bool) {
+		for i := 0; i < frames; i++ {
+			g.Tick()
+			for g.Ticks()%FrameTicks != 0 {
+				g.Tick()
+			}
+			g.Tick() // Apply autofire for the new frame.
+			pressed = append(pressed, g.JPad.Start.State)
+		}
+		return
+	}
+
+	g.setButton("start", true)
+	expected := []bool{true, true, false, false, false, true, true, true, false, false}
+	for i, pressed := range states(10) {
+		if pressed != expected[i] {
+			t.Fatalf("start pressed at frame %d: %t, want %t", i, pressed, expected[i])
+		}
+	}
+
+	// Turning autofire off keeps held buttons pressed.
+	g.ToggleTurbo(KeyDown)
+	for i, pressed := range states(6) {
+		if !pressed {
+			t.Fatalf("start released at frame %d with autofire off", i)
+		}
+	}
+
+	g.ToggleTurbo(KeyDown)
+	g.setButton("start", false)
+	for i, pressed := range states(6) {
+		if pressed {
+			t.Fatalf("start pressed at frame %d after release", i)
+		}
+	}
+}
@@ -70,7 +70,8 @@ func (i *Interrupts) Contains(addr uint16) bool {
 func (i *Interrupts) Read(addr uint16) uint8 {
 	switch addr {
 	case AddrIF:
-		return *i.regIF & 0x1f
+		// Unused upper bits always read as 1.
+		return *i.regIF | 0xe0
 	case AddrIE:
 		return *i.regIE
 	}
@@ -94,3 +95,17 @@ func (i *Interrupts) Write(addr uint16, value uint8) {
 func (i *Interrupts) Request(interrupt uint8) {
 	*i.regIF |= interrupt
 }
+
+// Pending returns the highest-priority interrupt that is both requested and
+// enabled, or 0 if there is none.
+func (i *Interrupts) Pending() uint8 {
+	return Highest(*i.regIF & *i.regIE)
+}
+
+// Highest returns the highest-priority interrupt among the given flags, or 0
+// if none is set. Priority follows bit order, VBlank (bit 0) being the
+// highest and Joypad (bit 4) the lowest.
+func Highest(flags uint8) uint8 {
+	flags &= 0x1f
+	return flags & -flags
+}
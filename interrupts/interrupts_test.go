@@ -0,0 +1,34 @@
+package interrupts
+
+import "testing"
+
+func TestPending(t *testing.T) {
+	testCases := []struct {
+		regIF, regIE, expected uint8
+	}{
+		{0x00, 0x1f, 0},
+		{0x1f, 0x00, 0},
+		{Joypad | Serial, 0x1f, Serial},
+		{Joypad | Timer | LCDStat, 0x1f, LCDStat},
+		{VBlank | Joypad, 0x1f, VBlank},
+		{VBlank | Timer, Timer, Timer}, // VBlank not enabled
+		{0xe0 | Joypad, 0xff, Joypad},  // Upper bits don't count
+	}
+
+	for _, tc := range testCases {
+		i := New(&tc.regIF, &tc.regIE)
+		if pending := i.Pending(); pending != tc.expected {
+			t.Errorf("IF=0x%02x IE=0x%02x: Pending() == 0x%02x, want 0x%02x",
+				tc.regIF, tc.regIE, pending, tc.expected)
+		}
+	}
+}
+
+func TestReadIF(t *testing.T) {
+	var regIF, regIE uint8
+	i := New(&regIF, &regIE)
+	i.Request(Timer)
+	if value := i.Read(AddrIF); value != 0xe4 {
+		t.Errorf("IF reads 0x%02x, want 0xe4", value)
+	}
+}
@@ -1,6 +1,9 @@
 package joypad
 
 import (
+	"fmt"
+
+	"github.com/lazy-stripes/goholint/interrupts"
 	"github.com/lazy-stripes/goholint/logger"
 )
 
@@ -30,6 +33,32 @@ const (
 	P15             // Bit 5 - Select Button Keys      (0=Select)
 )
 
+// SOCD (Simultaneous Opposing Cardinal Directions) resolution, deciding what
+// the game sees when Left and Right (or Up and Down) are pressed at once. A
+// real joypad can't do that, but a keyboard can and some games glitch.
+type SOCD uint8
+
+// Supported SOCD resolution modes.
+const (
+	SOCDRaw     SOCD = iota // Both directions are seen as pressed
+	SOCDNeutral             // Neither direction is seen as pressed
+	SOCDLast                // Only the latest direction pressed is seen
+)
+
+// ParseSOCD returns the SOCD resolution mode with the given name: raw,
+// neutral or last.
+func ParseSOCD(name string) (SOCD, error) {
+	switch name {
+	case "raw":
+		return SOCDRaw, nil
+	case "neutral":
+		return SOCDNeutral, nil
+	case "last":
+		return SOCDLast, nil
+	}
+	return SOCDRaw, fmt.Errorf("unknown SOCD resolution mode %s", name)
+}
+
 // Input storing needed bits for a button or a direction.
 type Input struct {
 	Selector uint8 // P14 or P15
@@ -41,6 +70,17 @@ type Input struct {
 type Joypad struct {
 	JOYP uint8
 
+	// Interrupts, if set, get a joypad interrupt request whenever a selected
+	// input gets pressed. This is also what wakes the CPU up from STOP.
+	Interrupts *interrupts.Interrupts
+
+	// OnPress, if set, is called whenever a selected input line goes low, e.g.
+	// to wake the CPU up from STOP (see cpu.CPU.Wake).
+	OnPress func()
+
+	// SOCD resolution applied to opposing directions (raw by default).
+	SOCD SOCD
+
 	Up     Input
 	Down   Input
 	Left   Input
@@ -51,6 +91,9 @@ type Joypad struct {
 	Start  Input
 
 	inputs []*Input // To iterate on all inputs at once
+
+	// Latest direction pressed on each axis, for SOCDLast.
+	lastX, lastY *Input
 }
 
 // New instantiates a Joypad addressable mapping to FF00 that will wait for
@@ -90,17 +133,47 @@ func (j *Joypad) Contains(addr uint16) bool {
 // Read returns the state of selected inputs (inverted logic).
 func (j *Joypad) Read(addr uint16) (value uint8) {
 	selected := j.JOYP & 0x30
-	// Set bits for inactive/unselected inputs, then NOT it all.
+	value = (^j.lines())&0x0f | selected
+	log.Sub("read").Desperatef("JOYP=0x%02x", value)
+	return value
+}
+
+// Returns bits set for selected inputs seen as pressed (proper logic).
+func (j *Joypad) lines() (value uint8) {
 	for _, input := range j.inputs {
-		if selected&input.Selector == 0 && input.State {
+		if j.JOYP&input.Selector == 0 && j.active(input) {
 			value |= input.Bit
 		}
 	}
-	value = (^value)&0x0f | selected
-	log.Sub("read").Desperatef("JOYP=0x%02x", value)
 	return value
 }
 
+// Returns whether an input should be seen as pressed, resolving opposing
+// directions according to the SOCD mode.
+func (j *Joypad) active(input *Input) bool {
+	if !input.State || j.SOCD == SOCDRaw {
+		return input.State
+	}
+
+	var opposite, last *Input
+	switch input {
+	case &j.Left:
+		opposite, last = &j.Right, j.lastX
+	case &j.Right:
+		opposite, last = &j.Left, j.lastX
+	case &j.Up:
+		opposite, last = &j.Down, j.lastY
+	case &j.Down:
+		opposite, last = &j.Up, j.lastY
+	default:
+		return true
+	}
+	if !opposite.State {
+		return true
+	}
+	return j.SOCD == SOCDLast && last == input
+}
+
 // Write updates the writeable bits of the JOYP register.
 func (j *Joypad) Write(addr uint16, value uint8) {
 	j.JOYP = value & 0x30
@@ -108,10 +181,72 @@ func (j *Joypad) Write(addr uint16, value uint8) {
 
 // KeyDown updates button states (if needed) when a key was pressed.
 func (j *Joypad) KeyDown(input *Input) {
-	input.State = true
+	j.set(input, true)
 }
 
 // KeyUp updates button states (if needed) when a key was released.
 func (j *Joypad) KeyUp(input *Input) {
-	input.State = false
+	j.set(input, false)
+}
+
+// Button returns the input associated with the given action name as used in
+// keymaps ("up", "down", "left", "right", "a", "b", "select" or "start"), or
+// nil if there is no such button.
+func (j *Joypad) Button(name string) *Input {
+	switch name {
+	case "up":
+		return &j.Up
+	case "down":
+		return &j.Down
+	case "left":
+		return &j.Left
+	case "right":
+		return &j.Right
+	case "a":
+		return &j.A
+	case "b":
+		return &j.B
+	case "select":
+		return &j.Select
+	case "start":
+		return &j.Start
+	}
+	return nil
+}
+
+// SetButton presses or releases a button by name (see Button), regardless of
+// where the input comes from. This is meant for scripted inputs.
+func (j *Joypad) SetButton(name string, pressed bool) error {
+	input := j.Button(name)
+	if input == nil {
+		return fmt.Errorf("unknown button %s", name)
+	}
+	log.Sub("input").Debugf("%s pressed: %t", name, pressed)
+	j.set(input, pressed)
+	return nil
+}
+
+// Sets an input's state and requests an interrupt if any selected line in JOYP
+// goes from high to low as a result. With SOCD resolution, releasing a
+// direction can also make the opposite one go low.
+func (j *Joypad) set(input *Input, pressed bool) {
+	before := j.lines()
+	if pressed && !input.State {
+		switch input {
+		case &j.Left, &j.Right:
+			j.lastX = input
+		case &j.Up, &j.Down:
+			j.lastY = input
+		}
+	}
+	input.State = pressed
+	if j.lines()&^before == 0 {
+		return
+	}
+	if j.Interrupts != nil {
+		j.Interrupts.Request(interrupts.Joypad)
+	}
+	if j.OnPress != nil {
+		j.OnPress()
+	}
 }
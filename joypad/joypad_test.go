@@ -0,0 +1,101 @@
+package joypad
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	script, err := ParseScript(strings.NewReader("# Press Start for one frame.\n10 start press\n11 start release\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := New()
+	j.Write(AddrJOYP, P14) // Select button keys.
+	for frame := uint64(9); frame <= 12; frame++ {
+		script.Apply(j, frame)
+
+		pressed := j.Read(AddrJOYP)&P13 == 0 // Inverted logic.
+		if pressed != (frame == 10) {
+			t.Errorf("frame %d: Start pressed=%t in JOYP", frame, pressed)
+		}
+	}
+
+	if _, err := ParseScript(strings.NewReader("1 turbo press")); err == nil {
+		t.Error("no error for unknown button")
+	}
+}
+
+func TestSOCD(t *testing.T) {
+	cases := []struct {
+		mode        SOCD
+		left, right bool // Expected states with Left then Right pressed.
+		afterRight  bool // Expected Left state after releasing Right.
+	}{
+		{SOCDRaw, true, true, true},
+		{SOCDNeutral, false, false, true},
+		{SOCDLast, false, true, true},
+	}
+
+	for _, c := range cases {
+		j := New()
+		j.SOCD = c.mode
+		j.Write(AddrJOYP, P15) // Select direction keys.
+		pressed := func(bit uint8) bool { return j.Read(AddrJOYP)&bit == 0 }
+
+		j.SetButton("left", true)
+		j.SetButton("right", true)
+		if pressed(P11) != c.left || pressed(P10) != c.right {
+			t.Errorf("mode %d: Left=%t, Right=%t with both pressed, want %t, %t",
+				c.mode, pressed(P11), pressed(P10), c.left, c.right)
+		}
+
+		j.SetButton("right", false)
+		if pressed(P11) != c.afterRight || pressed(P10) {
+			t.Errorf("mode %d: Left=%t, Right=%t after releasing Right",
+				c.mode, pressed(P11), pressed(P10))
+		}
+
+		// Other axis and buttons are not affected.
+		j.SetButton("up", true)
+		if !pressed(P12) {
+			t.Errorf("mode %d: Up not pressed", c.mode)
+		}
+	}
+
+	// Pressing Down last takes priority over Up, in either order.
+	j := New()
+	j.SOCD = SOCDLast
+	j.Write(AddrJOYP, P15)
+	j.SetButton("down", true)
+	j.SetButton("up", true)
+	j.SetButton("down", true) // Still held, doesn't count as a new press.
+	if value := j.Read(AddrJOYP); value&P12 != 0 || value&P13 == 0 {
+		t.Errorf("JOYP=0x%02x with Down then Up pressed, want Up only", value)
+	}
+
+	if _, err := ParseSOCD("first"); err == nil {
+		t.Error("no error for unknown SOCD mode")
+	}
+}
+
+func TestKeyMap(t *testing.T) {
+	j := New()
+	j.Write(AddrJOYP, P14) // Select button keys.
+
+	if !DefaultKeyMap.Key(j, "Enter", true) {
+		t.Fatal("Enter not mapped")
+	}
+	if !j.Start.State || j.Read(AddrJOYP)&P13 != 0 {
+		t.Error("Start not pressed by its key")
+	}
+	DefaultKeyMap.Key(j, "Enter", false)
+	if j.Start.State {
+		t.Error("Start still pressed after releasing its key")
+	}
+
+	if DefaultKeyMap.Key(j, "KeyQ", true) {
+		t.Error("unmapped key handled")
+	}
+}
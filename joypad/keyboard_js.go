@@ -0,0 +1,31 @@
+//go:build js && wasm
+// +build js,wasm
+
+package joypad
+
+import "syscall/js"
+
+// ListenKeyboard feeds keyboard events from the given target (usually the
+// document) to the joypad, looking up KeyboardEvent.code in the key map.
+// Handled keys don't reach the page. The returned function stops listening.
+func ListenKeyboard(j *Joypad, target js.Value, keys KeyMap) (stop func()) {
+	handler := func(pressed bool) js.Func {
+		return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			event := args[0]
+			if keys.Key(j, event.Get("code").String(), pressed) {
+				event.Call("preventDefault")
+			}
+			return nil
+		})
+	}
+	keyDown, keyUp := handler(true), handler(false)
+	target.Call("addEventListener", "keydown", keyDown)
+	target.Call("addEventListener", "keyup", keyUp)
+
+	return func() {
+		target.Call("removeEventListener", "keydown", keyDown)
+		target.Call("removeEventListener", "keyup", keyUp)
+		keyDown.Release()
+		keyUp.Release()
+	}
+}
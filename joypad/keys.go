@@ -0,0 +1,30 @@
+package joypad
+
+// KeyMap associates key names from an input source with button names as used
+// by Button, so frontends without SDL can drive the joypad. Key names are up to
+// the source, e.g. "ArrowUp" or "KeyS" for browser keyboard events.
+type KeyMap map[string]string
+
+// DefaultKeyMap matches the default SDL controls, using the browser's
+// KeyboardEvent.code names.
+var DefaultKeyMap = KeyMap{
+	"ArrowUp":    "up",
+	"ArrowDown":  "down",
+	"ArrowLeft":  "left",
+	"ArrowRight": "right",
+	"KeyS":       "a",
+	"KeyD":       "b",
+	"Backspace":  "select",
+	"Enter":      "start",
+}
+
+// Key presses or releases the button mapped to the given key, and returns
+// false if there is none.
+func (k KeyMap) Key(j *Joypad, key string, pressed bool) bool {
+	input := j.Button(k[key])
+	if input == nil {
+		return false
+	}
+	j.set(input, pressed)
+	return true
+}
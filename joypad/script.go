@@ -0,0 +1,118 @@
+package joypad
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScriptEvent is a single button change in an input script.
+type ScriptEvent struct {
+	Button  string
+	Pressed bool
+}
+
+// Script maps frame numbers to the button changes occurring at the start of
+// that frame, to replay inputs deterministically.
+type Script map[uint64][]ScriptEvent
+
+// LoadScript reads an input script from a file (see ParseScript).
+func LoadScript(filename string) (Script, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseScript(f)
+}
+
+// ParseScript reads an input script with one event per line in the form
+// "<frame> <button> press|release", e.g. "120 start press". Empty lines and
+// everything after a # are ignored.
+func ParseScript(r io.Reader) (Script, error) {
+	script := make(Script)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected <frame> <button> press|release", n)
+		}
+
+		frame, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid frame %s", n, fields[0])
+		}
+		button := strings.ToLower(fields[1])
+		if (&Joypad{}).Button(button) == nil {
+			return nil, fmt.Errorf("line %d: unknown button %s", n, fields[1])
+		}
+		var pressed bool
+		switch strings.ToLower(fields[2]) {
+		case "press":
+			pressed = true
+		case "release":
+			pressed = false
+		default:
+			return nil, fmt.Errorf("line %d: expected press or release, got %s", n, fields[2])
+		}
+
+		script.Add(frame, button, pressed)
+	}
+	return script, scanner.Err()
+}
+
+// Apply sets button states for all events scheduled at the given frame.
+func (s Script) Apply(j *Joypad, frame uint64) {
+	for _, event := range s[frame] {
+		j.SetButton(event.Button, event.Pressed)
+	}
+}
+
+// Changes returns whether applying the events scheduled at the given frame
+// would change any button state.
+func (s Script) Changes(j *Joypad, frame uint64) bool {
+	for _, event := range s[frame] {
+		if input := j.Button(event.Button); input != nil && input.State != event.Pressed {
+			return true
+		}
+	}
+	return false
+}
+
+// Add schedules a button change at the given frame.
+func (s Script) Add(frame uint64, button string, pressed bool) {
+	s[frame] = append(s[frame], ScriptEvent{button, pressed})
+}
+
+// String returns the script in the format expected by ParseScript, ordered by
+// frame.
+func (s Script) String() string {
+	frames := make([]uint64, 0, len(s))
+	for frame := range s {
+		frames = append(frames, frame)
+	}
+	sort.Slice(frames, func(i, j int) bool { return frames[i] < frames[j] })
+
+	var b strings.Builder
+	for _, frame := range frames {
+		for _, event := range s[frame] {
+			action := "release"
+			if event.Pressed {
+				action = "press"
+			}
+			fmt.Fprintf(&b, "%d %s %s\n", frame, event.Button, action)
+		}
+	}
+	return b.String()
+}
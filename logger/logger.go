@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strings"
 )
 
 // Copy of the last string displayed to gracefully handle repeated text.
@@ -32,6 +33,7 @@ const (
 var Levels = map[string]LogLevel{
 	"fatal":     Fatal,
 	"warning":   Warning,
+	"warn":      Warning,
 	"info":      Info,
 	"debug":     Debug,
 	"desperate": Desperate,
@@ -40,6 +42,10 @@ var Levels = map[string]LogLevel{
 // Level is the global log level above which nothing will be displayed.
 var Level = Info // Sensible default
 
+// ModuleLevels overrides the global log level for specific packages or
+// sub-modules. Modules listed here are also considered enabled.
+var ModuleLevels = make(map[string]LogLevel)
+
 // Enabled setting controls whether logging will occur for a given module name
 // when the usual methods are called. Default is no logging. Enabling 'all'
 // will turn on debug output for every module.
@@ -104,12 +110,18 @@ func (l *Logger) Sub(name string) *Logger {
 // global log level permits it.
 func (l *Logger) log(level LogLevel, format string, a ...interface{}) {
 	// "Do we need to log this?"
-	if level > Level {
-		return
-	}
+	if max, ok := l.moduleLevel(); ok {
+		if level > max {
+			return
+		}
+	} else {
+		if level > Level {
+			return
+		}
 
-	if !(Enabled["all"] || Enabled[l.Name] || Enabled[l.wildcard]) {
-		return
+		if !(Enabled["all"] || Enabled[l.Name] || Enabled[l.wildcard]) {
+			return
+		}
 	}
 
 	fmt.Print(Context())
@@ -127,6 +139,57 @@ func (l *Logger) log(level LogLevel, format string, a ...interface{}) {
 	}
 }
 
+// Returns the level specifically set for this logger or its package, if any.
+func (l *Logger) moduleLevel() (level LogLevel, ok bool) {
+	if level, ok = ModuleLevels[l.Name]; ok {
+		return
+	}
+	level, ok = ModuleLevels[strings.TrimSuffix(l.wildcard, "/*")]
+	return
+}
+
+// ParseLevels parses a comma-separated list of log levels, either global
+// (e.g. "debug") or per module (e.g. "ppu:debug,apu:warn,default:info"), and
+// returns them by module name. The global level uses the "default" key.
+func ParseLevels(spec string) (map[string]LogLevel, error) {
+	levels := make(map[string]LogLevel)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		module, name := "default", item
+		if i := strings.LastIndex(item, ":"); i >= 0 {
+			module, name = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+
+		level, ok := Levels[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown log level %s", name)
+		}
+		levels[module] = level
+	}
+	return levels, nil
+}
+
+// SetLevels parses a log level specification (see ParseLevels) and applies
+// it to the global level and per-module levels.
+func SetLevels(spec string) error {
+	levels, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	for module, level := range levels {
+		if module == "default" {
+			Level = level
+		} else {
+			ModuleLevels[module] = level
+		}
+	}
+	return nil
+}
+
 // Fatal prints a message (then panics regardless of debug level).
 func (l *Logger) Fatal(msg string) {
 	l.log(Fatal, "%s", msg)
@@ -241,5 +304,6 @@ func HelpLevels() {
 	fmt.Println("desperate")
 	fmt.Println()
 	fmt.Println("Debug modules must be enabled for anything to be displayed")
-	fmt.Println("(see -debug help).")
+	fmt.Println("(see -debug help), unless given a level of their own with")
+	fmt.Println("-level <module>:<level>, e.g. -level ppu:debug,apu:warn,default:info")
 }
@@ -0,0 +1,34 @@
+package logger
+
+import "testing"
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("ppu:debug, apu:warn,default:INFO,ppu/ticks:desperate")
+	if err != nil {
+		t.Fatalf("ParseLevels() returned error: %s", err)
+	}
+
+	want := map[string]LogLevel{
+		"ppu":       Debug,
+		"apu":       Warning,
+		"default":   Info,
+		"ppu/ticks": Desperate,
+	}
+	if len(levels) != len(want) {
+		t.Errorf("ParseLevels() returned %d levels, want %d", len(levels), len(want))
+	}
+	for module, level := range want {
+		if levels[module] != level {
+			t.Errorf("level for %s == %d, want %d", module, levels[module], level)
+		}
+	}
+
+	// Plain level for backwards compatibility.
+	if levels, _ := ParseLevels("debug"); levels["default"] != Debug {
+		t.Errorf("ParseLevels(\"debug\") == %v, want default:debug", levels)
+	}
+
+	if _, err := ParseLevels("ppu:loud"); err == nil {
+		t.Error("ParseLevels() accepted an unknown level")
+	}
+}
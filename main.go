@@ -20,15 +20,15 @@ import (
 	"os/signal"
 	"reflect"
 	"runtime/pprof"
-	"strings"
+	"syscall"
 	"unsafe"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 
-	"github.com/lazy-stripes/goholint/apu"
 	"github.com/lazy-stripes/goholint/gameboy"
 	"github.com/lazy-stripes/goholint/logger"
+	"github.com/lazy-stripes/goholint/memory"
 	"github.com/lazy-stripes/goholint/options"
 )
 
@@ -36,8 +36,13 @@ import (
 
 var quit chan bool // Used by the callback to tell the main function to quit.
 
+var exitCode int // Process exit code, set once the emulator is stopped.
+
+// Used to stop the -noaudio emulation loop and wait for it to return.
+var silentStop, silentDone chan struct{}
+
 func init() {
-	quit = make(chan bool)
+	quit = make(chan bool, 1)
 }
 
 // Not sure how I'm supposed to pass this to SDL. Go doesn't allow Go pointers
@@ -64,7 +69,13 @@ func mainLoopCallback(data unsafe.Pointer, buf *C.Uint8, len C.int) {
 		res := gb.Tick()
 
 		if res.Quit {
-			quit <- true
+			// Don't block if we already asked, the audio callback may be
+			// called again before audio is closed.
+			select {
+			case quit <- true:
+			default:
+			}
+			return
 		}
 
 		if res.Play {
@@ -75,23 +86,48 @@ func mainLoopCallback(data unsafe.Pointer, buf *C.Uint8, len C.int) {
 	}
 }
 
-// Print debug data on CTRL+C.
-func handleSIGINT(c chan os.Signal, gb *gameboy.GameBoy) {
-	// Wait for signal, quit cleanly with potential extra debug info if needed.
-	<-c
-	fmt.Println("\nTerminated...")
+// Emulation loop used instead of the audio callback with -noaudio. Samples go
+// to a NullAudio sink, which also paces emulation in real time. Returns when
+// the emulator quits or when stop is closed, closing done either way.
+func runSilent(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	defer gb.Recover()
+
+	sink := &gameboy.NullAudio{Rate: gb.APU.SamplingRate}
+	gb.Audio = sink
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
 
-	gb.Display.Close()
+		res := gb.Tick()
+		if res.Quit {
+			select {
+			case quit <- true:
+			default:
+			}
+			return
+		}
 
-	// TODO: only dump RAM/VRAM/Other if requested in parameters.
-	fmt.Print(gb.CPU)
-	fmt.Print(gb.PPU)
-	gb.CPU.DumpRAM()
+		// Don't check the time for every single sample.
+		if res.Play && sink.Samples%512 == 0 {
+			sink.Wait()
+		}
+	}
+}
 
-	// Force stopping CPU profiling.
-	pprof.StopCPUProfile()
+// Quit cleanly on CTRL+C or termination.
+func handleSignals(c chan os.Signal) {
+	<-c
+	fmt.Println("\nTerminated...")
 
-	os.Exit(-1)
+	// Let run() shut the emulator down as if the user had quit.
+	select {
+	case quit <- true:
+	default:
+	}
 }
 
 // Separate function to forcefully run in the main thread.
@@ -103,11 +139,8 @@ func run() {
 		os.Exit(0)
 	}
 
-	level, ok := logger.Levels[strings.ToLower(args.DebugLevel)]
-	if ok {
-		logger.Level = level
-	} else {
-		log.Fatal("unknown log level ", args.DebugLevel)
+	if err := logger.SetLevels(args.DebugLevel); err != nil {
+		log.Fatal(err)
 	}
 
 	for _, m := range args.DebugModules {
@@ -121,6 +154,29 @@ func run() {
 		logger.Enabled[m] = true
 	}
 
+	// Only show cartridge info if requested.
+	if args.Info {
+		header, err := memory.LoadHeader(args.ROMPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(header.Info())
+		os.Exit(0)
+	}
+
+	// Benchmarks run headless, without SDL.
+	if args.Benchmark > 0 {
+		fmt.Println(gameboy.Benchmark(args, args.Benchmark))
+		os.Exit(0)
+	}
+
+	// Nothing else may be printed to standard output when streaming frames to
+	// it, logs and traces go to standard error instead.
+	if args.RawFrames == "-" {
+		gameboy.ReserveStdout()
+	}
+
 	if args.CPUProfile != "" {
 		f, err := os.Create(args.CPUProfile)
 		if err != nil {
@@ -136,7 +192,11 @@ func run() {
 
 	// Execute all SDL operations in the main thread.
 	sdl.Do(func() {
-		sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO | sdl.INIT_EVENTS)
+		var flags uint32 = sdl.INIT_VIDEO | sdl.INIT_EVENTS
+		if !args.NoAudio {
+			flags |= sdl.INIT_AUDIO
+		}
+		sdl.Init(flags)
 		ttf.Init()
 
 		// Instantiate emulator and use it with signal interrupts.
@@ -149,25 +209,27 @@ func run() {
 			bufio.NewReader(os.Stdin).ReadBytes('\n')
 		}
 
-		// Handle SIGINT, store pointers to CPU and PPU for debug info.
+		// Handle SIGINT/SIGTERM.
 		c := make(chan os.Signal, 1)
-		go handleSIGINT(c, gb)
-		signal.Notify(c, os.Interrupt)
-
-		// Add CPU-specific context to debug output.
-		logger.Context = gb.CPU.Context
-		//logger.Context = func() string { return fmt.Sprintf("%s\n%s\n> ", gb.CPU, gb.PPU) } // TEMPORARY
+		go handleSignals(c)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+		// Add CPU-specific context to debug output. The CPU is replaced when
+		// loading another ROM, so don't keep a reference to it.
+		logger.Context = func() string { return gb.CPU.Context() }
+
+		if args.NoAudio {
+			silentStop = make(chan struct{})
+			silentDone = make(chan struct{})
+			go runSilent(silentStop, silentDone)
+			return
+		}
 
 		// An AudioSpec structure containing our parameters. After calling
 		// OpenAudio, it will also contain some values initialized by SDL itself,
 		// such as the audio buffer size.
-		spec := sdl.AudioSpec{
-			Freq:     apu.SamplingRate,
-			Format:   sdl.AUDIO_U8,
-			Channels: 2,
-			Samples:  apu.FramesPerBuffer,
-			Callback: sdl.AudioCallback(C.mainLoopCallback),
-		}
+		spec := gb.AudioSpec()
+		spec.Callback = sdl.AudioCallback(C.mainLoopCallback)
 
 		// We're asking SDL to honor our parameters exactly, or fail.
 		if err := sdl.OpenAudio(&spec, nil); err != nil {
@@ -178,14 +240,22 @@ func run() {
 		sdl.PauseAudio(false)
 	})
 
-	defer gb.Stop()
+	<-quit // Wait for the callback or signal handler to signal us.
+
+	// Without audio, nothing else stops emulation, so make sure it's over
+	// before saving anything.
+	if silentStop != nil {
+		close(silentStop)
+		<-silentDone
+	}
 
-	<-quit // Wait for the callback to signal us.
+	gb.Shutdown()
 
-	sdl.CloseAudio()
+	exitCode = gb.ExitCode()
 }
 
 func main() {
 	// Run main function in a separate goroutine so sdl can reserve the UI thread.
 	sdl.Main(run)
+	os.Exit(exitCode)
 }
@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/lazy-stripes/goholint/rng"
+)
+
+// Game Boy Camera (POCKET CAMERA) mapper. Source:
+// [PANCAM] https://gbdev.io/pandocs/Gameboy_Camera.html
+
+// Camera sensor output, as stored in RAM bank 0 after a capture.
+const (
+	CameraWidth     = 128
+	CameraHeight    = 112
+	CameraImageAddr = 0xa100 // Tiles for the captured image, line by line
+	CameraRAMBanks  = 16
+)
+
+// CameraPattern returns the shade (0-3, from lightest to darkest) of a pixel
+// in place of what the camera sensor would see.
+type CameraPattern func(x, y int) uint8
+
+// CameraBars is a test pattern made of vertical bars, one for each shade.
+func CameraBars(x, y int) uint8 {
+	return uint8(x * 4 / CameraWidth)
+}
+
+// CameraNoise returns a test pattern of random shades, reproducible for a
+// given seed.
+func CameraNoise(seed int64) CameraPattern {
+	r := rng.New(seed)
+	return func(x, y int) uint8 {
+		return uint8(r.Intn(4))
+	}
+}
+
+// ParseCameraPattern returns the test pattern with the given name: bars or
+// noise, the latter using the given seed.
+func ParseCameraPattern(name string, seed int64) (CameraPattern, error) {
+	switch name {
+	case "", "bars":
+		return CameraBars, nil
+	case "noise":
+		return CameraNoise(seed), nil
+	}
+	return nil, fmt.Errorf("unknown camera pattern %q", name)
+}
+
+// Camera emulates the Game Boy Camera's mapper, with up to 1MB ROM and 128KB
+// RAM. The sensor itself is a stub: captures complete immediately and store
+// a test pattern in RAM instead of an actual picture.
+type Camera struct {
+	*ROM            // Complete ROM (will be addressed according to ROMBank)
+	*RAM            // Battery-backed RAM, 16 banks
+	RAMEnabled bool // 0000-1FFF - RAM Enable (for writes only)
+
+	ROMBank uint8 // 2000-3FFF - ROM Bank Number
+	RAMBank uint8 // 4000-5FFF - RAM Bank Number, or 0x10 for camera registers
+
+	// Registers mapped at A000-A07F in camera mode. Only A000 can be read.
+	Registers [0x80]uint8
+
+	// Pattern is what the sensor "sees" (CameraBars by default).
+	Pattern CameraPattern
+
+	romBanks uint8
+	pin      bankPin // See ForceBank
+}
+
+// NewCamera creates an address space emulating a Game Boy Camera cartridge,
+// restoring RAM from the given save file if any.
+func NewCamera(rom *ROM, romBanks uint8, savePath string) *Camera {
+	// Too large for NewRAM's 16-bit size.
+	ram := &RAM{Bytes: make([]uint8, CameraRAMBanks*0x2000)}
+	if savePath != "" {
+		if err := ram.Load(savePath); err != nil {
+			log.Warning(err.Error())
+		}
+	}
+	return &Camera{
+		ROM:      rom,
+		RAM:      ram,
+		ROMBank:  1,
+		Pattern:  CameraBars,
+		romBanks: romBanks,
+	}
+}
+
+// Save writes the cartridge's RAM to its save file.
+func (c *Camera) Save() error {
+	return c.RAM.Save()
+}
+
+// String returns a human-readable summary of the current banking state.
+func (c *Camera) String() string {
+	return fmt.Sprintf("Camera - ROM bank: %d - RAM bank: %d - RAM enabled: %t - Camera mode: %t%s",
+		c.ROMBank, c.RAMBank&0x0f, c.RAMEnabled, c.cameraMode(), &c.pin)
+}
+
+// ForceBank pins the ROM bank mapped at 4000-7FFF to the given one until
+// ReleaseBank is called. It returns an error, leaving the mapped bank alone, if
+// the bank isn't in the ROM.
+func (c *Camera) ForceBank(bank int) error {
+	return c.pin.force(c.ROM, bank)
+}
+
+// ReleaseBank maps the ROM bank selected by the game at 4000-7FFF again.
+func (c *Camera) ReleaseBank() {
+	c.pin.release(c.ROMBank)
+}
+
+// MappedBank returns the ROM bank mapped at 4000-7FFF, masked to the number of
+// banks in the cartridge, or the forced one if any (see ForceBank).
+func (c *Camera) MappedBank() uint8 {
+	bank := c.ROMBank
+	if c.romBanks > 0 {
+		bank &= c.romBanks - 1
+	}
+	return c.pin.mapped(bank)
+}
+
+// Returns whether camera registers are mapped instead of RAM.
+func (c *Camera) cameraMode() bool {
+	return c.RAMBank&0x10 != 0
+}
+
+// Contains returns true if the requested address is anywhere in ROM or RAM.
+func (c *Camera) Contains(addr uint16) bool {
+	return addr <= 0x7fff || (addr >= 0xa000 && addr <= 0xbfff)
+}
+
+// Read returns the byte at requested address in current ROM or RAM bank, or
+// from camera registers.
+func (c *Camera) Read(addr uint16) uint8 {
+	switch {
+	case addr <= 0x3fff:
+		return c.ROM.Read(addr)
+
+	case addr >= 0x4000 && addr <= 0x7fff:
+		return c.ROM.read(uint(c.MappedBank())*0x4000 + uint(addr-0x4000))
+
+	case addr >= 0xa000 && addr <= 0xbfff:
+		if c.cameraMode() {
+			// [PANCAM] Registers are mirrored every 0x80 bytes, and all but
+			// the first are write-only.
+			if addr&0x7f == 0 {
+				return c.Registers[0]
+			}
+			return 0
+		}
+		return c.RAM.Bytes[c.ramOffset(addr)]
+	}
+	return 0xff
+}
+
+// Write value to RAM or camera registers, enable RAM or select ROM/RAM banks.
+func (c *Camera) Write(addr uint16, value uint8) {
+	switch {
+	// 0000-1FFF - RAM Enable
+	case addr <= 0x1fff:
+		c.RAMEnabled = value&0x0f == 0x0a
+
+	// 2000-3FFF - ROM Bank Number (bank 0 can be mapped there too)
+	case addr >= 0x2000 && addr <= 0x3fff:
+		c.ROMBank = value & 0x3f
+		log.Sub("mbc/write").Debugf("ROMBank=0x%02x", c.ROMBank)
+
+	// 4000-5FFF - RAM Bank Number, or camera registers if bit 4 is set
+	case addr >= 0x4000 && addr <= 0x5fff:
+		c.RAMBank = value & 0x1f
+		log.Sub("mbc/write").Debugf("RAMBank=0x%02x", c.RAMBank)
+
+	case addr >= 0xa000 && addr <= 0xbfff:
+		if c.cameraMode() {
+			c.Registers[addr&0x7f] = value
+			if addr&0x7f == 0 && value&1 != 0 {
+				c.capture()
+			}
+			return
+		}
+		if !c.RAMEnabled {
+			log.Sub("mbc/write").Desperatef("RAM not enabled, write to 0x%04x ignored.",
+				addr)
+			return
+		}
+		c.RAM.Bytes[c.ramOffset(addr)] = value
+	}
+}
+
+// Returns the offset in RAM for the given address in the current bank.
+func (c *Camera) ramOffset(addr uint16) int {
+	return int(c.RAMBank&0x0f)*0x2000 + int(addr-0xa000)
+}
+
+// Stores the test pattern as 2bpp tiles in RAM bank 0, where the camera would
+// store the picture taken. Captures take no time, so the busy bit in A000 is
+// cleared right away.
+func (c *Camera) capture() {
+	tiles := c.RAM.Bytes[CameraImageAddr-0xa000:]
+	for y := 0; y < CameraHeight; y++ {
+		for x := 0; x < CameraWidth; x++ {
+			shade := c.Pattern(x, y) & 3
+			offset := (y/8*CameraWidth/8+x/8)*16 + y%8*2
+			bit := uint8(0x80) >> uint(x%8)
+			if shade&1 != 0 {
+				tiles[offset] |= bit
+			} else {
+				tiles[offset] &^= bit
+			}
+			if shade&2 != 0 {
+				tiles[offset+1] |= bit
+			} else {
+				tiles[offset+1] &^= bit
+			}
+		}
+	}
+	c.Registers[0] &^= 1
+	log.Sub("mbc").Debug("camera capture done")
+}
@@ -1,36 +1,88 @@
 package memory
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/lazy-stripes/goholint/memory/chips"
 )
 
+// Mappers lists the cartridge chips that can be forced with NewForcedCartridge
+// regardless of the ROM header, by name.
+var Mappers = map[string]uint8{
+	"none":   chips.ROMOnly,
+	"mbc1":   chips.MBC1RAMBattery, // RAM and battery don't hurt if unused
+	"camera": chips.PocketCamera,
+	"huc1":   chips.HuC1RAMBattery,
+	"huc3":   chips.HuC3,
+}
+
+// Mappers that exist on real cartridges but aren't emulated yet (there is no
+// address space for them in this package), so forcing them is an error rather
+// than silently falling back to another mapper.
+var unsupportedMappers = []string{"mbc2", "mbc3", "mbc5"}
+
 // NewCartridge instantiates the proper kind of adress space depending on the
 // given ROM's header.
-// TODO: we only handle ROM-only and MBC1 so far.
+// TODO: we only handle ROM-only, MBC1, HuC1 and the Game Boy Camera so far.
 func NewCartridge(romPath, savePath string) (cart Addressable) {
-	log := log.Sub("cartridge") // Override default logger
 	if romPath == "" {
-		log.Warning("No cartridge loaded.")
+		log.Sub("cartridge").Warning("No cartridge loaded.")
 		return nil
 	}
 
 	rom := NewROM(romPath, 0) // XXX: do we actually ever need to specify start > 0?
+	return newCartridge(rom, rom.Read(AddrCartridgeType), savePath, false)
+}
+
+// NewForcedCartridge is the same as NewCartridge but uses the given mapper
+// (see Mappers) instead of the one declared in the ROM header, for bad dumps
+// or homebrew with a wrong header. The RAM size is still read from the header.
+func NewForcedCartridge(romPath, savePath, mapper string) (Addressable, error) {
+	chip, ok := Mappers[mapper]
+	if !ok {
+		for _, name := range unsupportedMappers {
+			if mapper == name {
+				return nil, fmt.Errorf("mapper %s is not supported yet", mapper)
+			}
+		}
+		return nil, fmt.Errorf("unknown mapper %q", mapper)
+	}
+	if romPath == "" {
+		return nil, errors.New("no cartridge to load")
+	}
+
+	log.Sub("cartridge").Infof("Forcing mapper %s", mapper)
+	return newCartridge(NewROM(romPath, 0), chip, savePath, true), nil
+}
+
+// Returns the address space for the given cartridge chip type and ROM. If the
+// mapper was forced, the header's ROM size can't be trusted either.
+func newCartridge(rom *ROM, chip uint8, savePath string, forced bool) (cart Addressable) {
+	log := log.Sub("cartridge") // Override default logger
 
 	// Check what kind of chip is in the ROM, return the proper struct.
-	log.Infof("Cartridge type 0x%02x", rom.Read(0x0147))
-	log.Infof("ROM size type 0x%02x", rom.Read(0x0148))
-	log.Infof("RAM size type 0x%02x", rom.Read(0x0149))
-	romBanks := chips.ROMBanks[rom.Read(0x0148)]
-	ramBanks := chips.RAMBanks[rom.Read(0x0149)]
-	switch chip := rom.Read(0x0147); chip {
+	log.Infof("Cartridge type 0x%02x", chip)
+	log.Infof("ROM size type 0x%02x", rom.Read(AddrROMSize))
+	log.Infof("RAM size type 0x%02x", rom.Read(AddrRAMSize))
+	romBanks := cartridgeROMBanks(rom, forced)
+	ramBanks := cartridgeRAMBanks(chip, rom.Read(AddrRAMSize))
+	switch chip {
 	case chips.ROMOnly:
 		cart = rom
 	case chips.MBC1:
-		cart = NewMBC1(rom, uint8(romBanks), 0, false, "")
+		cart = NewMBC1(rom, romBanks, 0, false, "")
 	case chips.MBC1RAM:
-		cart = NewMBC1(rom, uint8(romBanks), ramBanks, false, "")
+		cart = NewMBC1(rom, romBanks, ramBanks, false, "")
 	case chips.MBC1RAMBattery:
-		cart = NewMBC1(rom, uint8(romBanks), ramBanks, true, savePath)
+		cart = NewMBC1(rom, romBanks, ramBanks, true, savePath)
+	case chips.PocketCamera:
+		cart = NewCamera(rom, romBanks, savePath)
+	case chips.HuC1RAMBattery:
+		cart = NewHuC1(rom, romBanks, ramBanks, true, savePath)
+	case chips.HuC3:
+		log.Warning("HuC3 RTC not emulated, RTC commands are ignored")
+		cart = NewHuC3(rom, romBanks, ramBanks, savePath)
 	default:
 		log.Warningf("Unknown cartridge type 0x%02x", chip)
 		cart = rom
@@ -38,3 +90,36 @@ func NewCartridge(romPath, savePath string) (cart Addressable) {
 
 	return cart
 }
+
+// Returns the number of 16KB ROM banks for a cartridge, as used to mask bank
+// numbers. The header's ROM size is used unless the mapper was forced or it
+// says there is no banking (32KB), in which case banks are counted from the
+// ROM's actual size, so that a forced mapper never selects banks past its end.
+func cartridgeROMBanks(rom *ROM, forced bool) uint8 {
+	banks := chips.ROMBanks[rom.Read(AddrROMSize)]
+	if forced || banks == 0 || banks > 0xff {
+		return rom.banks()
+	}
+	return uint8(banks)
+}
+
+// Returns the number of 8KB RAM banks for a cartridge, given its type and RAM
+// size from the header. Some headers declare RAM (and a battery) with a RAM
+// size of 0, in which case a single bank is used so that saves still work.
+func cartridgeRAMBanks(chip, ramSize uint8) uint8 {
+	log := log.Sub("cartridge")
+	banks, ok := chips.RAMBanks[ramSize]
+	if !ok {
+		log.Warningf("Unknown RAM size type 0x%02x", ramSize)
+	}
+
+	switch chip {
+	case chips.MBC1RAM, chips.MBC1RAMBattery, chips.HuC1RAMBattery, chips.HuC3:
+		if banks == 0 {
+			log.Warningf("Cartridge type 0x%02x has RAM but RAM size type "+
+				"0x%02x says none, using one bank", chip, ramSize)
+			banks = 1
+		}
+	}
+	return banks
+}
@@ -0,0 +1,389 @@
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/memory/chips"
+)
+
+func TestBatteryWithoutRAM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// MBC1+RAM+BATTERY with a RAM size of 0.
+	rom := make([]byte, 0x8000)
+	rom[AddrCartridgeType] = chips.MBC1RAMBattery
+	rom[AddrROMSize] = 0x00
+	rom[AddrRAMSize] = 0x00
+	romPath := filepath.Join(dir, "game.gb")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	savePath := filepath.Join(dir, "game.sav")
+	cart := NewCartridge(romPath, savePath).(*MBC1)
+	if len(cart.RAM.Bytes) != 0x2000 {
+		t.Fatalf("cartridge RAM is %d bytes, want 0x2000", len(cart.RAM.Bytes))
+	}
+
+	cart.Write(0x0000, 0x0a) // Enable RAM
+	cart.Write(0xa123, 0x42)
+	if value := cart.Read(0xa123); value != 0x42 {
+		t.Errorf("RAM read 0x%02x, want 0x42", value)
+	}
+	if saved, err := ioutil.ReadFile(savePath); err != nil || saved[0x123] != 0x42 {
+		t.Errorf("RAM not saved (%v)", err)
+	}
+
+	// Cartridges without RAM at all ignore RAM accesses.
+	mbc := NewMBC1(NewROM(romPath, 0), 2, 0, false, "")
+	mbc.Write(0x0000, 0x0a)
+	mbc.Write(0xa000, 0x42)
+	if value := mbc.Read(0xa000); value != 0xff {
+		t.Errorf("RAM-less cartridge read 0x%02x, want 0xff", value)
+	}
+}
+
+func TestForcedMapper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The header says ROM only, with 4 ROM banks.
+	rom := make([]byte, 0x10000)
+	rom[AddrCartridgeType] = chips.ROMOnly
+	rom[AddrROMSize] = 0x01
+	romPath := filepath.Join(dir, "game.gb")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := NewCartridge(romPath, "").(*ROM); !ok {
+		t.Fatal("header's cartridge type not used by default")
+	}
+
+	cart, err := NewForcedCartridge(romPath, filepath.Join(dir, "game.sav"), "mbc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cart.(*MBC1); !ok {
+		t.Fatalf("forced mapper gave %T, want *MBC1", cart)
+	}
+
+	cart, err = NewForcedCartridge(romPath, "", "none")
+	if _, ok := cart.(*ROM); !ok || err != nil {
+		t.Errorf("forced mapper gave %T (%v), want *ROM", cart, err)
+	}
+
+	cart, err = NewForcedCartridge(romPath, "", "huc3")
+	if _, ok := cart.(*HuC1); !ok || err != nil {
+		t.Errorf("forced mapper gave %T (%v), want *HuC1", cart, err)
+	}
+
+	for _, mapper := range []string{"mbc2", "mbc3", "mbc5", "mbc42"} {
+		if _, err := NewForcedCartridge(romPath, "", mapper); err == nil {
+			t.Errorf("no error forcing mapper %s", mapper)
+		}
+	}
+}
+
+func TestForcedMapperSmallROM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 32KB homebrew ROM without banking. Bank 2 doesn't exist and wraps back
+	// to bank 0 on an actual MBC1.
+	rom := make([]byte, 0x8000)
+	rom[0x0000], rom[0x4000] = 0x10, 0x11
+	rom[AddrCartridgeType] = chips.ROMOnly
+	rom[AddrROMSize] = 0x00
+	romPath := filepath.Join(dir, "homebrew.gb")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := NewForcedCartridge(romPath, "", "mbc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ bank, want uint8 }{{1, 0x11}, {2, 0x10}, {3, 0x11}} {
+		cart.Write(0x2000, c.bank)
+		if value := cart.Read(0x4000); value != c.want {
+			t.Errorf("bank %d read 0x%02x, want 0x%02x", c.bank, value, c.want)
+		}
+	}
+
+	// Reads past the end of the ROM itself never panic.
+	if value := (&ROM{RAM{Bytes: rom}}).read(0x8000); value != 0xff {
+		t.Errorf("read past ROM end returned 0x%02x, want 0xff", value)
+	}
+}
+
+func TestForceBank(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 8 ROM banks, each starting with its own number.
+	rom := make([]byte, 8*0x4000)
+	for bank := 0; bank < 8; bank++ {
+		rom[bank*0x4000] = byte(bank)
+	}
+	rom[AddrCartridgeType] = chips.MBC1
+	rom[AddrROMSize] = 0x02
+	romPath := filepath.Join(dir, "game.gb")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cart := NewCartridge(romPath, "").(*MBC1)
+	var forcer BankForcer = cart
+	cart.Write(0x2000, 2)
+	if err := forcer.ForceBank(5); err != nil {
+		t.Fatal(err)
+	}
+	if bank := cart.Read(0x4000); bank != 5 {
+		t.Fatalf("read from bank %d, want forced bank 5", bank)
+	}
+
+	// Bank switches are ignored while forced, but still take effect once the
+	// bank is released.
+	cart.Write(0x2000, 3)
+	if bank := cart.Read(0x4000); bank != 5 {
+		t.Errorf("read from bank %d after bank switch, want forced bank 5", bank)
+	}
+	forcer.ReleaseBank()
+	if bank := cart.Read(0x4000); bank != 3 {
+		t.Errorf("read from bank %d after release, want 3", bank)
+	}
+
+	// Banks outside the ROM are refused instead of being truncated.
+	for _, bank := range []int{-1, 8, 258} {
+		if err := forcer.ForceBank(bank); err == nil {
+			t.Errorf("no error forcing ROM bank %d", bank)
+		}
+	}
+	if bank := cart.Read(0x4000); bank != 3 {
+		t.Errorf("read from bank %d after invalid forced banks, want 3", bank)
+	}
+
+	// 32KB ROMs have banks 0 and 1 only.
+	small := NewMBC1(&ROM{RAM{Bytes: rom[:0x8000]}}, 0, 0, false, "")
+	if err := small.ForceBank(2); err == nil {
+		t.Error("no error forcing ROM bank 2 on a 32KB ROM")
+	}
+	if err := small.ForceBank(0); err != nil || small.Read(0x4000) != 0 {
+		t.Errorf("forcing ROM bank 0 on a 32KB ROM failed (%v)", err)
+	}
+	// Other banked mappers can be pinned the same way.
+	for _, forcer := range []BankForcer{
+		NewHuC1(&ROM{RAM{Bytes: rom}}, 8, 0, false, ""),
+		NewCamera(&ROM{RAM{Bytes: rom}}, 8, ""),
+	} {
+		cart := forcer.(Addressable)
+		cart.Write(0x2000, 2)
+		if err := forcer.ForceBank(5); err != nil {
+			t.Fatal(err)
+		}
+		cart.Write(0x2000, 3)
+		if bank := cart.Read(0x4000); bank != 5 || forcer.MappedBank() != 5 {
+			t.Errorf("%T: read from bank %d, want forced bank 5", cart, bank)
+		}
+		forcer.ReleaseBank()
+		if bank := cart.Read(0x4000); bank != 3 || forcer.MappedBank() != 3 {
+			t.Errorf("%T: read from bank %d after release, want 3", cart, bank)
+		}
+		if err := forcer.ForceBank(8); err == nil {
+			t.Errorf("%T: no error forcing ROM bank 8", cart)
+		}
+	}
+}
+
+func TestCamera(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 4 ROM banks, each starting with its own number.
+	rom := make([]byte, 4*0x4000)
+	for bank := 0; bank < 4; bank++ {
+		rom[bank*0x4000] = byte(bank)
+	}
+	rom[AddrCartridgeType] = chips.PocketCamera
+	rom[AddrROMSize] = 0x01
+	rom[AddrRAMSize] = 0x04
+	romPath := filepath.Join(dir, "camera.gb")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, ok := NewCartridge(romPath, "").(*Camera)
+	if !ok {
+		t.Fatal("POCKET CAMERA cartridge type not using the camera mapper")
+	}
+
+	// ROM and RAM banking.
+	cart.Write(0x2000, 3)
+	if bank := cart.Read(0x4000); bank != 3 {
+		t.Errorf("read from ROM bank %d, want 3", bank)
+	}
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0x4000, 15)
+	cart.Write(0xa000, 0x42)
+	cart.Write(0x4000, 0)
+	if value := cart.Read(0xa000); value != 0 {
+		t.Errorf("RAM bank 0 read 0x%02x, want 0", value)
+	}
+	cart.Write(0x4000, 15)
+	if value := cart.Read(0xa000); value != 0x42 {
+		t.Errorf("RAM bank 15 read 0x%02x, want 0x42", value)
+	}
+
+	// Captures complete right away with the test pattern: a dark pixel in the
+	// top left corner of an otherwise light image.
+	cart.Pattern = func(x, y int) uint8 {
+		if x == 0 && y == 0 {
+			return 3
+		}
+		return 0
+	}
+	cart.Write(0x4000, 0x10)
+	cart.Write(0xa000, 0x01)
+	if value := cart.Read(0xa000); value&1 != 0 {
+		t.Error("capture still in progress")
+	}
+	if value := cart.Read(0xa080); value != cart.Read(0xa000) {
+		t.Error("camera registers not mirrored")
+	}
+	if value := cart.Read(0xa001); value != 0 {
+		t.Errorf("write-only camera register read 0x%02x, want 0", value)
+	}
+
+	cart.Write(0x4000, 0)
+	tile := []uint8{
+		cart.Read(CameraImageAddr), cart.Read(CameraImageAddr + 1),
+		cart.Read(CameraImageAddr + 2), cart.Read(CameraImageAddr + 3),
+	}
+	if tile[0] != 0x80 || tile[1] != 0x80 || tile[2] != 0 || tile[3] != 0 {
+		t.Errorf("captured tile starts with % x, want 80 80 00 00", tile)
+	}
+
+	if _, err := ParseCameraPattern("nope", 0); err == nil {
+		t.Error("no error for an unknown camera pattern")
+	}
+}
+
+func TestHuC1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 8 ROM banks, each starting with its own number, and 4 RAM banks.
+	rom := make([]byte, 8*0x4000)
+	for bank := 0; bank < 8; bank++ {
+		rom[bank*0x4000] = byte(bank)
+	}
+	rom[AddrCartridgeType] = chips.HuC1RAMBattery
+	rom[AddrROMSize] = 0x02
+	rom[AddrRAMSize] = 0x03
+	romPath := filepath.Join(dir, "huc1.gb")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, ok := NewCartridge(romPath, filepath.Join(dir, "huc1.sav")).(*HuC1)
+	if !ok {
+		t.Fatal("HuC1+RAM+BATTERY cartridge type not using the HuC1 mapper")
+	}
+
+	// ROM banking, masked to the cartridge's size, with bank 0 selecting 1.
+	for _, c := range []struct{ value, want uint8 }{{5, 5}, {0, 1}, {0x0b, 3}} {
+		cart.Write(0x2000, c.value)
+		if bank := cart.Read(0x4000); bank != c.want {
+			t.Errorf("selecting ROM bank 0x%02x read from bank %d, want %d",
+				c.value, bank, c.want)
+		}
+		if bank := cart.ROMBank(); bank != c.want {
+			t.Errorf("selecting ROM bank 0x%02x reports bank %d, want %d",
+				c.value, bank, c.want)
+		}
+	}
+	if bank := cart.Read(0x0000); bank != 0 {
+		t.Errorf("read from ROM bank %d at 0000, want 0", bank)
+	}
+
+	// RAM banking, without having to enable RAM first.
+	for bank := uint8(0); bank < HuC1RAMBanks; bank++ {
+		cart.Write(0x4000, bank)
+		cart.Write(0xa000, 0x40+bank)
+	}
+	for bank := uint8(0); bank < HuC1RAMBanks; bank++ {
+		cart.Write(0x4000, bank)
+		if value := cart.Read(0xa000); value != 0x40+bank {
+			t.Errorf("RAM bank %d read 0x%02x, want 0x%02x", bank, value, 0x40+bank)
+		}
+	}
+
+	// The IR port shadows RAM and never receives light.
+	cart.Write(0x0000, HuC1IRMode)
+	if value := cart.Read(0xa000); value != HuC1IRIdle {
+		t.Errorf("IR port read 0x%02x, want 0x%02x", value, HuC1IRIdle)
+	}
+	cart.Write(0xa000, HuC1IRLight)
+	if !cart.IRLED {
+		t.Error("IR LED not turned on")
+	}
+	cart.Write(0x0000, 0x0a)
+	if value := cart.Read(0xa000); value != 0x43 {
+		t.Errorf("RAM read 0x%02x after leaving IR mode, want 0x43", value)
+	}
+
+	// Banks past the end of a ROM without banking wrap around.
+	small := NewHuC1(&ROM{RAM{Bytes: rom[:0x8000]}}, 0, 0, false, "")
+	small.Write(0x2000, 2)
+	if bank := small.Read(0x4000); bank != 0 {
+		t.Errorf("32KB ROM read from bank %d after selecting bank 2, want 0", bank)
+	}
+
+	// HuC3 cartridges boot with the same banking, but RAM is only mapped in
+	// RAM mode so that RTC commands don't corrupt saves.
+	rom[AddrCartridgeType] = chips.HuC3
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	huc3, ok := NewCartridge(romPath, filepath.Join(dir, "huc3.sav")).(*HuC1)
+	if !ok {
+		t.Fatal("HuC3 cartridge type not using the HuC1 mapper")
+	}
+	huc3.Write(0x0000, HuC3RAMMode)
+	huc3.Write(0xa000, 0x42)
+	for _, mode := range []uint8{0x00, 0x0b, 0x0c, 0x0d} {
+		huc3.Write(0x0000, mode)
+		huc3.Write(0xa000, 0x10)
+		if value := huc3.Read(0xa000); value != UnmappedValue {
+			t.Errorf("HuC3 mode 0x%02x read 0x%02x, want 0x%02x", mode, value,
+				UnmappedValue)
+		}
+	}
+	huc3.Write(0x0000, HuC3RAMMode)
+	if value := huc3.Read(0xa000); value != 0x42 {
+		t.Errorf("HuC3 RAM read 0x%02x after RTC commands, want 0x42", value)
+	}
+}
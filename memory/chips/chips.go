@@ -10,6 +10,9 @@ const (
 	MBC2Battery    = 0x06
 	ROMRAM         = 0x08
 	ROMRAMBattery  = 0x09
+	PocketCamera   = 0xfc
+	HuC3           = 0xfe
+	HuC1RAMBattery = 0xff
 	// TODO: all others that are not strictly used with CGB.
 )
 
@@ -38,3 +41,35 @@ var RAMBanks = map[uint8]uint8{
 	0x04: 16,
 	0x05: 8,
 }
+
+// Names of cartridge chip types, as listed in the header documentation.
+var Names = map[uint8]string{
+	ROMOnly:        "ROM ONLY",
+	MBC1:           "MBC1",
+	MBC1RAM:        "MBC1+RAM",
+	MBC1RAMBattery: "MBC1+RAM+BATTERY",
+	MBC2:           "MBC2",
+	MBC2Battery:    "MBC2+BATTERY",
+	ROMRAM:         "ROM+RAM",
+	ROMRAMBattery:  "ROM+RAM+BATTERY",
+	0x0b:           "MMM01",
+	0x0c:           "MMM01+RAM",
+	0x0d:           "MMM01+RAM+BATTERY",
+	0x0f:           "MBC3+TIMER+BATTERY",
+	0x10:           "MBC3+TIMER+RAM+BATTERY",
+	0x11:           "MBC3",
+	0x12:           "MBC3+RAM",
+	0x13:           "MBC3+RAM+BATTERY",
+	0x19:           "MBC5",
+	0x1a:           "MBC5+RAM",
+	0x1b:           "MBC5+RAM+BATTERY",
+	0x1c:           "MBC5+RUMBLE",
+	0x1d:           "MBC5+RUMBLE+RAM",
+	0x1e:           "MBC5+RUMBLE+RAM+BATTERY",
+	0x20:           "MBC6",
+	0x22:           "MBC7+SENSOR+RUMBLE+RAM+BATTERY",
+	PocketCamera:   "POCKET CAMERA",
+	0xfd:           "BANDAI TAMA5",
+	HuC3:           "HuC3",
+	HuC1RAMBattery: "HuC1+RAM+BATTERY",
+}
@@ -46,6 +46,11 @@ func (d *DMA) Write(addr uint16, value uint8) {
 	log.Sub("dma").Debugf("Start DMA transfer 0x%04x→0xfe00", d.src)
 }
 
+// Active returns whether a transfer is in progress.
+func (d *DMA) Active() bool {
+	return d.isActive
+}
+
 // Tick advances DMA transfer one step if it's active. Called every clock tick.
 func (d *DMA) Tick() {
 	if !d.isActive {
@@ -65,3 +70,36 @@ func (d *DMA) Tick() {
 	log.Sub("dma").Debug("DMA transfer done")
 	d.isActive = false
 }
+
+// DMAGuard wraps the address space seen by the CPU to restrict it while an
+// OAM DMA transfer is in progress, as on hardware: reads below 0xff00 return
+// 0xff and writes there are ignored, leaving the CPU with I/O registers and
+// HRAM, which aren't on the buses used by DMA. Some games get this wrong and
+// only work without it, so it's optional (see -dmastrict).
+type DMAGuard struct {
+	Addressable
+	DMA *DMA
+}
+
+// Returns whether the CPU can't access the given address right now.
+func (g *DMAGuard) blocked(addr uint16) bool {
+	return addr < 0xff00 && g.DMA.Active()
+}
+
+// Read returns the value at the given address, or 0xff if it's blocked.
+func (g *DMAGuard) Read(addr uint16) uint8 {
+	if g.blocked(addr) {
+		log.Sub("dma").Debugf("blocked read at 0x%04x during DMA", addr)
+		return 0xff
+	}
+	return g.Addressable.Read(addr)
+}
+
+// Write sets the value at the given address unless it's blocked.
+func (g *DMAGuard) Write(addr uint16, value uint8) {
+	if g.blocked(addr) {
+		log.Sub("dma").Debugf("blocked write at 0x%04x during DMA", addr)
+		return
+	}
+	g.Addressable.Write(addr, value)
+}
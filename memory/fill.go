@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/lazy-stripes/goholint/rng"
+)
+
+// FillPattern decides the contents of RAM at power-up. Real hardware starts
+// with a semi-random pattern, and games that wrongly rely on it may behave
+// differently from one emulator to the next.
+type FillPattern uint8
+
+// Supported fill patterns.
+const (
+	FillDefault FillPattern = iota // Random VRAM, everything else zeroed
+	FillZero                       // All bytes set to 0x00
+	FillOnes                       // All bytes set to 0xff
+	FillRandom                     // Pseudo-random bytes from a seed
+)
+
+// ParseFill returns the fill pattern with the given name: default, zero, ff
+// or random.
+func ParseFill(name string) (FillPattern, error) {
+	switch name {
+	case "", "default":
+		return FillDefault, nil
+	case "zero":
+		return FillZero, nil
+	case "ff":
+		return FillOnes, nil
+	case "random":
+		return FillRandom, nil
+	}
+	return FillDefault, fmt.Errorf("unknown RAM fill pattern %q", name)
+}
+
+// Fill decides the contents of RAM regions at power-up. Random contents are
+// reproducible for a given seed, as long as regions are created from the same
+// Fill in the same order. A nil Fill uses the default pattern with seed 0.
+type Fill struct {
+	Pattern FillPattern
+	rand    *rand.Rand
+}
+
+// NewFill returns a Fill using the given pattern and seed for random contents.
+func NewFill(pattern FillPattern, seed int64) *Fill {
+	return &Fill{Pattern: pattern, rand: rng.New(seed)}
+}
+
+// Fills the given bytes according to the pattern. The default pattern leaves
+// them alone, unless told to randomize them.
+func (f *Fill) fill(bytes []uint8, random bool) {
+	if f == nil {
+		f = NewFill(FillDefault, 0)
+	}
+
+	switch f.Pattern {
+	case FillZero:
+		for i := range bytes {
+			bytes[i] = 0
+		}
+	case FillOnes:
+		for i := range bytes {
+			bytes[i] = 0xff
+		}
+	case FillRandom:
+		f.rand.Read(bytes)
+	default:
+		if random {
+			for i := range bytes {
+				bytes[i] = uint8(f.rand.Intn(0xff))
+			}
+		}
+	}
+}
+
+// NewFilledRAM instantiates RAM whose contents at power-up follow the given
+// fill pattern, zeroed by default.
+func NewFilledRAM(start, size uint16, fill *Fill) *RAM {
+	ram := NewRAM(start, size)
+	fill.fill(ram.Bytes, false)
+	return ram
+}
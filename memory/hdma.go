@@ -0,0 +1,120 @@
+package memory
+
+// CGB VRAM DMA implementation. Source:
+// [PANCGB] https://gbdev.io/pandocs/CGB_Registers.html#lcd-vram-dma-transfers
+
+// HDMA register addresses.
+const (
+	AddrHDMA1 = 0xff51 // Source, high byte
+	AddrHDMA2 = 0xff52 // Source, low byte (lower 4 bits ignored)
+	AddrHDMA3 = 0xff53 // Destination, high byte (only bits 0-4 used)
+	AddrHDMA4 = 0xff54 // Destination, low byte (lower 4 bits ignored)
+	AddrHDMA5 = 0xff55 // Length, mode and start
+)
+
+// HDMA5 bit 7: HBlank transfer when writing, transfer inactive when reading.
+const HDMA5HBlank uint8 = 1 << 7
+
+// Size of the blocks transferred at once, and per HBlank.
+const hdmaBlockSize = 0x10
+
+// HDMA address space for CGB transfers from ROM or RAM to VRAM, either all at
+// once (general purpose) or one 16-byte block per HBlank. Transfers happen
+// instantly, without stopping the CPU for their actual duration.
+type HDMA struct {
+	MMU Addressable
+
+	src, dest uint16
+	length    uint8 // Blocks left to transfer, minus 1 (HDMA5 bits 0-6)
+	active    bool  // HBlank transfer in progress
+	done      bool  // Last transfer completed (HDMA5 reads 0xff)
+}
+
+// NewHDMA returns an HDMA instance transferring data within the given address
+// space, which must span source and destination areas.
+func NewHDMA(mmu Addressable) *HDMA {
+	return &HDMA{MMU: mmu, dest: 0x8000, done: true}
+}
+
+// Contains returns true for HDMA registers.
+func (h *HDMA) Contains(addr uint16) bool {
+	return addr >= AddrHDMA1 && addr <= AddrHDMA5
+}
+
+// Read returns the transfer's status in HDMA5: remaining length with bit 7
+// reset while active, or set if cancelled, or 0xff when complete. Other
+// registers are write-only.
+func (h *HDMA) Read(addr uint16) uint8 {
+	if addr != AddrHDMA5 {
+		return 0xff
+	}
+	switch {
+	case h.done:
+		return 0xff
+	case h.active:
+		return h.length
+	}
+	return HDMA5HBlank | h.length
+}
+
+// Write sets source and destination addresses, or starts a transfer when
+// writing to HDMA5. Writing to HDMA5 with bit 7 reset during an HBlank
+// transfer cancels it instead.
+func (h *HDMA) Write(addr uint16, value uint8) {
+	switch addr {
+	case AddrHDMA1:
+		h.src = uint16(value)<<8 | h.src&0x00ff
+	case AddrHDMA2:
+		h.src = h.src&0xff00 | uint16(value&0xf0)
+	case AddrHDMA3:
+		h.dest = 0x8000 | uint16(value&0x1f)<<8 | h.dest&0x00ff
+	case AddrHDMA4:
+		h.dest = h.dest&0xff00 | uint16(value&0xf0)
+	case AddrHDMA5:
+		if h.active && value&HDMA5HBlank == 0 {
+			log.Sub("dma").Debugf("HDMA transfer cancelled, 0x%02x blocks left", h.length+1)
+			h.active = false
+			return
+		}
+		h.length = value &^ HDMA5HBlank
+		h.done = false
+		if value&HDMA5HBlank != 0 {
+			log.Sub("dma").Debugf("Start HBlank DMA 0x%04x→0x%04x (0x%x bytes)",
+				h.src, h.dest, (int(h.length)+1)*hdmaBlockSize)
+			h.active = true
+			return
+		}
+
+		log.Sub("dma").Debugf("General DMA 0x%04x→0x%04x (0x%x bytes)",
+			h.src, h.dest, (int(h.length)+1)*hdmaBlockSize)
+		for !h.done {
+			h.block()
+		}
+	}
+}
+
+// HBlank transfers the next block of an HBlank transfer in progress, if any.
+// Called by the PPU at the start of each HBlank.
+func (h *HDMA) HBlank() {
+	if h.active {
+		h.block()
+	}
+}
+
+// Copies one block and updates addresses and length accordingly.
+func (h *HDMA) block() {
+	for i := 0; i < hdmaBlockSize; i++ {
+		h.MMU.Write(h.dest, h.MMU.Read(h.src))
+		h.src++
+		// Destination wraps around within VRAM.
+		h.dest = 0x8000 | (h.dest+1)&0x1fff
+	}
+
+	if h.length == 0 {
+		h.active = false
+		h.done = true
+		h.length = 0x7f
+		return
+	}
+	h.length--
+}
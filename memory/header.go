@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lazy-stripes/goholint/memory/chips"
+)
+
+// Cartridge header. Source:
+// [PANHDR] https://gbdev.io/pandocs/The_Cartridge_Header.html
+
+// Cartridge header addresses.
+const (
+	AddrTitle          = 0x0134
+	AddrCGBFlag        = 0x0143
+	AddrNewLicensee    = 0x0144
+	AddrSGBFlag        = 0x0146
+	AddrCartridgeType  = 0x0147
+	AddrROMSize        = 0x0148
+	AddrRAMSize        = 0x0149
+	AddrDestination    = 0x014a
+	AddrOldLicensee    = 0x014b
+	AddrVersion        = 0x014c
+	AddrHeaderChecksum = 0x014d
+	AddrGlobalChecksum = 0x014e
+)
+
+// CGB flag values at 0x0143.
+const (
+	CGBEnhanced = 0x80 // Game supports CGB functions but works on DMG too
+	CGBOnly     = 0xc0 // Game only works on CGB
+)
+
+// SGBSupport is the value at 0x0146 for games supporting SGB functions.
+const SGBSupport = 0x03
+
+// Header holds the interesting bits of a cartridge header.
+type Header struct {
+	Title          string
+	CGBFlag        uint8
+	NewLicensee    string
+	SGBFlag        uint8
+	CartridgeType  uint8
+	ROMSize        uint8
+	RAMSize        uint8
+	Destination    uint8
+	OldLicensee    uint8
+	Version        uint8
+	HeaderChecksum uint8
+	GlobalChecksum uint16
+
+	computedChecksum uint8
+}
+
+// ReadHeader parses the cartridge header from the given address space, which
+// should map the cartridge's first ROM bank.
+func ReadHeader(mem Addressable) *Header {
+	h := Header{
+		CGBFlag:        mem.Read(AddrCGBFlag),
+		NewLicensee:    string([]byte{mem.Read(AddrNewLicensee), mem.Read(AddrNewLicensee + 1)}),
+		SGBFlag:        mem.Read(AddrSGBFlag),
+		CartridgeType:  mem.Read(AddrCartridgeType),
+		ROMSize:        mem.Read(AddrROMSize),
+		RAMSize:        mem.Read(AddrRAMSize),
+		Destination:    mem.Read(AddrDestination),
+		OldLicensee:    mem.Read(AddrOldLicensee),
+		Version:        mem.Read(AddrVersion),
+		HeaderChecksum: mem.Read(AddrHeaderChecksum),
+		GlobalChecksum: uint16(mem.Read(AddrGlobalChecksum))<<8 | uint16(mem.Read(AddrGlobalChecksum+1)),
+	}
+
+	// Newer cartridges use the last title bytes for the CGB flag (and
+	// manufacturer code, which we ignore).
+	titleEnd := uint16(AddrCGBFlag)
+	if h.CGBFlag&CGBEnhanced != 0 {
+		titleEnd = AddrCGBFlag - 4
+	}
+	var title []byte
+	for addr := uint16(AddrTitle); addr < titleEnd; addr++ {
+		title = append(title, mem.Read(addr))
+	}
+	if end := bytes.IndexByte(title, 0); end >= 0 {
+		title = title[:end]
+	}
+	h.Title = strings.TrimSpace(string(title))
+
+	// [PANHDR] x=0:FOR i=0134h TO 014Ch:x=x-MEM[i]-1:NEXT
+	for addr := uint16(AddrTitle); addr <= AddrVersion; addr++ {
+		h.computedChecksum = h.computedChecksum - mem.Read(addr) - 1
+	}
+
+	return &h
+}
+
+// LoadHeader reads the header of the given ROM file (see ReadROMFile for
+// supported formats) without setting up a cartridge.
+func LoadHeader(romPath string) (*Header, error) {
+	data, err := ReadROMFile(romPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= AddrGlobalChecksum+1 {
+		return nil, fmt.Errorf("%s is too small to hold a cartridge header (%d bytes)",
+			romPath, len(data))
+	}
+	return ReadHeader(&ROM{RAM{Bytes: data}}), nil
+}
+
+// CGB returns true if the cartridge supports CGB functions.
+func (h *Header) CGB() bool {
+	return h.CGBFlag&CGBEnhanced != 0
+}
+
+// ValidChecksum returns true if the header checksum matches the header data.
+// The boot ROM would refuse to run the cartridge otherwise.
+func (h *Header) ValidChecksum() bool {
+	return h.computedChecksum == h.HeaderChecksum
+}
+
+// String returns a human-readable summary of the header.
+func (h *Header) String() string {
+	return fmt.Sprintf("%q (type 0x%02x, CGB flag 0x%02x)", h.Title,
+		h.CartridgeType, h.CGBFlag)
+}
+
+// Info returns a detailed description of the header, one field per line, for
+// triaging ROM dumps.
+func (h *Header) Info() string {
+	var b strings.Builder
+
+	chip, ok := chips.Names[h.CartridgeType]
+	if !ok {
+		chip = "unknown"
+	}
+
+	romSize := "unknown"
+	if banks, ok := chips.ROMBanks[h.ROMSize]; ok {
+		if banks == 0 {
+			banks = 2 // No banking, 32KB
+		}
+		romSize = fmt.Sprintf("%dKB", int(banks)*16)
+	}
+
+	ramSize := "unknown"
+	switch banks, ok := chips.RAMBanks[h.RAMSize]; {
+	case h.RAMSize == 0x01:
+		ramSize = "2KB"
+	case ok:
+		ramSize = fmt.Sprintf("%dKB", int(banks)*8)
+	}
+
+	licensee := fmt.Sprintf("0x%02x", h.OldLicensee)
+	if h.OldLicensee == 0x33 {
+		licensee = fmt.Sprintf("%q (new code)", h.NewLicensee)
+	}
+
+	cgb := "no"
+	switch {
+	case h.CGBFlag&CGBOnly == CGBOnly:
+		cgb = "CGB only"
+	case h.CGB():
+		cgb = "CGB enhanced"
+	}
+
+	checksum := "valid"
+	if !h.ValidChecksum() {
+		checksum = fmt.Sprintf("INVALID (expected 0x%02x)", h.computedChecksum)
+	}
+
+	fmt.Fprintf(&b, "Title:           %s\n", h.Title)
+	fmt.Fprintf(&b, "Cartridge type:  0x%02x (%s)\n", h.CartridgeType, chip)
+	fmt.Fprintf(&b, "ROM size:        0x%02x (%s)\n", h.ROMSize, romSize)
+	fmt.Fprintf(&b, "RAM size:        0x%02x (%s)\n", h.RAMSize, ramSize)
+	fmt.Fprintf(&b, "CGB flag:        0x%02x (%s)\n", h.CGBFlag, cgb)
+	fmt.Fprintf(&b, "SGB flag:        0x%02x (%t)\n", h.SGBFlag, h.SGBFlag == SGBSupport)
+	fmt.Fprintf(&b, "Licensee:        %s\n", licensee)
+	fmt.Fprintf(&b, "Version:         %d\n", h.Version)
+	fmt.Fprintf(&b, "Header checksum: 0x%02x (%s)\n", h.HeaderChecksum, checksum)
+	fmt.Fprintf(&b, "Global checksum: 0x%04x\n", h.GlobalChecksum)
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/memory/chips"
+)
+
+func TestHeaderInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rom := make([]byte, 0x8000)
+	copy(rom[AddrTitle:], "POKEMON RED")
+	rom[AddrNewLicensee], rom[AddrNewLicensee+1] = '0', '1'
+	rom[AddrSGBFlag] = SGBSupport
+	rom[AddrCartridgeType] = chips.MBC1RAMBattery
+	rom[AddrROMSize] = 0x05
+	rom[AddrRAMSize] = 0x03
+	rom[AddrOldLicensee] = 0x33
+	rom[AddrVersion] = 1
+	rom[AddrGlobalChecksum], rom[AddrGlobalChecksum+1] = 0x91, 0xe6
+
+	// Valid checksum.
+	var checksum uint8
+	for addr := AddrTitle; addr <= AddrVersion; addr++ {
+		checksum = checksum - rom[addr] - 1
+	}
+	rom[AddrHeaderChecksum] = checksum
+
+	path := filepath.Join(dir, "red.gb")
+	if err := ioutil.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	header, err := LoadHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := header.Info()
+	for _, want := range []string{
+		"Title:           POKEMON RED\n",
+		"Cartridge type:  0x03 (MBC1+RAM+BATTERY)\n",
+		"ROM size:        0x05 (1024KB)\n",
+		"RAM size:        0x03 (32KB)\n",
+		"CGB flag:        0x00 (no)\n",
+		"SGB flag:        0x03 (true)\n",
+		"Licensee:        \"01\" (new code)\n",
+		"Version:         1\n",
+		"(valid)\n",
+		"Global checksum: 0x91e6\n",
+	} {
+		if !strings.Contains(info, want) {
+			t.Errorf("header info is missing %q:\n%s", want, info)
+		}
+	}
+
+	// Checksum mismatch.
+	rom[AddrHeaderChecksum]++
+	ioutil.WriteFile(path, rom, 0644)
+	if header, err = LoadHeader(path); err != nil || !strings.Contains(header.Info(), "INVALID") {
+		t.Errorf("invalid checksum not reported (%v)", err)
+	}
+
+	// Files too small for a header.
+	ioutil.WriteFile(path, rom[:0x100], 0644)
+	if _, err := LoadHeader(path); err == nil {
+		t.Error("no error for a ROM without a header")
+	}
+}
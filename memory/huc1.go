@@ -0,0 +1,201 @@
+package memory
+
+import "fmt"
+
+// Hudson HuC1 mapper. Source:
+// [PANHUC] https://gbdev.io/pandocs/HuC1.html
+// HuC3 register modes: https://gbdev.io/pandocs/HuC3.html
+
+// HuC1 register values.
+const (
+	HuC1IRMode   = 0x0e // Written to 0000-1FFF to map the IR port at A000-BFFF
+	HuC1IRLight  = 0x01 // IR port bit set when receiving light (or LED on)
+	HuC1IRIdle   = 0xc0 // IR port value when no light is received
+	HuC1RAMBanks = 4
+	HuC3RAMMode  = 0x0a // Written to 0000-1FFF on HuC3 to map RAM at A000-BFFF
+)
+
+// HuC1 emulates Hudson's HuC1 mapper, with up to 1MB ROM and 32KB
+// battery-backed RAM. It works much like MBC1, except the RAM enable register
+// switches A000-BFFF between RAM and the infrared port. The IR port is a stub:
+// the LED can be turned on but no light is ever received.
+type HuC1 struct {
+	*ROM             // Complete ROM (will be addressed according to ROMBank)
+	*RAM             // Battery-backed RAM, up to 4 banks
+	IRMode     bool  // 0000-1FFF - IR port mapped instead of RAM
+	IRLED      bool  // Whether the game turned the IR LED on
+	BankNumber uint8 // 2000-3FFF - ROM Bank Number (see ROMBank)
+	RAMBank    uint8 // 4000-5FFF - RAM Bank Number
+
+	mode     uint8 // Last value written to 0000-1FFF
+	huc3     bool  // RAM only mapped in HuC3RAMMode (see NewHuC3)
+	battery  bool
+	romBanks uint8
+	ramBanks uint8
+	pin      bankPin // See ForceBank
+}
+
+// NewHuC1 creates an address space emulating a cartridge with a HuC1 chip,
+// restoring RAM from the given save file if it's battery-backed.
+func NewHuC1(rom *ROM, romBanks, ramBanks uint8, battery bool, savePath string) *HuC1 {
+	ram := NewRAM(0, uint16(ramBanks)*0x2000)
+	if battery {
+		if err := ram.Load(savePath); err != nil {
+			log.Warning(err.Error())
+		}
+	}
+	// Banks are masked with romBanks-1, which needs the actual ROM size.
+	if romBanks == 0 {
+		romBanks = rom.banks()
+	}
+	return &HuC1{
+		ROM:        rom,
+		RAM:        ram,
+		BankNumber: 1,
+		battery:    battery,
+		romBanks:   romBanks,
+		ramBanks:   ramBanks,
+	}
+}
+
+// NewHuC3 creates an address space emulating a cartridge with a HuC3 chip, as
+// far as ROM and RAM banking go. The RTC isn't emulated: register modes other
+// than RAM (0x0a) and IR (0x0e) map nothing at A000-BFFF, so that RTC commands
+// don't end up in battery-backed RAM.
+func NewHuC3(rom *ROM, romBanks, ramBanks uint8, savePath string) *HuC1 {
+	h := NewHuC1(rom, romBanks, ramBanks, true, savePath)
+	h.huc3 = true
+	return h
+}
+
+// Save writes the cartridge's RAM to its save file if it's battery-backed.
+func (h *HuC1) Save() error {
+	if !h.battery {
+		return nil
+	}
+	return h.RAM.Save()
+}
+
+// String returns a human-readable summary of the current banking state.
+func (h *HuC1) String() string {
+	chip := "HuC1"
+	if h.huc3 {
+		chip = "HuC3"
+	}
+	return fmt.Sprintf("%s - ROM bank: %d - RAM bank: %d - IR mode: %t - IR LED: %t%s",
+		chip, h.ROMBank(), h.ramBank(), h.IRMode, h.IRLED, &h.pin)
+}
+
+// ROMBank returns the ROM bank mapped at 4000-7FFF, masked to the number of
+// banks in the cartridge.
+func (h *HuC1) ROMBank() uint8 {
+	return h.BankNumber & (h.romBanks - 1)
+}
+
+// ForceBank pins the ROM bank mapped at 4000-7FFF to the given one until
+// ReleaseBank is called. It returns an error, leaving the mapped bank alone, if
+// the bank isn't in the ROM.
+func (h *HuC1) ForceBank(bank int) error {
+	return h.pin.force(h.ROM, bank)
+}
+
+// ReleaseBank maps the ROM bank selected by the game at 4000-7FFF again.
+func (h *HuC1) ReleaseBank() {
+	h.pin.release(h.ROMBank())
+}
+
+// MappedBank returns the ROM bank mapped at 4000-7FFF, which is the forced one
+// if any (see ForceBank).
+func (h *HuC1) MappedBank() uint8 {
+	return h.pin.mapped(h.ROMBank())
+}
+
+// Returns the RAM bank mapped at A000-BFFF, masked to the number of banks in
+// the cartridge.
+func (h *HuC1) ramBank() uint8 {
+	if h.ramBanks == 0 {
+		return 0
+	}
+	return h.RAMBank % h.ramBanks
+}
+
+// Returns whether RAM is mapped at A000-BFFF. HuC1 maps it unless in IR mode,
+// HuC3 only in RAM mode.
+func (h *HuC1) ramMapped() bool {
+	if h.huc3 {
+		return h.mode == HuC3RAMMode
+	}
+	return !h.IRMode
+}
+
+// Contains returns true if the requested address is anywhere in ROM or RAM.
+func (h *HuC1) Contains(addr uint16) bool {
+	return addr <= 0x7fff || (addr >= 0xa000 && addr <= 0xbfff)
+}
+
+// Read returns the byte at requested address in current ROM or RAM bank, or
+// the IR port's value.
+func (h *HuC1) Read(addr uint16) uint8 {
+	switch {
+	case addr <= 0x3fff:
+		return h.ROM.Read(addr)
+
+	case addr >= 0x4000 && addr <= 0x7fff:
+		return h.ROM.read(uint(h.MappedBank())*0x4000 + uint(addr-0x4000))
+
+	case addr >= 0xa000 && addr <= 0xbfff:
+		if h.IRMode {
+			return HuC1IRIdle
+		}
+		if h.ramMapped() && h.ramBanks > 0 {
+			return h.RAM.Read(uint16(h.ramBank())*0x2000 + addr - 0xa000)
+		}
+	}
+	return UnmappedValue
+}
+
+// Write value to RAM or the IR port, or select ROM/RAM banks.
+func (h *HuC1) Write(addr uint16, value uint8) {
+	switch {
+	// 0000-1FFF - IR port (0x0e) or RAM select. RAM doesn't need enabling.
+	case addr <= 0x1fff:
+		h.mode = value
+		h.IRMode = value == HuC1IRMode
+		log.Sub("mbc/write").Debugf("Mode 0x%02x, IR mode: %t", value, h.IRMode)
+
+	// 2000-3FFF - ROM Bank Number (bank 0 selects bank 1, like MBC1)
+	case addr >= 0x2000 && addr <= 0x3fff:
+		h.BankNumber = value & 0x3f
+		if h.BankNumber == 0 {
+			h.BankNumber = 1
+		}
+		log.Sub("mbc/write").Debugf("BankNumber=0x%02x", h.BankNumber)
+
+	// 4000-5FFF - RAM Bank Number
+	case addr >= 0x4000 && addr <= 0x5fff:
+		h.RAMBank = value & (HuC1RAMBanks - 1)
+		log.Sub("mbc/write").Debugf("RAMBank=0x%02x", h.RAMBank)
+
+	// 6000-7FFF - No banking mode on HuC1, writes are ignored.
+
+	case addr >= 0xa000 && addr <= 0xbfff:
+		if h.IRMode {
+			h.IRLED = value&HuC1IRLight != 0
+			log.Sub("mbc").Debugf("IR LED on: %t", h.IRLED)
+			return
+		}
+		if !h.ramMapped() || h.ramBanks == 0 {
+			log.Sub("mbc/write").Desperatef("RAM not mapped, write to 0x%04x ignored.",
+				addr)
+			return
+		}
+		h.RAM.Write(uint16(h.ramBank())*0x2000+addr-0xa000, value)
+
+		// Write save file on change, like MBC1. FIXME: Buffer it.
+		if h.battery {
+			if err := h.RAM.Save(); err != nil {
+				log.Sub("mbc").Warningf("save RAM failed (%s)", err)
+			}
+		}
+	}
+}
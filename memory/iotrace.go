@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// I/O register range covered by IOTrace.
+const (
+	IOStart = 0xff00
+	IOEnd   = 0xff7f
+)
+
+// IONames maps I/O register addresses to their usual names, as in [PANDOCS].
+var IONames = map[uint16]string{
+	0xff00: "P1", 0xff01: "SB", 0xff02: "SC",
+	0xff04: "DIV", 0xff05: "TIMA", 0xff06: "TMA", 0xff07: "TAC",
+	0xff0f: "IF",
+	0xff10: "NR10", 0xff11: "NR11", 0xff12: "NR12", 0xff13: "NR13", 0xff14: "NR14",
+	0xff16: "NR21", 0xff17: "NR22", 0xff18: "NR23", 0xff19: "NR24",
+	0xff1a: "NR30", 0xff1b: "NR31", 0xff1c: "NR32", 0xff1d: "NR33", 0xff1e: "NR34",
+	0xff20: "NR41", 0xff21: "NR42", 0xff22: "NR43", 0xff23: "NR44",
+	0xff24: "NR50", 0xff25: "NR51", 0xff26: "NR52",
+	0xff40: "LCDC", 0xff41: "STAT", 0xff42: "SCY", 0xff43: "SCX",
+	0xff44: "LY", 0xff45: "LYC", 0xff46: "DMA", 0xff47: "BGP",
+	0xff48: "OBP0", 0xff49: "OBP1", 0xff4a: "WY", 0xff4b: "WX",
+	0xff4d: "KEY1", 0xff4f: "VBK", 0xff50: "BOOT",
+	0xff51: "HDMA1", 0xff52: "HDMA2", 0xff53: "HDMA3", 0xff54: "HDMA4", 0xff55: "HDMA5",
+	0xff56: "RP",
+	0xff68: "BCPS", 0xff69: "BCPD", 0xff6a: "OCPS", 0xff6b: "OCPD", 0xff6c: "OPRI",
+	0xff70: "SVBK",
+}
+
+// IOName returns the name of the I/O register at the given address, with wave
+// RAM bytes numbered from 0, or the address itself for unknown registers.
+func IOName(addr uint16) string {
+	if name, ok := IONames[addr]; ok {
+		return name
+	}
+	if addr >= 0xff30 && addr <= 0xff3f {
+		return fmt.Sprintf("WAVE%X", addr-0xff30)
+	}
+	return fmt.Sprintf("0x%04x", addr)
+}
+
+// ParseIOFilter returns the set of I/O addresses given as a comma-separated
+// list of register names (as in IONames) or hexadecimal addresses, with or
+// without a 0x or $ prefix. An empty list or "all" returns an empty set, which
+// traces everything.
+func ParseIOFilter(list string) (map[uint16]bool, error) {
+	filter := make(map[uint16]bool)
+	if list == "" || list == "all" {
+		return filter, nil
+	}
+
+	names := make(map[string]uint16, len(IONames))
+	for addr, name := range IONames {
+		names[name] = addr
+	}
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if addr, ok := names[strings.ToUpper(field)]; ok {
+			filter[addr] = true
+			continue
+		}
+		hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(field), "0x"), "$")
+		addr, err := strconv.ParseUint(hex, 16, 16)
+		if err != nil || addr < IOStart || addr > IOEnd {
+			return nil, fmt.Errorf("unknown I/O register %q", field)
+		}
+		filter[uint16(addr)] = true
+	}
+	return filter, nil
+}
+
+// IOTrace wraps the address space seen by the CPU to print every write to I/O
+// registers along with the program counter, for reverse-engineering games
+// (see -iotrace). Other accesses go through untouched.
+type IOTrace struct {
+	Addressable
+	PC     *uint16         // Program counter at the time of the write
+	Filter map[uint16]bool // Registers to trace, all of them if empty
+	Output io.Writer
+}
+
+// Write prints the write if it's traced, then passes it on.
+func (t *IOTrace) Write(addr uint16, value uint8) {
+	if addr >= IOStart && addr <= IOEnd && (len(t.Filter) == 0 || t.Filter[addr]) {
+		fmt.Fprintf(t.Output, "PC=0x%04x %-5s (0x%04x) = 0x%02x\n", *t.PC, IOName(addr), addr, value)
+	}
+	t.Addressable.Write(addr, value)
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// StdinPath can be used instead of a file name to read a ROM from standard
+// input.
+const StdinPath = "-"
+
+// Standard input is read once and for all since it can't be rewound, and the
+// ROM might be needed more than once (e.g. to compute its hash).
+var (
+	stdin     io.Reader = os.Stdin
+	stdinOnce sync.Once
+	stdinData []byte
+	stdinErr  error
+)
+
+// Magic bytes at the start of a ZIP archive or gzip stream.
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// ROMExtensions lists file extensions we consider to be GameBoy ROMs when
+// looking for one inside an archive or listing them in the ROM browser.
+var ROMExtensions = []string{".gb", ".gbc"}
+
+// ReadROMFile returns the contents of a ROM file. Archives (ZIP) and
+// compressed files (gzip) are detected either by extension or by their magic
+// bytes and the ROM is transparently extracted from them. This is used for
+// both boot ROMs and cartridges. A file name of "-" (StdinPath) reads the
+// whole ROM from standard input.
+func ReadROMFile(filename string) ([]byte, error) {
+	var data []byte
+	var err error
+	if filename == StdinPath {
+		data, err = readStdin()
+	} else {
+		data, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ROM file %s (%s)", filename, err)
+	}
+	return decodeROM(filename, data)
+}
+
+// Buffer the whole of standard input, since memory controllers need random
+// access to the ROM.
+func readStdin() ([]byte, error) {
+	stdinOnce.Do(func() {
+		stdinData, stdinErr = ioutil.ReadAll(stdin)
+	})
+	return stdinData, stdinErr
+}
+
+// ROMHash returns the SHA-1 hash of the given ROM contents as a hex string,
+// to identify games regardless of their file name.
+func ROMHash(data []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(data))
+}
+
+// Look at a file's name and contents to figure out whether it needs to be
+// unpacked, and return the actual ROM bytes.
+func decodeROM(filename string, data []byte) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ext == ".zip" || bytes.HasPrefix(data, zipMagic):
+		return extractZIP(filename, data)
+	case ext == ".gz" || bytes.HasPrefix(data, gzipMagic):
+		return gunzip(filename, data)
+	}
+	return data, nil
+}
+
+// Return the decompressed contents of a gzip file.
+func gunzip(filename string, data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open gzip file %s (%s)", filename, err)
+	}
+	defer r.Close()
+
+	log.Debugf("Decompressing %s", filename)
+	rom, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s (%s)", filename, err)
+	}
+	return rom, nil
+}
+
+// Return the contents of the first GameBoy ROM found in a ZIP archive.
+func extractZIP(filename string, data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open ZIP file %s (%s)", filename, err)
+	}
+
+	var roms []*zip.File
+	for _, f := range archive.File {
+		if isROMName(f.Name) {
+			roms = append(roms, f)
+		}
+	}
+	if len(roms) == 0 {
+		return nil, fmt.Errorf("no GB ROM found in %s", filename)
+	}
+	if len(roms) > 1 {
+		log.Infof("%d ROMs found in %s, using %s", len(roms), filename,
+			roms[0].Name)
+	}
+
+	log.Debugf("Extracting %s from %s", roms[0].Name, filename)
+	rc, err := roms[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	rom, err := ioutil.ReadAll(rc)
+	if err == nil && len(rom) == 0 {
+		err = errors.New("empty ROM")
+	}
+	return rom, err
+}
+
+// Returns true if the given file name has a known ROM extension.
+func isROMName(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range ROMExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestExtractZIP(t *testing.T) {
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"README.txt", []byte("not a ROM")},
+		{"game.GB", []byte{0x00, 0xc3, 0x50, 0x01}},
+		{"other.gbc", []byte{0xff, 0xff}},
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range files {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(f.data)
+	}
+	w.Close()
+
+	// Name shouldn't matter, magic bytes should be enough.
+	rom, err := decodeROM("game.bin", buf.Bytes())
+	if err != nil {
+		t.Fatalf("decodeROM() returned error: %s", err)
+	}
+	if !bytes.Equal(rom, files[1].data) {
+		t.Errorf("decodeROM() == %x, want %x", rom, files[1].data)
+	}
+
+	// Plain ROMs should be returned as-is.
+	rom, err = decodeROM("game.gb", files[1].data)
+	if err != nil || !bytes.Equal(rom, files[1].data) {
+		t.Errorf("decodeROM() == %x (%v), want %x", rom, err, files[1].data)
+	}
+}
+
+func TestGunzip(t *testing.T) {
+	want := []byte{0x31, 0xfe, 0xff, 0xaf, 0x21, 0xff, 0x9f}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(want)
+	w.Close()
+
+	for _, name := range []string{"dmg_rom.bin.gz", "dmg_rom.bin"} {
+		rom, err := decodeROM(name, buf.Bytes())
+		if err != nil {
+			t.Fatalf("decodeROM(%s) returned error: %s", name, err)
+		}
+		if !bytes.Equal(rom, want) {
+			t.Errorf("decodeROM(%s) == %x, want %x", name, rom, want)
+		}
+	}
+}
+
+func TestReadStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { stdin = os.Stdin }()
+	stdin = r
+	stdinOnce = sync.Once{}
+
+	// Write more than a pipe buffer's worth to make sure we read everything.
+	data := make([]byte, 0x20000)
+	for i := range data {
+		data[i] = uint8(i)
+	}
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+
+	// Reading a second time should return the same buffered bytes.
+	for i := 0; i < 2; i++ {
+		rom, err := ReadROMFile(StdinPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rom, data) {
+			t.Errorf("read #%d: got %d bytes, want %d", i, len(rom), len(data))
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package memory
 
+import "fmt"
+
 // Memory Bank Controllers. Source:
 // [PANMBC] https://gbdev.io/pandocs/#mbc1
 
@@ -9,6 +11,57 @@ const (
 	RAMBanking = 0x01
 )
 
+// BankForcer is implemented by mappers whose switchable ROM bank can be pinned
+// for debugging. While pinned, 4000-7FFF maps the forced bank no matter what
+// the game writes to bank registers, and those writes still update them so
+// that releasing the bank goes back to what the game selected.
+type BankForcer interface {
+	ForceBank(bank int) error
+	ReleaseBank()
+	MappedBank() uint8 // Bank mapped at 4000-7FFF, forced or not
+}
+
+// Bank pinned at 4000-7FFF for debugging, shared by mappers implementing
+// BankForcer.
+type bankPin struct {
+	bank   uint8
+	forced bool
+}
+
+// Pins the given bank, or returns an error if it isn't in the ROM.
+func (p *bankPin) force(rom *ROM, bank int) error {
+	banks := (len(rom.Bytes) + 0x3fff) / 0x4000
+	if bank < 0 || bank >= banks {
+		return fmt.Errorf("ROM bank %d out of range (ROM has %d banks)", bank, banks)
+	}
+	p.bank = uint8(bank)
+	p.forced = true
+	log.Sub("mbc").Infof("ROM bank %d forced", p.bank)
+	return nil
+}
+
+// Unpins the bank, logging the one selected by the game instead.
+func (p *bankPin) release(selected uint8) {
+	p.forced = false
+	log.Sub("mbc").Infof("ROM bank %d selected", selected)
+}
+
+// Returns the forced bank if any, or the given bank selected by the game.
+func (p *bankPin) mapped(selected uint8) uint8 {
+	if p.forced {
+		return p.bank
+	}
+	return selected
+}
+
+// Returns a suffix for mappers' String methods if a bank is forced.
+func (p *bankPin) String() string {
+	if !p.forced {
+		return ""
+	}
+	return fmt.Sprintf(" - Forced ROM bank: %d", p.bank)
+}
+
 // MBC1 (max 2MByte ROM and/or 32KByte RAM)
 type MBC1 struct {
 	*ROM            // Complete ROM (will be addressed according to ROMBank)
@@ -29,6 +82,9 @@ type MBC1 struct {
 	// Max number of ROM/RAM banks.
 	romBanks uint8 // MBC1 has max 2 MByte ROM (128 banks)
 	ramBanks uint8
+
+	// Bank pinned at 4000-7FFF for debugging, if forced (see ForceBank).
+	pin bankPin
 }
 
 // NewMBC1 creates an address space emulating a cartridge with an MBC1 chip.
@@ -45,6 +101,11 @@ func NewMBC1(rom *ROM, romBanks uint8, ramBanks uint8, battery bool, savePath st
 		}
 	}
 
+	// Banks are masked with romBanks-1, which needs the actual ROM size.
+	if romBanks == 0 {
+		romBanks = rom.banks()
+	}
+
 	return &MBC1{
 		ROM:     rom,
 		RAM:     ram,
@@ -56,6 +117,38 @@ func NewMBC1(rom *ROM, romBanks uint8, ramBanks uint8, battery bool, savePath st
 	}
 }
 
+// Save writes the cartridge's RAM to its save file if it's battery-backed.
+func (m *MBC1) Save() error {
+	if !m.battery {
+		return nil
+	}
+	return m.RAM.Save()
+}
+
+// String returns a human-readable summary of the current banking state.
+func (m *MBC1) String() string {
+	return fmt.Sprintf("MBC1 - ROM bank: %d - RAM bank: %d - RAM enabled: %t - Banking mode: %d%s",
+		m.ROMBank(), m.RAMBank(), m.RAMEnabled, m.BankingMode, &m.pin)
+}
+
+// ForceBank pins the ROM bank mapped at 4000-7FFF to the given one until
+// ReleaseBank is called. It returns an error, leaving the mapped bank alone, if
+// the bank isn't in the ROM.
+func (m *MBC1) ForceBank(bank int) error {
+	return m.pin.force(m.ROM, bank)
+}
+
+// ReleaseBank maps the ROM bank selected by the game at 4000-7FFF again.
+func (m *MBC1) ReleaseBank() {
+	m.pin.release(m.ROMBank())
+}
+
+// MappedBank returns the ROM bank mapped at 4000-7FFF, which is the forced one
+// if any (see ForceBank).
+func (m *MBC1) MappedBank() uint8 {
+	return m.pin.mapped(m.ROMBank())
+}
+
 // ROMBank returns the currently selected ROM bank according to our internal
 // registers.
 func (m *MBC1) ROMBank() (bank uint8) {
@@ -110,10 +203,10 @@ func (m *MBC1) Read(addr uint16) uint8 {
 
 	case addr >= 0x4000 && addr <= 0x7fff:
 		log.Sub("mbc/read").Desperatef("Read ROM at %x.",
-			uint(m.ROMBank())*0x4000+uint(addr-0x4000))
-		return m.ROM.read(uint(m.ROMBank())*0x4000 + uint(addr-0x4000))
+			uint(m.MappedBank())*0x4000+uint(addr-0x4000))
+		return m.ROM.read(uint(m.MappedBank())*0x4000 + uint(addr-0x4000))
 
-	case m.RAMEnabled && addr >= 0xa000 && addr <= 0xbfff:
+	case m.RAMEnabled && m.ramBanks > 0 && addr >= 0xa000 && addr <= 0xbfff:
 		return m.RAM.Read(uint16(m.RAMBank())*0x2000 + uint16(addr-0xa000))
 
 	default:
@@ -148,7 +241,7 @@ func (m *MBC1) Write(addr uint16, value uint8) {
 
 	// A000-BFFF - RAM Bank 00-03, if any
 	case addr >= 0xa000 && addr <= 0xbfff:
-		if !m.RAMEnabled {
+		if !m.RAMEnabled || m.ramBanks == 0 {
 			log.Sub("mbc/write").Desperatef("RAM not enabled, write to 0x%04x ignored.",
 				addr)
 			return
@@ -13,6 +13,12 @@ type Addressable interface {
 	Write(addr uint16, value uint8)
 }
 
+// Saver is implemented by cartridges whose battery-backed RAM can be written
+// to disk.
+type Saver interface {
+	Save() error
+}
+
 // Package-wide logger initialized below.
 var log = logger.New("memory", "memory-related operations")
 
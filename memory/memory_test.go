@@ -1,8 +1,11 @@
 package memory
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -107,7 +110,369 @@ func TestMMU(t *testing.T) {
 	}
 }
 
+func TestMMUOverlap(t *testing.T) {
+	mmu := NewEmptyMMU()
+	mmu.Add(NewRAM(0xc000, 0x2000))
+	mmu.Add(NewRAM(0xff80, 0x7f)) // Adjacent ranges are fine
+
+	vram := NewVRAM(0xdf00, 0x200, nil)
+	err := mmu.CheckOverlap(vram)
+	if err == nil {
+		t.Fatal("no overlap detected")
+	}
+	want := "address space *memory.RAM overlaps with *memory.RAM in 0xdf00-0xdfff"
+	if err.Error() != want {
+		t.Errorf("overlap error is %q, want %q", err, want)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("adding an overlapping space didn't panic")
+			}
+		}()
+		mmu.Add(vram)
+	}()
+
+	// Deliberate shadowing is still possible.
+	rom := NewRAM(0xc000, 0x10)
+	rom.Bytes[0] = 0x42
+	mmu.Overlay(rom)
+	if mmu.Read(0xc000) != 0x42 {
+		t.Error("overlaid space doesn't take precedence")
+	}
+}
+
+func TestUnmappedRead(t *testing.T) {
+	mmu := NewMMU([]Addressable{NewRAM(0xc000, 0x20)})
+	for _, addr := range []uint16{0x0000, 0xbfff, 0xc020, 0xfea0, 0xffff} {
+		if value := mmu.Read(addr); value != UnmappedValue {
+			t.Errorf("Read(0x%04x) == 0x%02x, want 0x%02x", addr, value, UnmappedValue)
+		}
+	}
+
+	// Same for an MMU with nothing mapped at all.
+	if value := NewEmptyMMU().Read(0xfea0); value != UnmappedValue {
+		t.Errorf("empty MMU Read(0xfea0) == 0x%02x, want 0x%02x", value, UnmappedValue)
+	}
+}
+
 func TestROMWrite(t *testing.T) {
 	rom := NewROM("/dev/null", 0)
 	rom.Write(0, 42)
 }
+
+func TestDumpMemory(t *testing.T) {
+	ram := NewRAM(0xc000, 0x20)
+	for i := range ram.Bytes {
+		ram.Bytes[i] = uint8(0x40 + i)
+	}
+	mmu := NewMMU([]Addressable{ram})
+
+	// Start before RAM to check unmapped addresses.
+	dump := mmu.DumpMemory(0xbffe, 6)
+	want := []byte{0xff, 0xff, 0x40, 0x41, 0x42, 0x43}
+	if !bytes.Equal(dump, want) {
+		t.Errorf("DumpMemory() == %x, want %x", dump, want)
+	}
+
+	if dump := mmu.DumpMemory(0xfffe, 8); len(dump) != 2 {
+		t.Errorf("DumpMemory() past 0xffff returned %d bytes, want 2", len(dump))
+	}
+	for _, start := range []uint{0x10000, 0x12345} {
+		if dump := mmu.DumpMemory(start, 4); len(dump) != 0 {
+			t.Errorf("DumpMemory(0x%x) returned %d bytes, want none", start, len(dump))
+		}
+	}
+
+	hex := mmu.HexDump(0xc000, 4)
+	wantHex := "c000  40 41 42 43                                      |@ABC|\n"
+	if hex != wantHex {
+		t.Errorf("HexDump() == %q, want %q", hex, wantHex)
+	}
+}
+
+func TestDMAGuard(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		wram := NewRAM(0xc000, 0x2000)
+		hram := NewRAM(0xff80, 0x7f)
+		oam := NewRAM(0xfe00, 0xa0)
+		dma := &DMA{}
+		mmu := NewMMU([]Addressable{wram, oam, dma, hram})
+		dma.MMU = mmu
+
+		var bus Addressable = mmu
+		if strict {
+			bus = &DMAGuard{Addressable: mmu, DMA: dma}
+		}
+
+		wram.Write(0xc000, 0x42)
+		hram.Write(0xff80, 0x24)
+		bus.Write(AddrDMA, 0xc0)
+		dma.Tick()
+
+		want := uint8(0x42)
+		if strict {
+			want = 0xff
+		}
+		if value := bus.Read(0xc000); value != want {
+			t.Errorf("strict=%t: WRAM reads 0x%02x during DMA, want 0x%02x", strict, value, want)
+		}
+		if value := bus.Read(0xff80); value != 0x24 {
+			t.Errorf("strict=%t: HRAM reads 0x%02x during DMA, want 0x24", strict, value)
+		}
+		bus.Write(0xc001, 0x99)
+		if written := wram.Read(0xc001) == 0x99; written == strict {
+			t.Errorf("strict=%t: WRAM written=%t during DMA", strict, written)
+		}
+
+		// Everything is accessible again once the transfer is done.
+		for dma.Active() {
+			dma.Tick()
+		}
+		if value := bus.Read(0xc000); value != 0x42 {
+			t.Errorf("strict=%t: WRAM reads 0x%02x after DMA, want 0x42", strict, value)
+		}
+		if oam.Read(0xfe00) != 0x42 {
+			t.Errorf("strict=%t: DMA didn't copy WRAM to OAM", strict)
+		}
+	}
+}
+
+func TestWRAMBanking(t *testing.T) {
+	wram := NewWRAM(true, nil)
+	mmu := NewEmptyMMU()
+	mmu.Add(wram)
+
+	mmu.Write(0xc000, 0x11) // Bank 0, always mapped
+	mmu.Write(AddrSVBK, 2)
+	mmu.Write(0xd000, 0x22)
+	mmu.Write(AddrSVBK, 5)
+	mmu.Write(0xd000, 0x55)
+
+	if v := mmu.Read(0xd000); v != 0x55 {
+		t.Errorf("bank 5 holds 0x%02x, want 0x55", v)
+	}
+	mmu.Write(AddrSVBK, 2)
+	if v := mmu.Read(0xd000); v != 0x22 {
+		t.Errorf("bank 2 holds 0x%02x, want 0x22", v)
+	}
+	if v := mmu.Read(0xc000); v != 0x11 {
+		t.Errorf("bank 0 holds 0x%02x, want 0x11", v)
+	}
+	if v := mmu.Read(AddrSVBK); v != 0xfa {
+		t.Errorf("SVBK == 0x%02x, want 0xfa", v)
+	}
+
+	// Bank 0 can't be selected for 0xd000-0xdfff, it maps bank 1 instead.
+	mmu.Write(AddrSVBK, 1)
+	mmu.Write(0xd000, 0x01)
+	mmu.Write(AddrSVBK, 0)
+	if v := mmu.Read(0xd000); v != 0x01 || wram.Banks[0][0] != 0x11 {
+		t.Errorf("SVBK=0 maps 0x%02x, want bank 1's 0x01", v)
+	}
+
+	// No banking in DMG mode.
+	if NewWRAM(false, nil).Contains(AddrSVBK) {
+		t.Error("SVBK mapped in DMG mode")
+	}
+}
+
+func TestFillPattern(t *testing.T) {
+	// The same seed gives the same contents, in every region.
+	fill := NewFill(FillRandom, 42)
+	vram, wram := NewVRAM(0x8000, 0x2000, fill), NewWRAM(true, fill)
+	fill = NewFill(FillRandom, 42)
+	if !bytes.Equal(NewVRAM(0x8000, 0x2000, fill).Bytes, vram.Bytes) ||
+		NewWRAM(true, fill).Banks != wram.Banks {
+		t.Error("seeded RAM contents differ between runs")
+	}
+	fill = NewFill(FillRandom, 43)
+	if bytes.Equal(NewVRAM(0x8000, 0x2000, fill).Bytes, vram.Bytes) {
+		t.Error("RAM contents identical with different seeds")
+	}
+
+	for i, value := range NewFilledRAM(0xff80, 0x7f, NewFill(FillOnes, 0)).Bytes {
+		if value != 0xff {
+			t.Fatalf("byte %d is 0x%02x, want 0xff", i, value)
+		}
+	}
+
+	// Only VRAM is random by default.
+	if !bytes.Equal(NewFilledRAM(0xff80, 0x7f, nil).Bytes, make([]byte, 0x7f)) {
+		t.Error("RAM not zeroed by default")
+	}
+
+	if _, err := ParseFill("sometimes"); err == nil {
+		t.Error("no error for an unknown fill pattern")
+	}
+}
+
+func TestHDMA(t *testing.T) {
+	vram := NewRAM(0x8000, 0x2000)
+	wram := NewRAM(0xc000, 0x2000)
+	for i := range wram.Bytes {
+		wram.Bytes[i] = uint8(i)
+	}
+	mmu := NewMMU([]Addressable{vram, wram})
+	hdma := NewHDMA(mmu)
+	mmu.Add(hdma)
+
+	setup := func(src, dest uint16) {
+		mmu.Write(AddrHDMA1, uint8(src>>8))
+		mmu.Write(AddrHDMA2, uint8(src))
+		mmu.Write(AddrHDMA3, uint8(dest>>8))
+		mmu.Write(AddrHDMA4, uint8(dest))
+	}
+
+	// General purpose transfer: 0x40 bytes from 0xc100 to 0x8800 right away.
+	setup(0xc100, 0x8800)
+	mmu.Write(AddrHDMA5, 0x03)
+	for i := uint16(0); i < 0x40; i++ {
+		if v := mmu.Read(0x8800 + i); v != uint8(0x100+i) {
+			t.Fatalf("general DMA: 0x%04x holds 0x%02x, want 0x%02x", 0x8800+i, v, uint8(0x100+i))
+		}
+	}
+	if v := mmu.Read(0x8840); v != 0 {
+		t.Errorf("general DMA copied past its length (0x%02x at 0x8840)", v)
+	}
+	if v := mmu.Read(AddrHDMA5); v != 0xff {
+		t.Errorf("HDMA5=0x%02x after general DMA, want 0xff", v)
+	}
+
+	// HBlank transfer: 3 blocks from 0xc200 to 0x9000, one per HBlank.
+	setup(0xc200, 0x9000)
+	mmu.Write(AddrHDMA5, HDMA5HBlank|0x02)
+	if v := mmu.Read(0x9000); v != 0 {
+		t.Error("HBlank DMA copied data before HBlank")
+	}
+	for block := uint16(0); block < 3; block++ {
+		if v := mmu.Read(AddrHDMA5); v != uint8(2-block) {
+			t.Errorf("HDMA5=0x%02x before block %d, want 0x%02x", v, block, 2-block)
+		}
+		hdma.HBlank()
+		addr := 0x9000 + block*0x10
+		if v := mmu.Read(addr + 0xf); v != uint8(0x20f+block*0x10) {
+			t.Errorf("block %d: 0x%04x holds 0x%02x", block, addr+0xf, v)
+		}
+		if v := mmu.Read(addr + 0x10); v != 0 {
+			t.Errorf("block %d: copied past the block", block)
+		}
+	}
+	if v := mmu.Read(AddrHDMA5); v != 0xff {
+		t.Errorf("HDMA5=0x%02x after HBlank DMA, want 0xff", v)
+	}
+
+	// HBlank transfers can be cancelled.
+	setup(0xc300, 0x9100)
+	mmu.Write(AddrHDMA5, HDMA5HBlank|0x07)
+	hdma.HBlank()
+	mmu.Write(AddrHDMA5, 0x00)
+	hdma.HBlank()
+	if v := mmu.Read(AddrHDMA5); v != HDMA5HBlank|0x06 {
+		t.Errorf("HDMA5=0x%02x after cancelling, want 0x86", v)
+	}
+	if v := mmu.Read(0x9110); v != 0 {
+		t.Error("cancelled HBlank DMA kept copying")
+	}
+}
+
+func TestIOTrace(t *testing.T) {
+	filter, err := ParseIOFilter("lcdc, 0xff47,SCX")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	pc := uint16(0x0150)
+	trace := &IOTrace{
+		Addressable: NewRAM(0xc000, 0x4000),
+		PC:          &pc,
+		Filter:      filter,
+		Output:      &output,
+	}
+
+	trace.Write(0xff40, 0x91)
+	pc = 0x0153
+	trace.Write(0xff47, 0xe4)
+	trace.Write(0xff42, 0x10) // SCY, filtered out
+	trace.Write(0xc000, 0x42) // Not I/O
+	trace.Write(0xff43, 0x08)
+
+	expected := "PC=0x0150 LCDC  (0xff40) = 0x91\n" +
+		"PC=0x0153 BGP   (0xff47) = 0xe4\n" +
+		"PC=0x0153 SCX   (0xff43) = 0x08\n"
+	if output.String() != expected {
+		t.Errorf("traced:\n%s\nwant:\n%s", output.String(), expected)
+	}
+	if v := trace.Read(0xff47); v != 0xe4 {
+		t.Errorf("traced write not passed on, read 0x%02x", v)
+	}
+
+	if IOName(0xff3a) != "WAVEA" || IOName(0xff03) != "0xff03" {
+		t.Errorf("unexpected names %s, %s", IOName(0xff3a), IOName(0xff03))
+	}
+	if _, err := ParseIOFilter("NOPE"); err == nil {
+		t.Error("no error for an unknown register")
+	}
+
+	// Hexadecimal addresses don't need a prefix.
+	for _, list := range []string{"ff43", "$ff43", "0XFF43"} {
+		filter, err := ParseIOFilter(list)
+		if err != nil || len(filter) != 1 || !filter[0xff43] {
+			t.Errorf("ParseIOFilter(%q) = %v, %v", list, filter, err)
+		}
+	}
+}
+
+func TestLoadMismatchedSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, size := range []int{0x1000, 0x2000 + 16} {
+		save := make([]byte, size)
+		for i := range save {
+			save[i] = 0x42
+		}
+		path := filepath.Join(dir, "game.sav")
+		if err := ioutil.WriteFile(path, save, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		ram := NewRAM(0xa000, 0x2000)
+		ram.Bytes[0x1fff] = 0x99
+		if err := ram.Load(path); err != nil {
+			t.Errorf("0x%04x-byte save not loaded: %v", size, err)
+			continue
+		}
+		if len(ram.Bytes) != 0x2000 {
+			t.Errorf("0x%04x-byte save resized RAM to 0x%04x bytes", size,
+				len(ram.Bytes))
+			continue
+		}
+		if ram.Read(0xa000) != 0x42 {
+			t.Errorf("0x%04x-byte save not loaded at the start of RAM", size)
+		}
+
+		// Undersized saves leave the rest of RAM alone.
+		want := uint8(0x42)
+		if size < 0x2000 {
+			want = 0x99
+		}
+		if value := ram.Read(0xbfff); value != want {
+			t.Errorf("0x%04x-byte save: last RAM byte is 0x%02x, want 0x%02x",
+				size, value, want)
+		}
+
+		// Saving again writes RAM as is.
+		if err := ram.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if saved, _ := ioutil.ReadFile(path); len(saved) != 0x2000 {
+			t.Errorf("0x%04x-byte save rewritten with 0x%04x bytes", size, len(saved))
+		}
+	}
+}
@@ -1,5 +1,14 @@
 package memory
 
+import (
+	"bytes"
+	"fmt"
+)
+
+// UnmappedValue is what reading from an address no space handles returns. The
+// data bus is pulled up on hardware, so such reads see all bits set.
+const UnmappedValue = 0xff
+
 // MMU manages an arbitrary number of ordered address spaces. It also satisfies
 // the Addressable interface.
 type MMU struct {
@@ -7,6 +16,8 @@ type MMU struct {
 }
 
 // NewMMU returns an instance of MMU initialized with existing address spaces.
+// They're used as given, so any of them can deliberately shadow the ones that
+// come after it. Use NewEmptyMMU and Add to catch accidental overlaps instead.
 func NewMMU(spaces []Addressable) *MMU {
 	return &MMU{spaces}
 }
@@ -17,11 +28,47 @@ func NewEmptyMMU() *MMU {
 	return &MMU{empty}
 }
 
-// Add an address space at the end of this MMU's list.
+// Add an address space at the end of this MMU's list. This panics if any
+// address it contains is already handled by another space in the MMU, as the
+// new one would silently never see it. Use Overlay to shadow addresses on
+// purpose.
 func (m *MMU) Add(space Addressable) {
+	if err := m.CheckOverlap(space); err != nil {
+		panic(err)
+	}
 	m.Spaces = append(m.Spaces, space)
 }
 
+// Overlay adds an address space at the start of this MMU's list, so that it
+// takes precedence over the others for the addresses it contains (e.g. the
+// boot ROM over the cartridge's first bytes).
+func (m *MMU) Overlay(space Addressable) {
+	m.Spaces = append([]Addressable{space}, m.Spaces...)
+}
+
+// CheckOverlap returns an error describing the address range where the given
+// space overlaps with the ones already in the MMU, if any.
+func (m *MMU) CheckOverlap(space Addressable) error {
+	for addr := 0; addr <= 0xffff; addr++ {
+		if !space.Contains(uint16(addr)) {
+			continue
+		}
+		other := m.space(uint16(addr))
+		if other == nil {
+			continue
+		}
+
+		// Find where the overlap ends, for a more helpful message.
+		last := addr
+		for last < 0xffff && space.Contains(uint16(last+1)) && m.space(uint16(last+1)) == other {
+			last++
+		}
+		return fmt.Errorf("address space %T overlaps with %T in 0x%04x-0x%04x",
+			space, other, addr, last)
+	}
+	return nil
+}
+
 // Contains returns whether one of the address spaces known to the MMU contains
 // the given address. The first address space in the internal list containing a
 // given address will shadow any other that may contain it.
@@ -41,13 +88,14 @@ func (m *MMU) space(addr uint16) Addressable {
 
 // Read finds the first address space compatible with the given address and
 // returns the value at that address. If no space contains the requested
-// address, it returns 0xff (emulates black bar on boot).
+// address, it logs it at debug level and returns UnmappedValue (which also
+// emulates the black bar on boot).
 func (m *MMU) Read(addr uint16) uint8 {
 	if space := m.space(addr); space != nil {
 		return space.Read(addr)
 	}
 	log.Sub("mmu/read").Debugf("MMU.Read: Unmapped address 0x%04x", addr)
-	return 0xff
+	return UnmappedValue
 }
 
 // Write finds the first address space compatible with the given address and
@@ -61,3 +109,48 @@ func (m *MMU) Write(addr uint16, value uint8) {
 			addr, value)
 	}
 }
+
+// DumpMemory returns a copy of length bytes starting at the given address, as
+// currently mapped (i.e. reading from the currently selected banks). Reads go
+// straight to address spaces, without logging. Addresses beyond 0xffff are
+// ignored. Unmapped addresses read as UnmappedValue.
+func (m *MMU) DumpMemory(start, length uint) []byte {
+	if start >= 0x10000 {
+		return []byte{}
+	}
+	if length > 0x10000-start {
+		length = 0x10000 - start
+	}
+	dump := make([]byte, length)
+	for i := range dump {
+		addr := uint16(start + uint(i))
+		if space := m.space(addr); space != nil {
+			dump[i] = space.Read(addr)
+		} else {
+			dump[i] = UnmappedValue
+		}
+	}
+	return dump
+}
+
+// HexDump returns a human-readable dump of a memory region, 16 bytes per line
+// prefixed by their address and followed by their printable ASCII values.
+func (m *MMU) HexDump(start, length uint) string {
+	var b bytes.Buffer
+	dump := m.DumpMemory(start, length)
+	for offset := 0; offset < len(dump); offset += 16 {
+		line := dump[offset:]
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		fmt.Fprintf(&b, "%04x  % -47x  |", start+uint(offset), line)
+		for _, c := range line {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			b.WriteByte(c)
+		}
+		b.WriteString("|\n")
+	}
+	return b.String()
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 )
 
 // RAM as an array of R/W bytes at addresses starting from a given offset.
@@ -38,23 +37,23 @@ func (r *RAM) Contains(addr uint16) bool {
 }
 
 // NewVRAM instantiates a slice of the given size to represent RAM, initialized
-// with random values.
-func NewVRAM(start, size uint16) *RAM {
+// with random values unless the given fill pattern says otherwise.
+func NewVRAM(start, size uint16, fill *Fill) *RAM {
 	vram := NewRAM(start, size)
-	for i := range vram.Bytes {
-		vram.Bytes[i] = uint8(rand.Intn(0xff))
-	}
+	fill.fill(vram.Bytes, true)
 	return vram
 }
 
 // Load sets the current content of RAM from the given file, and stores the
-// path to that file for subsequent saves.
+// path to that file for subsequent saves. Files larger than RAM are truncated
+// (some emulators append extra data such as clock state), and smaller ones
+// only replace the beginning of RAM, with a warning either way. The file will
+// have the size of RAM once saved again.
 func (r *RAM) Load(filename string) error {
-	if r.saveFile != filename {
+	if r.saveFile != "" && r.saveFile != filename {
 		log.Warningf("calling Load(%s) on RAM with an existing save file (%s)",
 			filename, r.saveFile)
 	}
-	oldSavefile := r.saveFile
 	r.saveFile = filename
 
 	bytes, err := ioutil.ReadFile(filename)
@@ -63,18 +62,17 @@ func (r *RAM) Load(filename string) error {
 		return fmt.Errorf("cannot load RAM file %s (%s)", filename, err)
 	}
 
-	// Token attempt at checking file validity.
-	if len(bytes) != len(r.Bytes) {
-		// In this specific case, reset saveFile because this may well be an
-		// innocent mistake and we don't want that file overwritten later.
-		r.saveFile = oldSavefile
-
-		return fmt.Errorf("RAM size (0x%04x) does not match save file's (0x%04x)",
-			len(r.Bytes), len(bytes))
+	switch {
+	case len(bytes) > len(r.Bytes):
+		log.Warningf("save file %s is larger than RAM (0x%04x > 0x%04x), "+
+			"ignoring the extra bytes", filename, len(bytes), len(r.Bytes))
+	case len(bytes) < len(r.Bytes):
+		log.Warningf("save file %s is smaller than RAM (0x%04x < 0x%04x), "+
+			"leaving the rest of RAM as is", filename, len(bytes), len(r.Bytes))
 	}
 
 	// Replace current (normally empty) RAM with file contents.
-	r.Bytes = bytes
+	copy(r.Bytes, bytes)
 	log.Infof("loading RAM values from %s", filename)
 
 	return nil
@@ -0,0 +1,78 @@
+package memory
+
+// CGB work RAM banking. Source:
+// [PANCGB] https://gbdev.io/pandocs/CGB_Registers.html#ff70---svbk---cgb-mode-only---wram-bank
+
+// AddrSVBK is the address of the CGB WRAM bank select register.
+const AddrSVBK = 0xff70
+
+// Work RAM layout.
+const (
+	WRAMStart    = 0xc000
+	WRAMBankSize = 0x1000
+	WRAMBanks    = 8
+)
+
+// WRAM is the work RAM at 0xc000-0xdfff. The first 4KB always map bank 0. In
+// CGB mode, the other 4KB map one of banks 1 to 7 as selected by the SVBK
+// register (where 0 also selects bank 1). In DMG mode, SVBK is not mapped and
+// bank 1 is always used.
+type WRAM struct {
+	Banks [WRAMBanks][WRAMBankSize]uint8
+	SVBK  uint8
+	CGB   bool // Whether SVBK is mapped
+}
+
+// NewWRAM returns work RAM filled according to the given fill pattern, with
+// banking enabled in CGB mode.
+func NewWRAM(cgb bool, fill *Fill) *WRAM {
+	w := &WRAM{CGB: cgb}
+	for i := range w.Banks {
+		fill.fill(w.Banks[i][:], false)
+	}
+	return w
+}
+
+// Bank returns the bank currently mapped at 0xd000-0xdfff.
+func (w *WRAM) Bank() int {
+	if bank := int(w.SVBK & 0x07); bank != 0 {
+		return bank
+	}
+	return 1
+}
+
+// Returns the bank and offset in that bank for the given address.
+func (w *WRAM) locate(addr uint16) (bank int, offset uint16) {
+	offset = addr - WRAMStart
+	if offset < WRAMBankSize {
+		return 0, offset
+	}
+	return w.Bank(), offset - WRAMBankSize
+}
+
+// Contains returns true for work RAM addresses, and SVBK in CGB mode.
+func (w *WRAM) Contains(addr uint16) bool {
+	return (addr >= WRAMStart && addr < WRAMStart+2*WRAMBankSize) ||
+		(w.CGB && addr == AddrSVBK)
+}
+
+// Read returns the value at the given address in the currently mapped bank,
+// or SVBK with its unused bits set.
+func (w *WRAM) Read(addr uint16) uint8 {
+	if addr == AddrSVBK {
+		return 0xf8 | w.SVBK
+	}
+	bank, offset := w.locate(addr)
+	return w.Banks[bank][offset]
+}
+
+// Write stores the value at the given address in the currently mapped bank,
+// or selects a new bank if writing to SVBK.
+func (w *WRAM) Write(addr uint16, value uint8) {
+	if addr == AddrSVBK {
+		w.SVBK = value & 0x07
+		return
+	}
+	bank, offset := w.locate(addr)
+	w.Banks[bank][offset] = value
+}
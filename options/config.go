@@ -5,14 +5,17 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/lazy-stripes/goholint/memory"
 
 	"gopkg.in/ini.v1"
 )
 
-// Keymap associating an action name (joypad input, UI command...) to an input.
-type Keymap map[string]sdl.Keycode
+// Keymap associating an action name (joypad input, UI command...) to the name
+// of a key, as found in the config file. Names are translated by the frontend,
+// so that options don't depend on SDL.
+type Keymap map[string]string
 
 const (
 	// ConfigFolder is the path to our dedicated folder in the user's home.
@@ -22,13 +25,55 @@ const (
 	// automatically if no config exists at run time. TODO: embed from file?
 	DefaultConfig = `# Most of the flags (except, obviously -config) can be overridden here with
 # the exact same name. See -help for details.
+# Per-game overrides can be put in ~/.goholint/games/<title or ROM SHA-1>.ini
 
-#boot = path/to/dmg_rom.bin
+#audiobuffer = 2048 # Sample frames, lower for less lag, higher if sound crackles
+#batch = 4         # PPU dots per step, faster but less accurate
+#blankscreen = band # Or frozen, 0-3, path/to/image.png
+#boot = path/to/dmg_rom.bin,path/to/cgb_rom.bin # Or a single boot ROM
+#breakat = Main,01:4000 # Labels, bank:address or addresses
+#brightness = 10   # Percent, or negative to darken
+#breakpoint = dump # Or screenshot, halt
+#camera = noise    # Game Boy Camera test pattern, or bars
+#contrast = 20     # Percent, or negative down to -100 (flat gray)
 #cpuprofile = path/to/cpuprofile.pprof
-#level = debug
+#opprofile = 1     # Print executions and cycles per opcode on exit
+#level = debug     # Or per module, e.g. ppu:debug,apu:warn,default:info
+#dmastrict = 1
 #fastboot = 1
+#filename = {title}-{n} # Captures named e.g. TETRIS-1.png, TETRIS-2.png...
+#flip = h          # Or v, hv (mirrored display and recordings)
+#focuspause = 1
+#gifskip = 150     # Drop the first 150 frames of GIFs (boot logo)
+#ghosting = 50     # Keep 50% of each frame in the next in GIFs (LCD blur)
+#iotrace = LCDC,STAT # Or all
+#jpegquality = 90
+#dmg = 1
+#mbc = mbc1        # Or none, huc1, huc3, camera (mbc2/3/5 aren't emulated yet)
+#noaudio = 1       # Discard sound, e.g. without an audio device
 #nosync = 1
+#oambug = 1
+#palette = path/to/palette.pal
+#palettewrite = ignore  # Or latch, instant (BGP/OBP writes during mode 3)
+#pauseoninput = 1  # Pause where replayed inputs change (resume or step to go on)
+#ramfill = random  # Or default, zero, ff
+#rawformat = indexed # Or rgba, for rawframes
+#rawframes = path/to/pipe # Or - for stdout
+#romdir = path/to/roms
+#samplerate = 48000
+#seed = 42         # Same random RAM contents on every run
+#screenshot = png  # Or bmp, jpeg
+#socd = neutral    # Or raw, last
+#statbug = 1
+#swapab = 1        # Swap the A and B buttons' keys (left-handed play)
+#sym = path/to/game.sym
+#tilewatch = 1
+#tint = ffe8c0     # Multiply output colors by this one
+#timelapse = 600   # Save a screenshot every 600 frames (about 10s)
+#turbo = a,b       # Autofire while held
+#turborate = 4     # Frames between autofire press and release
 #waitkey = 1
+#waveinterp = 1
 #zoom = 1
 
 # Define your keymap below with <action>=<key>. Key codes are taken from the
@@ -46,24 +91,84 @@ start  = RETURN    # Start Button
 
 screenshot = F12   # Save a screenshot in the current directory
 
+dumpstate = F9     # Save emulator state and a screenshot for bug reports
+
+dumpvram = F8      # Save tiles and background map as PNG in the save directory
+
 recordgif = g      # Start/stop recording video output to GIF
 
-# TODO: quit, reset, snapshot...
+toggleui = u       # Hide/show the UI overlay
+
+fullscreen = F11   # Switch between windowed and fullscreen display
+
+pause     = p      # Pause/resume emulation
+stepframe = n      # While paused, run a single frame
+
+vramviewer = v     # Open/hide a window showing tile maps and tiles in VRAM
+
+openrom = o        # Browse ROMs in the ROM folder (arrows, Enter, Escape)
+
+cyclepalette = c   # Switch to the next built-in palette
+
+speedup   = ]      # Run emulation 0.25x faster (up to 4x)
+speeddown = [      # Run emulation 0.25x slower (down to 0.25x)
+
+turbo = t          # Turn autofire on/off for buttons set with the turbo option
+
+togglebg     = F1  # Hide/show the background layer
+togglewindow = F2  # Hide/show the window layer
+togglesprite = F3  # Hide/show sprites
+
+reboot = F5        # Restart the current ROM, running the boot ROM
+
+# TODO: quit, snapshot...
 `
 )
 
+// GamesFolder contains per-game config overrides, layered on top of the main
+// config. They are named after the ROM's SHA-1 hash (e.g. games/<sha1>.ini)
+// or the cartridge title (e.g. games/TETRIS.ini), the hash taking precedence.
+var GamesFolder = filepath.Join(ConfigFolder, "games")
+
+// Swap returns a copy of the keymap where the keys bound to the two given
+// actions are exchanged.
+func (k Keymap) Swap(action1, action2 string) Keymap {
+	swapped := make(Keymap, len(k))
+	for action, key := range k {
+		swapped[action] = key
+	}
+	swapped[action1], swapped[action2] = k[action2], k[action1]
+	return swapped
+}
+
 // DefaultKeymap is a reasonable default mapping for QWERTY/AZERTY layouts.
 var DefaultKeymap = Keymap{
-	"up":         sdl.K_UP,
-	"down":       sdl.K_DOWN,
-	"left":       sdl.K_LEFT,
-	"right":      sdl.K_RIGHT,
-	"a":          sdl.K_s,
-	"b":          sdl.K_d,
-	"select":     sdl.K_BACKSPACE,
-	"start":      sdl.K_RETURN,
-	"screenshot": sdl.K_F12,
-	"recordgif":  sdl.K_g,
+	"up":           "UP",
+	"down":         "DOWN",
+	"left":         "LEFT",
+	"right":        "RIGHT",
+	"a":            "s",
+	"b":            "d",
+	"select":       "BACKSPACE",
+	"start":        "RETURN",
+	"screenshot":   "F12",
+	"dumpstate":    "F9",
+	"recordgif":    "g",
+	"toggleui":     "u",
+	"fullscreen":   "F11",
+	"pause":        "p",
+	"stepframe":    "n",
+	"vramviewer":   "v",
+	"openrom":      "o",
+	"cyclepalette": "c",
+	"speedup":      "]",
+	"speeddown":    "[",
+	"turbo":        "t",
+	"togglebg":     "F1",
+	"togglewindow": "F2",
+	"togglesprite": "F3",
+	"dumpvram":     "F8",
+	"reboot":       "F5",
 }
 
 // configKey returns a config key by the given name if it's present in the file
@@ -103,6 +208,56 @@ func applyUint(cfg *ini.File, flags map[string]bool, name string, dst *uint) {
 	}
 }
 
+// Same as apply for signed 64-bit integers.
+func applyInt64(cfg *ini.File, flags map[string]bool, name string, dst *int64) {
+	if key := configKey(cfg, flags, name); key != nil {
+		if i, err := key.Int64(); err == nil {
+			*dst = i
+		}
+	}
+}
+
+// expandHome replaces a leading ~ in the given path with the user's home
+// folder. Go doesn't natively handle ~ in paths, fair enough.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if u, err := user.Current(); err == nil {
+			return filepath.Join(u.HomeDir, path[1:])
+		}
+	}
+	return path
+}
+
+// gameConfigPaths returns the paths of existing per-game config files for the
+// given ROM, by increasing order of priority.
+func gameConfigPaths(romPath string) (paths []string) {
+	if romPath == "" {
+		return nil
+	}
+	data, err := memory.ReadROMFile(romPath)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	if len(data) > memory.AddrGlobalChecksum {
+		header := memory.ReadHeader(&memory.RAM{Bytes: data[:memory.AddrGlobalChecksum+2]})
+		if header.Title != "" && !strings.ContainsAny(header.Title, `/\`) {
+			names = append(names, header.Title)
+		}
+	}
+	names = append(names, memory.ROMHash(data))
+
+	folder := expandHome(GamesFolder)
+	for _, name := range names {
+		path := filepath.Join(folder, name+".ini")
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 // Attempt to create home config folder and copy our default config there.
 func createDefaultConfig() {
 	// Only create default config if the config folder isn't there yet.
@@ -131,48 +286,108 @@ func createDefaultConfig() {
 
 // Update reads all parameters from a given configuration file and updates the
 // Options instance with those values, skipping all options that may already
-// have been set on the command-line.
+// have been set on the command-line. Per-game overrides for the ROM being run
+// (see GamesFolder) are applied on top of that file. The keys for the A and B
+// buttons are then swapped if requested (see -swapab).
 func (o *Options) Update(configPath string, flags map[string]bool) {
-	if configPath == "" {
-		return
+	// No real error handling, this method should be forgiving.
+	if err := o.UpdateE(configPath, flags); err != nil {
+		fmt.Println(err)
 	}
+}
 
-	// Go doesn't natively handle ~ in paths, fair enough.
-	if configPath[0] == '~' {
-		if u, err := user.Current(); err == nil {
-			configPath = filepath.Join(u.HomeDir, configPath[1:])
+// UpdateE is the same as Update but returns an error if the configuration
+// file can't be read or parsed, in which case options other than the A/B swap
+// are left untouched. A broken per-game override is skipped, the main file's
+// values are still applied and the error is returned afterwards. Missing keys
+// and values that don't parse are ignored either way.
+func (o *Options) UpdateE(configPath string, flags map[string]bool) (err error) {
+	// After loading the keymap, so that custom keys are swapped too.
+	defer func() {
+		if o.SwapAB {
+			o.Keymap = o.Keymap.Swap("a", "b")
 		}
+	}()
+
+	if configPath == "" {
+		return nil
 	}
 
+	configPath = expandHome(configPath)
 	cfg, err := ini.Load(configPath)
 	if err != nil {
-		// No real error handling, this method should be forgiving.
-		fmt.Printf("Can't load config file %s (%s)\n", configPath, err)
-		return
+		return fmt.Errorf("can't load config file %s (%s)", configPath, err)
+	}
+
+	for _, path := range gameConfigPaths(o.ROMPath) {
+		if appendErr := cfg.Append(path); appendErr != nil && err == nil {
+			err = fmt.Errorf("can't load game config file %s (%s)", path, appendErr)
+		}
 	}
 
 	// Using quick and dirty helpers because mixed types and lazy.
+	applyUint(cfg, flags, "batch", &o.Batch)
+	applyUint(cfg, flags, "audiobuffer", &o.AudioBuffer)
+	apply(cfg, flags, "blankscreen", &o.BlankScreen)
 	apply(cfg, flags, "boot", &o.BootROM)
+	applyInt64(cfg, flags, "brightness", &o.Brightness)
+	apply(cfg, flags, "breakat", &o.BreakAt)
+	apply(cfg, flags, "breakpoint", &o.Breakpoint)
+	apply(cfg, flags, "camera", &o.Camera)
+	applyInt64(cfg, flags, "contrast", &o.Contrast)
 	apply(cfg, flags, "cpuprofile", &o.CPUProfile)
-	// TODO: debug special format.
+	applyBool(cfg, flags, "opprofile", &o.OpProfile)
+	// Either a global level or per-module levels (see logger.ParseLevels).
 	apply(cfg, flags, "level", &o.DebugLevel)
+	applyBool(cfg, flags, "dmastrict", &o.DMAStrict)
 	applyBool(cfg, flags, "fastboot", &o.FastBoot)
+	apply(cfg, flags, "flip", &o.Flip)
+	applyBool(cfg, flags, "focuspause", &o.FocusPause)
+	apply(cfg, flags, "filename", &o.Filename)
+	applyUint(cfg, flags, "gifskip", &o.GIFSkip)
+	applyUint(cfg, flags, "ghosting", &o.Ghosting)
+	apply(cfg, flags, "iotrace", &o.IOTrace)
+	applyUint(cfg, flags, "jpegquality", &o.JPEGQuality)
+	applyBool(cfg, flags, "dmg", &o.ForceDMG)
+	apply(cfg, flags, "mbc", &o.MBC)
+	applyBool(cfg, flags, "noaudio", &o.NoAudio)
 	applyBool(cfg, flags, "nosync", &o.VSync)
+	applyBool(cfg, flags, "oambug", &o.OAMBug)
+	apply(cfg, flags, "palette", &o.PalettePath)
+	apply(cfg, flags, "palettewrite", &o.PaletteWrite)
+	applyBool(cfg, flags, "pauseoninput", &o.PauseOnInput)
+	apply(cfg, flags, "ramfill", &o.RAMFill)
+	apply(cfg, flags, "romdir", &o.ROMDir)
+	applyUint(cfg, flags, "samplerate", &o.SamplingRate)
+	apply(cfg, flags, "screenshot", &o.Screenshots)
+	applyInt64(cfg, flags, "seed", &o.Seed)
+	apply(cfg, flags, "socd", &o.SOCD)
+	applyBool(cfg, flags, "swapab", &o.SwapAB)
+	applyBool(cfg, flags, "statbug", &o.STATBug)
+	applyBool(cfg, flags, "tilewatch", &o.TileWatch)
+	apply(cfg, flags, "rawformat", &o.RawFormat)
+	apply(cfg, flags, "rawframes", &o.RawFrames)
+	apply(cfg, flags, "sym", &o.Symbols)
+	apply(cfg, flags, "tint", &o.Tint)
+	applyUint(cfg, flags, "timelapse", &o.TimeLapse)
+	apply(cfg, flags, "turbo", &o.Turbo)
+	applyUint(cfg, flags, "turborate", &o.TurboRate)
 	// TODO: savedir (and just ditch savepath altogether)
 	applyBool(cfg, flags, "waitkey", &o.WaitKey)
+	applyBool(cfg, flags, "waveinterp", &o.WaveInterp)
 	applyUint(cfg, flags, "zoom", &o.ZoomFactor)
 
 	// Ignoring options that are not really interesting as a config.
 	// Such as -cyles, -gif or -rom...
 
-	// Set keymap here. Build on top of default. TODO: validate.
+	// Set keymap here. Build on top of default. Key names are validated by
+	// the frontend.
 	keySection := cfg.Section("keymap")
 	for key := range o.Keymap {
 		// Key() will return the empty string if it doesn't exist, it's fine.
-		keyName := keySection.Key(key).String()
-		keySym := sdl.GetKeyFromName(keyName)
-		if keySym != sdl.K_UNKNOWN {
-			o.Keymap[key] = keySym
+		if keyName := keySection.Key(key).String(); keyName != "" {
+			o.Keymap[key] = keyName
 		}
 	}
+	return err
 }
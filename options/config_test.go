@@ -0,0 +1,128 @@
+package options
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGameConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(path, content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rom := make([]byte, 0x8000)
+	romPath := filepath.Join(dir, "game.gb")
+	write(romPath, string(rom))
+
+	configPath := filepath.Join(dir, "config.ini")
+	write(configPath, "zoom = 3\nfastboot = 1\n")
+
+	defer func(folder string) { GamesFolder = folder }(GamesFolder)
+	GamesFolder = filepath.Join(dir, "games")
+	os.Mkdir(GamesFolder, 0755)
+	write(filepath.Join(GamesFolder, fmt.Sprintf("%x.ini", sha1.Sum(rom))), "zoom = 4\n")
+
+	o := Options{ROMPath: romPath}
+	o.Update(configPath, map[string]bool{})
+	if o.ZoomFactor != 4 {
+		t.Errorf("zoom is %d, want per-game value 4", o.ZoomFactor)
+	}
+	if !o.FastBoot {
+		t.Error("fastboot from base config was not applied")
+	}
+
+	// Command-line flags still win.
+	o = Options{ROMPath: romPath, ZoomFactor: 1}
+	o.Update(configPath, map[string]bool{"zoom": true})
+	if o.ZoomFactor != 1 {
+		t.Errorf("zoom is %d, want command-line value 1", o.ZoomFactor)
+	}
+}
+
+func TestUpdateE(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.ini")
+	if err := ioutil.WriteFile(configPath, []byte("zoom = 3\n[keymap\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := Options{ZoomFactor: 2}
+	if err := o.UpdateE(configPath, map[string]bool{}); err == nil {
+		t.Error("no error for a broken config file")
+	}
+	if o.ZoomFactor != 2 {
+		t.Errorf("zoom is %d after a broken config, want 2", o.ZoomFactor)
+	}
+
+	// Unknown keys and bad values are still fine.
+	if err := ioutil.WriteFile(configPath, []byte("zoom = big\nnope = 1\nfastboot = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.UpdateE(configPath, map[string]bool{}); err != nil {
+		t.Errorf("error for a valid config: %v", err)
+	}
+	if o.ZoomFactor != 2 || !o.FastBoot {
+		t.Error("valid config not applied as expected")
+	}
+
+	if err := o.UpdateE(filepath.Join(dir, "missing.ini"), map[string]bool{}); err == nil {
+		t.Error("no error for a missing config file")
+	}
+}
+
+func TestSwapAB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.ini")
+	if err := ioutil.WriteFile(configPath, []byte("swapab = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys already customized are swapped as well.
+	o := Options{Keymap: DefaultKeymap.Swap("a", "select")}
+	o.Update(configPath, map[string]bool{})
+	if o.Keymap["a"] != DefaultKeymap["b"] || o.Keymap["b"] != DefaultKeymap["select"] {
+		t.Errorf("A and B bound to %v and %v after swapping, want %v and %v",
+			o.Keymap["a"], o.Keymap["b"], DefaultKeymap["b"], DefaultKeymap["select"])
+	}
+	if o.Keymap["start"] != DefaultKeymap["start"] {
+		t.Error("swapping A and B changed Start")
+	}
+
+	// Same thing through UpdateE.
+	o = Options{Keymap: DefaultKeymap}
+	if err := o.UpdateE(configPath, map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if o.Keymap["a"] != DefaultKeymap["b"] || o.Keymap["b"] != DefaultKeymap["a"] {
+		t.Error("A and B not swapped by UpdateE")
+	}
+
+	// Without the option, nothing changes.
+	o = Options{Keymap: DefaultKeymap}
+	o.Update("", map[string]bool{})
+	if o.Keymap["a"] != DefaultKeymap["a"] || o.Keymap["b"] != DefaultKeymap["b"] {
+		t.Error("A and B swapped without -swapab")
+	}
+}
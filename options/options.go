@@ -7,19 +7,67 @@ import (
 
 // Options structure grouping command line flags values.
 type Options struct {
-	BootROM      string // -boot <path>
+	AudioBuffer  uint   // -audiobuffer <frames>
+	Batch        uint   // -batch <dots>
+	Benchmark    uint   // -benchmark <frames>
+	BlankScreen  string // -blankscreen <band|frozen|0-3|path>
+	BootROM      string // -boot <path>[,<path>]
+	Brightness   int64  // -brightness <percent>
+	BreakAt      string // -breakat <labels|addresses>
+	Breakpoint   string // -breakpoint <action>
+	Camera       string // -camera <bars|noise>
+	Contrast     int64  // -contrast <percent>
 	CPUProfile   string // -cpuprofile <path>
 	DebugLevel   string // -level <debug level>
 	DebugModules module // -debug <module>
+	DMAStrict    bool   // -dmastrict
 	Duration     uint   // -cycles <amount>
+	ExitCode     string // -exitcode <serial|address>
 	FastBoot     bool   // -fastboot
+	Flip         string // -flip <none|h|v|hv>
+	FocusPause   bool   // -focuspause
+	ForceAudio   bool   // -forceaudio
+	ForceDMG     bool   // -dmg
 	GIFPath      string // -gif <path>
+	Filename     string // -filename <template>
+	GIFSkip      uint   // -gifskip <frames>
+	Ghosting     uint   // -ghosting <percent>
+	Info         bool   // -info
+	InputScript  string // -input <path>
+	IOTrace      string // -iotrace <all|registers>
+	JPEGQuality  uint   // -jpegquality <1-100>
 	Keymap       Keymap // From config.
+	MBC          string // -mbc <none|mbc1|huc1|huc3|camera>
+	MoviePath    string // -movie <path>
+	NoAudio      bool   // -noaudio
+	OAMBug       bool   // -oambug
+	OpProfile    bool   // -opprofile
+	STATBug      bool   // -statbug
+	PalettePath  string // -palette <path>
+	PaletteWrite string // -palettewrite <latch|ignore|instant>
+	PauseOnInput bool   // -pauseoninput
 	VSync        bool   // -vsync
+	RAMFill      string // -ramfill <default|zero|ff|random>
+	RawFormat    string // -rawformat <rgba|indexed>
+	RawFrames    string // -rawframes <path|->
+	RecordMovie  string // -recordmovie <path>
+	SamplingRate uint   // -samplerate <Hz>
 	ROMPath      string // -rom <path>
+	ROMDir       string // -romdir <path>
 	SaveDir      string // -savedir <path>
+	Seed         int64  // -seed <seed>
 	SavePath     string // -save <full path>
+	Screenshots  string // -screenshot <format>
+	SOCD         string // -socd <raw|neutral|last>
+	SwapAB       bool   // -swapab
+	Symbols      string // -sym <path>
+	TileWatch    bool   // -tilewatch
+	Tint         string // -tint <RRGGBB>
+	TimeLapse    uint   // -timelapse <frames>
+	Turbo        string // -turbo <buttons>
+	TurboRate    uint   // -turborate <frames>
 	WaitKey      bool   // -waitkey
+	WaveInterp   bool   // -waveinterp
 	ZoomFactor   uint   // -zoom <factor>
 }
 
@@ -41,16 +89,64 @@ func (m *module) Set(value string) error {
 }
 
 // Supported command-line options for the emulator.
-var bootROM = flag.String("boot", "bin/boot/dmg_rom.bin", "Full path to boot ROM")
+var audioBuffer = flag.Uint("audiobuffer", 1024, "Audio buffer size in sample frames, a power of two from 256 to 8192 (lower for less lag, higher if sound crackles)")
+var blankScreen = flag.String("blankscreen", "", "What to show while the LCD is off: band, frozen (last frame), a shade from 0 to 3 or an image file (default: shade 0, band in GIFs)")
+var bootROM = flag.String("boot", "bin/boot/dmg_rom.bin", "Full path to boot ROM, or to DMG and CGB boot ROMs separated by a comma (picked from the game's mode)")
+var brightness = flag.Int64("brightness", 0, "Brighten (or darken if negative) output colors by that percentage of full intensity")
+var breakAt = flag.String("breakat", "", "Comma-separated breakpoints by label (see -sym), bank:address or address, triggering the -breakpoint action (default: dump)")
+var breakpoint = flag.String("breakpoint", "", "Action on LD B,B breakpoints: dump, screenshot or halt (default: ignore)")
 var configPath = flag.String("config", "~/.goholint.ini", "Path to custom config file")
+var camera = flag.String("camera", "bars", "Test pattern captured by the Game Boy Camera: bars or noise")
+var contrast = flag.Int64("contrast", 0, "Increase (or decrease if negative, down to -100) output contrast by that percentage")
 var cpuprofile = flag.String("cpuprofile", "", "Write cpu profile to file")
+var opProfile = flag.Bool("opprofile", false, "Count executions and cycles per opcode, printed on exit")
 var duration = flag.Uint("cycles", 0, "Stop after executing that many cycles")
+var exitCode = flag.String("exitcode", "", "With -cycles, exit with the byte at that address (e.g. 0xa000), or 0/1 depending on 'Passed' being sent over 'serial'")
 var debugModules module
-var debugLevel = flag.String("level", "info", "Debug level (-level help for full list)")
+var benchmark = flag.Uint("benchmark", 0, "Run the ROM headless for that many frames as fast as possible, print frames/s and emulated MHz, and exit")
+var batch = flag.Uint("batch", 1, "Advance the PPU that many dots at a time, trading accuracy of mid-line effects for speed")
+var dmaStrict = flag.Bool("dmastrict", false, "Restrict the CPU to I/O registers and HRAM during OAM DMA, like hardware does")
+var debugLevel = flag.String("level", "info", "Debug level, global or per module as in ppu:debug,default:info (-level help for full list)")
 var fastBoot = flag.Bool("fastboot", false, "Bypass boot ROM execution")
+var filename = flag.String("filename", "", "Template for screenshot, GIF and dump file names, with {title}, {date}, {cycle} and {n} (first free number) placeholders (default goholint-{date}-{cycle})")
+var flip = flag.String("flip", "none", "Mirror the display, screenshots and recordings: none, h (horizontally), v (vertically) or hv (both)")
+var focusPause = flag.Bool("focuspause", false, "Pause (and mute) emulation while the window doesn't have focus")
+var forceAudio = flag.Bool("forceaudio", false, "Play all sound channels regardless of their registers (audio debugging)")
+var forceDMG = flag.Bool("dmg", false, "Run CGB-enhanced games in DMG mode")
 var gifPath = flag.String("gif", "", "Record gif file")
+var gifSkip = flag.Uint("gifskip", 0, "Drop that many frames at the start of GIF recordings (e.g. 150 to skip the boot logo)")
+var ghosting = flag.Uint("ghosting", 0, "Blend each frame with that percentage of the previous ones in GIF recordings, like slow DMG LCDs (e.g. 50)")
+var info = flag.Bool("info", false, "Print the ROM's cartridge header details and exit")
+var inputScript = flag.String("input", "", "Replay joypad inputs from a script file (lines of '<frame> <button> press|release')")
+var ioTrace = flag.String("iotrace", "", "Print CPU writes to I/O registers: all, or a comma-separated list of names or addresses (e.g. LCDC,BGP,0xff43)")
+var jpegQuality = flag.Uint("jpegquality", 90, "Quality of JPEG screenshots, from 1 to 100")
+var mbc = flag.String("mbc", "", "Force the cartridge's mapper regardless of its header: none, mbc1, huc1, huc3 or camera (default: from header). mbc2, mbc3 and mbc5 aren't emulated yet and are rejected")
+var moviePath = flag.String("movie", "", "Replay joypad inputs from a movie file recorded with -recordmovie")
+var noAudio = flag.Bool("noaudio", false, "Don't open an audio device, samples are discarded (emulation still runs in real time)")
+var oamBug = flag.Bool("oambug", false, "Emulate DMG OAM corruption on 16-bit inc/dec during OAM search")
+var pauseOnInput = flag.Bool("pauseoninput", false, "Pause at each frame where a replayed movie or input script changes inputs")
+var palettePath = flag.String("palette", "", "Palette file (JASC-PAL or binary .pal) for the four DMG shades")
+var paletteWrite = flag.String("palettewrite", "latch", "When BGP/OBP writes during pixel transfer show: latch (from the next pixel), ignore (dropped) or instant")
+var seed = flag.Int64("seed", 0, "Seed for everything random (e.g. power-up RAM contents), to reproduce runs exactly")
+var screenshots = flag.String("screenshot", "png", "Screenshot file format: png, bmp or jpeg")
+var socd = flag.String("socd", "raw", "How opposing directions pressed at once are seen: raw (both), neutral (neither) or last (latest pressed)")
+var statBug = flag.Bool("statbug", false, "Emulate spurious DMG STAT interrupts when writing to STAT")
+var swapAB = flag.Bool("swapab", false, "Swap the keys bound to the A and B buttons (e.g. for left-handed play)")
+var tileWatch = flag.Bool("tilewatch", false, "Warn when the CPU writes tile data already fetched for the line being drawn (timing debug aid)")
+var rawFormat = flag.String("rawformat", "rgba", "Pixel format for -rawframes: rgba or indexed (shades 0-3)")
+var rawFrames = flag.String("rawframes", "", "Stream raw frames to a file or named pipe (- for stdout) after a 64-byte header, e.g. for ffmpeg")
+var symbolFile = flag.String("sym", "", "Symbol file with labels for breakpoints (default: the ROM's path with a .sym extension)")
+var timeLapse = flag.Uint("timelapse", 0, "Save a numbered PNG screenshot every that many frames (0 to disable)")
+var tint = flag.String("tint", "", "Multiply output colors by this RRGGBB color (e.g. ffe8c0 for a warmer screen)")
+var turbo = flag.String("turbo", "", "Autofire buttons while held, comma-separated (e.g. a,b), toggled with the turbo key")
+var turboRate = flag.Uint("turborate", 2, "Frames between autofire press and release (2 for 15 presses per second)")
 var vSync = flag.Bool("vsync", false, "Force sync to VBlank")
-var romPath = flag.String("rom", "", "ROM file to load")
+var ramFill = flag.String("ramfill", "default", "Power-up RAM contents: default (random VRAM, zeroed RAM), zero, ff or random")
+var recordMovie = flag.String("recordmovie", "", "Record joypad inputs to a movie file")
+var samplingRate = flag.Uint("samplerate", 22050, "Audio output sample rate in Hz (e.g. 44100 or 48000), should match the sound card")
+var romPath = flag.String("rom", "", "ROM file to load (- for standard input)")
+var romDir = flag.String("romdir", "", "Folder listed by the openrom action (default: the current ROM's folder)")
+var waveInterp = flag.Bool("waveinterp", false, "Smooth the wave channel's output to reduce aliasing (not hardware-accurate)")
 var waitKey = flag.Bool("waitkey", false, "Wait for keypress to start CPU (to help with screen captures)")
 var zoomFactor = flag.Uint("zoom", 2, "Zoom factor (default is 2x)")
 
@@ -70,16 +166,64 @@ func Parse() *Options {
 	// value, and then we load parameters from the config but avoid overwriting
 	// any variable that's been explicitly set by a flag.
 	options := Options{
+		Batch:        *batch,
+		Benchmark:    *benchmark,
+		AudioBuffer:  *audioBuffer,
+		BlankScreen:  *blankScreen,
 		BootROM:      *bootROM,
+		Brightness:   *brightness,
+		BreakAt:      *breakAt,
+		Breakpoint:   *breakpoint,
+		Camera:       *camera,
+		Contrast:     *contrast,
 		CPUProfile:   *cpuprofile,
+		OpProfile:    *opProfile,
 		Duration:     *duration,
+		ExitCode:     *exitCode,
 		DebugModules: debugModules,
 		DebugLevel:   *debugLevel,
+		DMAStrict:    *dmaStrict,
 		FastBoot:     *fastBoot,
+		Flip:         *flip,
+		FocusPause:   *focusPause,
+		ForceAudio:   *forceAudio,
+		ForceDMG:     *forceDMG,
 		GIFPath:      *gifPath,
+		Filename:     *filename,
+		GIFSkip:      *gifSkip,
+		Ghosting:     *ghosting,
+		Info:         *info,
+		InputScript:  *inputScript,
+		IOTrace:      *ioTrace,
+		JPEGQuality:  *jpegQuality,
+		MBC:          *mbc,
+		MoviePath:    *moviePath,
+		NoAudio:      *noAudio,
+		OAMBug:       *oamBug,
+		STATBug:      *statBug,
+		PalettePath:  *palettePath,
+		PaletteWrite: *paletteWrite,
+		PauseOnInput: *pauseOnInput,
 		VSync:        *vSync,
+		RAMFill:      *ramFill,
+		RecordMovie:  *recordMovie,
+		SamplingRate: *samplingRate,
 		ROMPath:      *romPath,
+		ROMDir:       *romDir,
+		Screenshots:  *screenshots,
+		Seed:         *seed,
+		SOCD:         *socd,
+		TileWatch:    *tileWatch,
+		RawFormat:    *rawFormat,
+		RawFrames:    *rawFrames,
+		SwapAB:       *swapAB,
+		Symbols:      *symbolFile,
+		Tint:         *tint,
+		TimeLapse:    *timeLapse,
+		Turbo:        *turbo,
+		TurboRate:    *turboRate,
 		WaitKey:      *waitKey,
+		WaveInterp:   *waveInterp,
 		ZoomFactor:   *zoomFactor,
 	}
 
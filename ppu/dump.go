@@ -0,0 +1,74 @@
+package ppu
+
+import (
+	"image"
+	"image/color"
+)
+
+// Dimensions of VRAM dumps. Tile maps are 32×32 tiles, and the 384 tiles in
+// tile data are laid out as 16 columns of 24 rows.
+const (
+	MapSize     = 32 * 8
+	TileColumns = 16
+	TileRows    = 24
+
+	// VRAMView shows both tile maps side by side, followed by tile data.
+	VRAMViewWidth  = 2*MapSize + TileColumns*8
+	VRAMViewHeight = MapSize
+)
+
+// DumpTiles renders all tiles in VRAM (0x8000-0x97ff) shaded with the current
+// background palette, using the given colors for shades 0 to 3.
+func (p *PPU) DumpTiles(palette color.Palette) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, TileColumns*8, TileRows*8), palette)
+	p.drawTiles(img, 0, 0)
+	return img
+}
+
+// DumpBackground renders the full 256×256 tile map at the given address (see
+// BGMap and WindowMap) using the current tile data selection and background
+// palette, with the given colors for shades 0 to 3.
+func (p *PPU) DumpBackground(mapAddr uint16, palette color.Palette) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, MapSize, MapSize), palette)
+	p.drawMap(img, 0, 0, mapAddr)
+	return img
+}
+
+// VRAMView renders both tile maps (0x9800 then 0x9c00) and tile data in a
+// single image, like the VRAM viewer in BGB.
+func (p *PPU) VRAMView(palette color.Palette) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, VRAMViewWidth, VRAMViewHeight), palette)
+	p.drawMap(img, 0, 0, 0x9800)
+	p.drawMap(img, MapSize, 0, 0x9c00)
+	p.drawTiles(img, 2*MapSize, 0)
+	return img
+}
+
+// Draws all 384 tiles with their top-left corner at (x, y).
+func (p *PPU) drawTiles(img *image.Paletted, x, y int) {
+	for i := 0; i < TileColumns*TileRows; i++ {
+		p.drawTile(img, x+i%TileColumns*8, y+i/TileColumns*8, 0x8000+uint16(i)*16)
+	}
+}
+
+// Draws the tile map at the given address with its top-left corner at (x, y).
+func (p *PPU) drawMap(img *image.Paletted, x, y int, mapAddr uint16) {
+	for i := 0; i < 32*32; i++ {
+		tileID := p.Read(mapAddr + uint16(i))
+		p.drawTile(img, x+i%32*8, y+i/32*8, TileAddress(p.LCDC, tileID))
+	}
+}
+
+// Decodes a single tile the same way the fetcher does and draws it with its
+// top-left corner at (x, y).
+func (p *PPU) drawTile(img *image.Paletted, x, y int, tileAddr uint16) {
+	var line [8]uint8
+	for row := uint8(0); row < 8; row++ {
+		p.Fetcher.ReadTileLine(0, tileAddr, row, 0, &line)
+		p.Fetcher.ReadTileLine(1, tileAddr, row, 0, &line)
+		for col, colorIndex := range line {
+			shade := (p.BGP >> (colorIndex << 1)) & 3
+			img.SetColorIndex(x+col, y+int(row), shade)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ppu
+
+import (
+	"image/color"
+	"testing"
+)
+
+var testShades = color.Palette{
+	color.Gray{0xff}, color.Gray{0xaa}, color.Gray{0x55}, color.Gray{0x00},
+}
+
+func TestVRAMView(t *testing.T) {
+	p, _ := newTestPPU()
+	p.BGP = 0xe4 // Identity palette.
+	p.LCDC |= LCDCBGWindowTileDataSelect
+
+	// Clear VRAM, then make tile 1's first line go through all 4 colors
+	// twice, and use that tile as the second tile of the 0x9800 map and the
+	// first of the 0x9c00 one.
+	for addr := uint16(0x8000); addr < 0xa000; addr++ {
+		p.Write(addr, 0)
+	}
+	p.Write(0x8010, 0x33) // 00110011
+	p.Write(0x8011, 0x55) // 01010101
+	p.Write(0x9801, 1)
+	p.Write(0x9c00, 1)
+
+	want := []uint8{0, 2, 1, 3, 0, 2, 1, 3}
+	check := func(name string, pix []uint8) {
+		for i, shade := range want {
+			if pix[i] != shade {
+				t.Errorf("%s: pixel %d is %d, want %d", name, i, pix[i], shade)
+			}
+		}
+	}
+
+	tiles := p.DumpTiles(testShades)
+	check("DumpTiles", tiles.Pix[8:16])
+
+	background := p.DumpBackground(p.WindowMap(), testShades)
+	check("DumpBackground(0x9800)", background.Pix[8:16])
+	background = p.DumpBackground(0x9c00, testShades)
+	check("DumpBackground(0x9c00)", background.Pix[0:8])
+
+	view := p.VRAMView(testShades)
+	check("VRAMView 0x9800", view.Pix[8:16])
+	check("VRAMView 0x9c00", view.Pix[MapSize:MapSize+8])
+	check("VRAMView tiles", view.Pix[2*MapSize+8:2*MapSize+16])
+
+	// Shades go through BGP.
+	p.BGP = 0x1b // Inverted.
+	tiles = p.DumpTiles(testShades)
+	if tiles.Pix[11] != 0 {
+		t.Errorf("inverted color 3 has shade %d, want 0", tiles.Pix[11])
+	}
+}
@@ -8,16 +8,25 @@ import (
 // Fetcher reads tile data from VRAM and pushes pixels to PPU FIFO.
 type Fetcher struct {
 	Enabled         bool
+	ClockFactor     int // Dots taken by each step (2 by default)
 	fifo            *FIFO
 	vRAM            memory.Addressable
 	ticks           int
 	state, oldState states.State
-	lcdc            *uint8 // Reference to LCDC for sprites height bit
+	lcdc            *uint8 // Reference to LCDC for tile data area and sprites height
+	priority        *ObjectPriority
+	hidden          *Layer // Reference to layers hidden for debugging
 	mapAddr         uint16 // Start address of BG/Windows map row
-	dataAddr        uint16 // Start address of Sprite/BG tile data
 	tileOffset      uint8  // X offset in the tile map row (will wrap around)
 	tileLine        uint8  // Y offset (in pixels) in the tile
-	signedID        bool
+
+	// Background fetches follow scroll registers as they change (see
+	// StartBackground).
+	scroll    bool
+	scx, scy  *uint8 // References to scroll registers
+	ly        *uint8 // Reference to current line
+	mapBase   uint16 // Start address of the BG map
+	tileCount uint8  // Tiles fetched since the start of the line
 
 	tileID   uint8
 	tileData [8]uint8
@@ -28,19 +37,37 @@ type Fetcher struct {
 	spriteOffset uint8 // X offset for sprite (if not fully on screen)
 	spriteLine   uint8 // Y offset (in pixels) in the sprite
 	spriteData   [8]uint8
+
+	// Line on which each tile data byte was last read, numbered from 1 and
+	// only kept when watching tile writes (see PPU.WatchTileWrites).
+	fetched []uint32
+	line    uint32
 }
 
 // Start fetching a line of pixels from the given tile in the given tilemap
-// address space when Tick() is called.
-func (f *Fetcher) Start(mapAddr, dataAddr uint16, tileOffset, tileLine uint8, signedID bool) {
-	f.mapAddr, f.dataAddr = mapAddr, dataAddr
+// address space when Tick() is called. Tile data is read from the area
+// selected in LCDC at the time each tile is fetched (see TileAddress).
+func (f *Fetcher) Start(mapAddr uint16, tileOffset, tileLine uint8) {
+	f.mapAddr = mapAddr
 	f.tileOffset, f.tileLine = tileOffset, tileLine
-	f.signedID = signedID
+	f.scroll = false
 	f.state = states.ReadTileID
 	f.Enabled = true
 	f.fifo.Clear()
 }
 
+// StartBackground starts fetching a line of background pixels from the given
+// tilemap when Tick() is called. Unlike Start, the tile row and column are
+// computed from SCX, SCY and LY whenever a tile ID is read, so that scroll
+// changes take effect from the next tile fetch rather than the next line.
+func (f *Fetcher) StartBackground(mapBase uint16) {
+	f.Start(mapBase, 0, 0)
+	f.line++
+	f.mapBase = mapBase
+	f.tileCount = 0
+	f.scroll = true
+}
+
 // FetchSprite pauses the current fetching state to read sprite data and mix it
 // in the pixel FIFO.
 func (f *Fetcher) FetchSprite(sprite Sprite, spriteOffset, spriteLine uint8) {
@@ -56,7 +83,7 @@ func (f *Fetcher) Tick() {
 		return
 	}
 	f.ticks++
-	if f.ticks < ClockFactor {
+	if f.ticks < f.ClockFactor {
 		return
 	}
 
@@ -65,40 +92,53 @@ func (f *Fetcher) Tick() {
 
 	switch f.state {
 	case states.ReadTileID:
+		if f.scroll {
+			y := *f.scy + *f.ly
+			f.tileLine = y % 8
+			f.mapAddr = f.mapBase + uint16(y/8)*32
+			f.tileOffset = (*f.scx/8 + f.tileCount) % 32
+		}
 		f.tileID = f.vRAM.Read(f.mapAddr + uint16(f.tileOffset))
 		f.state = states.ReadTileData0
 		//logger.Printf("fetcher", "%04x: %02x\n", f.mapAddr+uint(f.tileOffset), f.tileID)
 
 	case states.ReadTileData0:
-		f.ReadTileLine(0, f.dataAddr, f.tileID, f.signedID, f.tileLine, 0, &f.tileData)
+		f.ReadTileLine(0, TileAddress(*f.lcdc, f.tileID), f.tileLine, 0, &f.tileData)
 		f.state = states.ReadTileData1
 
 	case states.ReadTileData1:
-		f.ReadTileLine(1, f.dataAddr, f.tileID, f.signedID, f.tileLine, 0, &f.tileData)
+		f.ReadTileLine(1, TileAddress(*f.lcdc, f.tileID), f.tileLine, 0, &f.tileData)
 		f.state = states.PushToFIFO
 
 	case states.PushToFIFO:
 		if f.fifo.Size() <= 8 {
 			for i := 0; i < 8; i++ {
-				f.fifo.Push(Pixel{f.tileData[i], PixelBGP})
+				f.fifo.Push(Pixel{Color: f.tileData[i], Palette: PixelBGP})
 			}
 			f.tileOffset = (f.tileOffset + 1) % 32
+			f.tileCount++
 			f.state = states.ReadTileID
 		}
 	case states.ReadSpriteID:
-		f.spriteID = f.vRAM.Read(f.sprite.Address + 2) // We already read X&Y
+		f.spriteID = f.sprite.OBJ(f.vRAM).Tile() // We already read X&Y
+		if *f.lcdc&LCDCSpriteSize != 0 {
+			// [PANDOCS] In 8x16 mode, bit 0 of the tile index is ignored:
+			// the top half is tile ID&0xfe and the bottom half ID|0x01. The
+			// sprite line then carries over to the second tile on its own.
+			f.spriteID &= 0xfe
+		}
 		f.state = states.ReadSpriteFlags
 
 	case states.ReadSpriteFlags:
-		f.spriteFlags = f.vRAM.Read(f.sprite.Address + 3)
+		f.spriteFlags = f.sprite.OBJ(f.vRAM).Flags()
 		f.state = states.ReadSpriteData0
 
 	case states.ReadSpriteData0:
-		f.ReadTileLine(0, 0x8000, f.spriteID, false, f.spriteLine, f.spriteFlags, &f.spriteData)
+		f.ReadTileLine(0, SpriteTileAddress(f.spriteID), f.spriteLine, f.spriteFlags, &f.spriteData)
 		f.state = states.ReadSpriteData1
 
 	case states.ReadSpriteData1:
-		f.ReadTileLine(1, 0x8000, f.spriteID, false, f.spriteLine, f.spriteFlags, &f.spriteData)
+		f.ReadTileLine(1, SpriteTileAddress(f.spriteID), f.spriteLine, f.spriteFlags, &f.spriteData)
 		f.state = states.MixInFIFO
 
 	case states.MixInFIFO:
@@ -106,37 +146,48 @@ func (f *Fetcher) Tick() {
 			break
 		}
 
+		// Sprites are still fetched while hidden so that timing doesn't
+		// change, they're just not mixed in.
+		if *f.hidden&LayerSprites != 0 {
+			f.state = f.oldState
+			break
+		}
+
 		// Mix sprite pixels with FIFO, taking into account offset if sprite
 		// is only partially displayed (i.e. entering screen from the left).
 		var palette uint8
-		if f.spriteFlags&0x10 == 0 {
+		if f.spriteFlags&SpritePalette == 0 {
 			palette = PixelOBP0
 		} else {
 			palette = PixelOBP1
 		}
+		oamIndex := uint8(f.sprite.OBJ(f.vRAM).Index())
 		for i := int(f.spriteOffset); i < 8; i++ {
-			f.fifo.Mix(i-int(f.spriteOffset), Pixel{f.spriteData[i], palette})
+			pixel := Pixel{Color: f.spriteData[i], Palette: palette, OAMIndex: oamIndex}
+			f.fifo.Mix(i-int(f.spriteOffset), pixel, f.priority.OAMOrder)
 		}
 		f.state = f.oldState
 	}
 }
 
+// SpriteTileAddress returns the address in VRAM of a sprite tile's data given
+// its ID. Sprites always use unsigned IDs from 0x8000.
+func SpriteTileAddress(tileID uint8) uint16 {
+	return 0x8000 + uint16(tileID)*16
+}
+
 // ReadTileLine updates internal pixel buffer with LSB or MSB tile line
-// depending on current state.
-func (f *Fetcher) ReadTileLine(bitPlane uint8, tileDataAddr uint16, tileID uint8, signedID bool, tileLine uint8, flags uint8, data *[8]uint8) {
-	var offset uint16
-	if signedID {
-		offset = uint16(int16(tileDataAddr) + int16(int8(tileID))*16)
-	} else {
-		offset = tileDataAddr + (uint16(tileID) * 16)
-	}
+// depending on current state, for the tile whose data starts at tileAddr (see
+// TileAddress and SpriteTileAddress).
+func (f *Fetcher) ReadTileLine(bitPlane uint8, tileAddr uint16, tileLine uint8, flags uint8, data *[8]uint8) {
 	if flags&SpriteFlipY != 0 {
 		// If flipping, get line at (spriteSize-1-line)
 		height := uint8(8<<((*f.lcdc&LCDCSpriteSize)>>2) - 1)
 		tileLine = height - tileLine
 	}
-	addr := offset + (uint16(tileLine) * 2)
+	addr := tileAddr + (uint16(tileLine) * 2)
 
+	f.fetchedTileData(addr + uint16(bitPlane))
 	pixelData := f.vRAM.Read(addr + uint16(bitPlane))
 	for bitPos := 7; bitPos >= 0; bitPos-- {
 		var pixelIndex uint
@@ -50,7 +50,12 @@ func (f *FIFO) Size() int {
 
 // Mix sprite pixel data in the lower half of the FIFO. Priorities are hard to
 // understand in most docs I've seen, so this is empirical at best.
-func (f *FIFO) Mix(offset int, pixel Pixel) {
+//
+// Sprites are mixed in the order they're fetched, i.e. by X coordinate then
+// OAM index, so a sprite pixel only replaces background pixels unless oamOrder
+// is set (see ObjectPriority), in which case it also replaces pixels from
+// sprites further in OAM.
+func (f *FIFO) Mix(offset int, pixel Pixel, oamOrder bool) {
 	index := (f.out + offset) % len(f.fifo)
 	current := f.fifo[index]
 
@@ -61,8 +66,10 @@ func (f *FIFO) Mix(offset int, pixel Pixel) {
 
 	// Mix pixel in if the current one is from the background.
 	// TODO: OBJ-BG priority attribute bit.
-	// TODO: OAM index, possibly needed in Pixel struct.
-	if current.Palette == PixelBGP {
+	switch {
+	case current.Palette == PixelBGP:
+		f.fifo[index] = pixel
+	case oamOrder && pixel.OAMIndex < current.OAMIndex:
 		f.fifo[index] = pixel
 	}
 }
@@ -0,0 +1,37 @@
+package ppu
+
+// Layer flags for hiding parts of the picture while debugging rendering (see
+// PPU.Hidden).
+type Layer uint8
+
+// Layers composited by the PPU.
+const (
+	LayerBackground Layer = 1 << iota
+	LayerWindow
+	LayerSprites
+)
+
+// ToggleLayer hides or shows the given layers and returns whether they're now
+// shown.
+func (p *PPU) ToggleLayer(layer Layer) bool {
+	p.Hidden ^= layer
+	return p.Hidden&layer == 0
+}
+
+// Returns the color index to display for a pixel from the FIFO: the lightest
+// shade if it belongs to a hidden background or window layer. Sprites are
+// left out when mixing them in instead (see Fetcher.hidden), which lets what's
+// behind them show.
+func (p *PPU) visible(pixel Pixel, color uint8) uint8 {
+	if pixel.Palette != PixelBGP {
+		return color
+	}
+	layer := LayerBackground
+	if p.window {
+		layer = LayerWindow
+	}
+	if p.Hidden&layer != 0 {
+		return 0
+	}
+	return color
+}
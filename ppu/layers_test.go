@@ -0,0 +1,78 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/lazy-stripes/goholint/interrupts"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+func TestHiddenLayers(t *testing.T) {
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.BGP = 0xe4
+	p.OBP0 = 0xe4
+
+	// The background is solid color 2 (tile 2), with a sprite of solid
+	// color 1 (tile 1) in the top left corner.
+	p.LoadTiles(0x8000, make([]byte, 0x1800))
+	map0 := make([]byte, 0x400)
+	for i := range map0 {
+		map0[i] = 2
+	}
+	p.LoadMap(0x9800, map0)
+	for row := uint16(0); row < 8; row++ {
+		p.Write(0x8010+row*2, 0xff)
+		p.Write(0x8021+row*2, 0xff)
+	}
+	for i := 0; i < OBJCount; i++ {
+		p.OBJ(i).SetY(0) // Off-screen
+	}
+	obj := p.OBJ(0)
+	obj.SetY(16)
+	obj.SetX(8)
+	obj.SetTile(1)
+
+	lcdc := LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect |
+		LCDCSpriteDisplayEnable
+	p.LCDC = lcdc
+	frame := func() []uint8 {
+		for frames := display.Frames; display.Frames == frames; {
+			p.Tick()
+		}
+		return display.Frame[:screen.ScreenWidth]
+	}
+
+	if line := frame(); line[0] != 1 || line[8] != 2 {
+		t.Fatalf("line starts with shades %v, want sprite then background", line[:9])
+	}
+
+	if p.ToggleLayer(LayerSprites) {
+		t.Error("sprite layer still shown after toggling")
+	}
+	for x, shade := range frame()[:8] {
+		if shade != 2 {
+			t.Errorf("pixel %d has shade %d with sprites hidden, want background 2",
+				x, shade)
+		}
+	}
+
+	p.ToggleLayer(LayerBackground)
+	for x, shade := range frame()[:16] {
+		if shade != 0 {
+			t.Errorf("pixel %d has shade %d with all layers hidden, want 0", x, shade)
+		}
+	}
+	if p.LCDC != lcdc {
+		t.Error("hiding layers changed LCDC")
+	}
+
+	if !p.ToggleLayer(LayerSprites) {
+		t.Error("sprite layer still hidden after toggling")
+	}
+	if line := frame(); line[0] != 1 || line[8] != 0 {
+		t.Errorf("line starts with shades %v, want sprite on blank background", line[:9])
+	}
+}
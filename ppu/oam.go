@@ -28,10 +28,11 @@ func (o *OAM) Start() {
 
 // Tick advances OAM search one step and returns true when the search is over.
 func (o *OAM) Tick() (done bool) {
-	o.sprite.Address = AddrOAM + uint16(o.index*4)
+	obj := OBJ{Address: AddrOAM + uint16(o.index)*objSize, mem: o.ram}
+	o.sprite.Address = obj.Address
 	switch o.state {
 	case states.ReadSpriteY:
-		o.sprite.Y = o.ram.Read(o.sprite.Address)
+		o.sprite.Y = obj.Y()
 		o.state = states.ReadSpriteX
 	case states.ReadSpriteX:
 		// Sprite table must have room left, and current sprite tile must
@@ -58,7 +59,7 @@ func (o *OAM) Tick() (done bool) {
 		//              |
 		//              .
 		//             144
-		o.sprite.X = o.ram.Read(o.sprite.Address + 1)
+		o.sprite.X = obj.X()
 		if o.sprite.X != 0 {
 			y := *o.ly + 16
 			if o.sprite.Y <= y && o.sprite.Y+height > y {
@@ -70,5 +71,5 @@ func (o *OAM) Tick() (done bool) {
 		o.index++
 	}
 
-	return o.index >= 40
+	return o.index >= OBJCount
 }
@@ -0,0 +1,44 @@
+package ppu
+
+import "github.com/lazy-stripes/goholint/ppu/states"
+
+// Source: [PANDOCS] https://gbdev.io/pandocs/OAM_Corruption_Bug.html
+
+// Size of an OAM row (two sprites) as accessed by the PPU during OAM search.
+const oamRowSize = 8
+
+// CorruptOAM emulates the DMG OAM corruption bug triggered when the CPU
+// increments or decrements a 16-bit register holding an address in the
+// 0xfe00-0xfeff range while the PPU is searching OAM. Only the basic write
+// corruption pattern is implemented, and only if OAMBug is set.
+func (p *PPU) CorruptOAM(addr uint16) {
+	if !p.OAMBug || addr < AddrOAM || addr > 0xfeff {
+		return
+	}
+	if !p.LCD.Enabled() || p.state != states.OAMSearch {
+		return
+	}
+
+	// The PPU reads one row (two sprites) per CPU cycle. The first row is
+	// never corrupted.
+	row := int(p.OAM.index / 2)
+	if row == 0 || row >= len(p.oamRAM.Bytes)/oamRowSize {
+		return
+	}
+	corruptOAMRow(p.oamRAM.Bytes, row)
+}
+
+// Applies the write corruption pattern to the given OAM row: its first word
+// is mangled with the previous row's first and third words, and the other
+// three words are copied from the previous row.
+func corruptOAMRow(oam []uint8, row int) {
+	cur, prev := row*oamRowSize, (row-1)*oamRowSize
+	word := func(offset int) uint16 {
+		return uint16(oam[offset]) | uint16(oam[offset+1])<<8
+	}
+
+	a, b, c := word(cur), word(prev), word(prev+4)
+	value := ((a ^ c) & (b ^ c)) ^ c
+	oam[cur], oam[cur+1] = uint8(value), uint8(value>>8)
+	copy(oam[cur+2:cur+oamRowSize], oam[prev+2:prev+oamRowSize])
+}
@@ -0,0 +1,82 @@
+package ppu
+
+import (
+	"fmt"
+
+	"github.com/lazy-stripes/goholint/ppu/states"
+)
+
+// PaletteWrites decides when BGP, OBP0 and OBP1 writes made during pixel
+// transfer (mode 3) become visible. Pixels are always colored as they're
+// shifted out to the LCD, so pixels already on screen are never affected.
+type PaletteWrites uint8
+
+// Supported palette write modes.
+const (
+	PaletteWritesLatch   PaletteWrites = iota // Latched until the next pixel has been shifted out
+	PaletteWritesIgnore                       // Dropped, some emulators do that to work around timing
+	PaletteWritesInstant                      // Visible from the very next pixel
+)
+
+// ParsePaletteWrites returns the palette write mode with the given name:
+// latch, ignore or instant.
+func ParsePaletteWrites(name string) (PaletteWrites, error) {
+	switch name {
+	case "latch":
+		return PaletteWritesLatch, nil
+	case "ignore":
+		return PaletteWritesIgnore, nil
+	case "instant":
+		return PaletteWritesInstant, nil
+	}
+	return PaletteWritesLatch, fmt.Errorf("unknown palette write mode %s", name)
+}
+
+// Palette register write waiting for a pixel boundary.
+type paletteLatch struct {
+	reg     *uint8
+	value   uint8
+	pending bool
+}
+
+// Returns the palette register mapped at the given address, or nil.
+func (p *PPU) paletteRegister(addr uint16) *uint8 {
+	switch addr {
+	case AddrBGP:
+		return &p.BGP
+	case AddrOBP0:
+		return &p.OBP0
+	case AddrOBP1:
+		return &p.OBP1
+	}
+	return nil
+}
+
+// Handles a CPU write to a palette register according to PaletteWrites.
+// Returns false if the write should go through right away.
+func (p *PPU) writePalette(addr uint16, value uint8) bool {
+	reg := p.paletteRegister(addr)
+	if reg == nil || !p.LCD.Enabled() || p.state != states.PixelTransfer {
+		return false
+	}
+
+	switch p.PaletteWrites {
+	case PaletteWritesLatch:
+		// Flush any write still waiting, in case it was to another register.
+		p.applyPalette()
+		p.latch = paletteLatch{reg: reg, value: value, pending: true}
+		return true
+	case PaletteWritesIgnore:
+		log.Debugf("ignored palette write 0x%04x=0x%02x at x=%d", addr, value, p.x)
+		return true
+	}
+	return false
+}
+
+// Applies a latched palette write, if any.
+func (p *PPU) applyPalette() {
+	if p.latch.pending {
+		*p.latch.reg = p.latch.value
+		p.latch.pending = false
+	}
+}
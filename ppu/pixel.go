@@ -7,8 +7,10 @@ const (
 	PixelOBP1 = 2
 )
 
-// Pixel holding its color index and palette to be used in our FIFO.
+// Pixel holding its color index and palette to be used in our FIFO, and for
+// sprites, the index of the sprite in OAM.
 type Pixel struct {
-	Color   uint8
-	Palette uint8
+	Color    uint8
+	Palette  uint8
+	OAMIndex uint8
 }
@@ -11,7 +11,6 @@ import (
 	"github.com/lazy-stripes/goholint/memory"
 	"github.com/lazy-stripes/goholint/ppu/states"
 	"github.com/lazy-stripes/goholint/screen"
-	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Package-wide logger.
@@ -22,10 +21,6 @@ func init() {
 	log.Add("ticks", "ticks taken per PPU phase (Desperate only)")
 }
 
-// ClockFactor representing the number of ticks taken by each step (base is 4).
-// Used in Fetcher's Tick() method.
-var ClockFactor = 2
-
 // Register addresses.
 const (
 	AddrLCDC = 0xff40
@@ -79,6 +74,38 @@ type PPU struct {
 	BGP        uint8
 	OBP0, OBP1 uint8
 
+	// OAMBug enables emulation of the DMG OAM corruption bug (see CorruptOAM).
+	OAMBug bool
+
+	// STATBug enables emulation of spurious STAT interrupts on DMG when
+	// writing to STAT (see statWriteBug).
+	STATBug bool
+
+	// PaletteWrites decides when palette writes made during pixel transfer
+	// become visible (see PaletteWrites).
+	PaletteWrites PaletteWrites
+
+	// Priority holds the CGB OPRI register, to be mapped in CGB mode only.
+	Priority ObjectPriority
+
+	// Hidden layers are left out of the output, without touching LCDC or
+	// affecting timing (see ToggleLayer).
+	Hidden Layer
+
+	// OnVBlank, if set, is called once per frame when entering VBlank, or at
+	// the equivalent rate while the LCD is off.
+	OnVBlank func()
+
+	// OnHBlank, if set, is called with the current line at the start of each
+	// HBlank, e.g. to schedule per-line work such as HDMA transfers.
+	OnHBlank func(ly uint8)
+
+	// OnTileWrite, if set, is called with the address and current line when
+	// the CPU writes tile data fetched for that line (see WatchTileWrites).
+	OnTileWrite func(addr uint16, ly uint8)
+
+	oamRAM *memory.RAM
+
 	ticks int
 	state states.State
 
@@ -86,15 +113,22 @@ type PPU struct {
 	x      uint8
 	window bool // True if window fetch in progress
 
+	// Internal window line counter, only incremented on lines where the
+	// window was actually drawn, so that hiding it mid-frame (e.g. with WX
+	// off-screen) resumes it where it left off.
+	windowLine uint8
+
 	// Quick and dirty mapping of PixelPalette index to palette register
 	// for quick access when pushing pixels to LCD.
 	palettes [3]*uint8
+	latch    paletteLatch // Palette write waiting for a pixel boundary
 
 	frames uint // DEBUG for counting
 }
 
-// New PPU instance.
-func New(display screen.Display) *PPU {
+// New PPU instance, with VRAM and OAM contents following the given fill
+// pattern (nil for the default).
+func New(display screen.Display, fill *memory.Fill) *PPU {
 	p := PPU{MMU: memory.NewEmptyMMU(), LCD: display}
 	p.Add(memory.Registers{
 		AddrLCDC: &p.LCDC,
@@ -110,15 +144,18 @@ func New(display screen.Display) *PPU {
 		AddrWX:   &p.WX,
 	})
 
-	videoRAM := memory.NewVRAM(0x8000, 0x2000)
-	oamRAM := memory.NewRAM(AddrOAM, 0xa0)
+	videoRAM := memory.NewVRAM(0x8000, 0x2000, fill)
+	oamRAM := memory.NewFilledRAM(AddrOAM, 0xa0, fill)
 
 	p.Add(videoRAM)
 	p.Add(oamRAM)
 
-	p.Fetcher = Fetcher{fifo: &p.FIFO, vRAM: p.MMU, lcdc: &p.LCDC}
+	p.Fetcher = Fetcher{fifo: &p.FIFO, vRAM: p.MMU, lcdc: &p.LCDC,
+		priority: &p.Priority, scx: &p.SCX, scy: &p.SCY, ly: &p.LY,
+		hidden: &p.Hidden, ClockFactor: 2}
 	p.OAM = OAM{Sprites: make([]Sprite, 0, 10), ram: oamRAM, ly: &p.LY,
 		lcdc: &p.LCDC}
+	p.oamRAM = oamRAM
 
 	p.palettes = [3]*uint8{&p.BGP, &p.OBP0, &p.OBP1}
 	p.state = states.OAMSearch
@@ -126,9 +163,26 @@ func New(display screen.Display) *PPU {
 	return &p
 }
 
+// Mode returns the PPU's current state, which is also its STAT mode (HBlank
+// while the LCD is off).
+func (p *PPU) Mode() states.State {
+	return p.state
+}
+
+// Dot returns the position of the PPU within the current scanline, from 0 to
+// 455, as the number of dots elapsed since the line started. Always 0 while
+// the LCD is off.
+func (p *PPU) Dot() int {
+	if !p.LCD.Enabled() {
+		return 0
+	}
+	return p.ticks
+}
+
 // String returns a human-readable representation of the PPU's current state.
 func (p *PPU) String() string {
 	var b bytes.Buffer
+	fmt.Fprintf(&b, "Mode: %d\n", p.state)
 	fmt.Fprintf(&b, "LCDC: %#02x\n", p.LCDC)
 	fmt.Fprintf(&b, "STAT: %#02x\n", p.STAT)
 	fmt.Fprintf(&b, "SCY:  %#02x\n", p.SCY)
@@ -157,12 +211,21 @@ func (p *PPU) Write(addr uint16, value uint8) {
 	switch addr {
 	case AddrSTAT:
 		log.Debugf("PPU.Write(0x%04x[STAT], 0x%02x)", addr, value)
+		if p.STATBug {
+			p.statWriteBug()
+		}
 		p.STAT = value & 0xf8
 	case AddrLY:
 		// [PANDOCS] says writing to it "resets counter"?
 		log.Debugf("PPU.Write(0x%04x[LY], 0x%02x)", addr, value)
 		log.Warning("Write to LY. What do?")
 	default:
+		if p.writePalette(addr, value) {
+			return
+		}
+		if p.Fetcher.fetched != nil {
+			p.checkTileWrite(addr)
+		}
 		p.MMU.Write(addr, value)
 	}
 }
@@ -182,8 +245,8 @@ func (p *PPU) Read(addr uint16) uint8 {
 	return p.MMU.Read(addr)
 }
 
-// Tick advances the CPU state one step. Return whether we reached VBlank so
-// that event polling can happen then.
+// Tick advances the PPU state one dot, i.e. one machine tick, making the PPU a
+// clock.Ticker.
 func (p *PPU) Tick() {
 	p.Cycle++
 	p.ticks++
@@ -195,6 +258,9 @@ func (p *PPU) Tick() {
 			if p.ticks%(456*153) == 0 {
 				log.Sub("ticks").Desperatef("Disabled: %d ticks", 456*153)
 				p.LCD.VBlank()
+				if p.OnVBlank != nil {
+					p.OnVBlank()
+				}
 			}
 		} else {
 			p.OAM.Start()
@@ -207,6 +273,8 @@ func (p *PPU) Tick() {
 			// Disable LCD. Clean up internal state.
 			p.LY = 0
 			p.x = 0
+			p.windowLine = 0
+			p.applyPalette()
 			// [TCAFBD] STAT mode flag is zero when LCD is off.
 			p.state = 0
 			p.LCD.Disable()
@@ -221,13 +289,10 @@ func (p *PPU) Tick() {
 	case states.OAMSearch:
 		// Tick will return true when all OAM space has been searched.
 		if p.OAM.Tick() {
-			// Initialize fetcher for background.
-			y := p.SCY + p.LY
-			tileLine := y % 8
-			tileOffset := p.SCX / 8
-			tileMapRowAddr := p.BGMap() + (uint16(y/8) * 32)
-			tileDataAddr, signedID := p.TileData()
-			p.Fetcher.Start(tileMapRowAddr, tileDataAddr, tileOffset, tileLine, signedID)
+			// Initialize fetcher for background. Only the fine X scroll is
+			// latched for the whole line, the fetcher reads SCX, SCY and the
+			// tile data area for each tile.
+			p.Fetcher.StartBackground(p.BGMap())
 
 			p.x = 0
 			p.toDrop = p.SCX % 8
@@ -252,15 +317,17 @@ func (p *PPU) Tick() {
 		if !p.window && p.LCDC&LCDCWindowDisplayEnable > 0 &&
 			p.LY >= p.WY && p.x+7 >= p.WX {
 			p.window = true
-			p.toDrop = 0 // Window doesn't scroll
 
-			// Reinitialize fetcher for window.
-			y := p.LY - p.WY
+			// Reinitialize fetcher for window. It normally starts at its
+			// first column, except with WX<7 where it's partly off-screen,
+			// or if WX was moved to the left of the current pixel.
+			y := p.windowLine
+			column := p.x + 7 - p.WX
+			p.toDrop = column % 8
 			tileLine := y % 8
-			tileOffset := (p.x - p.WX + 7) / 8
+			tileOffset := column / 8
 			tileMapRowAddr := p.WindowMap() + (uint16(y/8) * 32)
-			tileDataAddr, signedID := p.TileData()
-			p.Fetcher.Start(tileMapRowAddr, tileDataAddr, tileOffset, tileLine, signedID)
+			p.Fetcher.Start(tileMapRowAddr, tileOffset, tileLine)
 			return
 		}
 
@@ -302,10 +369,17 @@ func (p *PPU) Tick() {
 
 		p.x += p.Pop()
 		if p.x == 160 {
+			if p.window {
+				p.windowLine++
+			}
 			p.window = false
+			p.applyPalette()
 			p.LCD.HBlank()
 			p.state = states.HBlank
 			p.RequestLCDInterrupt(interrupts.STATMode0)
+			if p.OnHBlank != nil {
+				p.OnHBlank(p.LY)
+			}
 
 			log.Sub("ticks").Desperatef("Pixel Transfer: %d ticks", p.ticks)
 		}
@@ -320,11 +394,15 @@ func (p *PPU) Tick() {
 			p.setLY(p.LY + 1)
 			if p.LY == 144 {
 				p.frames++
-				sdl.Do(p.LCD.VBlank) // Keep GPU stuff in OS thread.
+				p.windowLine = 0
+				p.LCD.VBlank()
 				p.state = states.VBlank
 				p.RequestLCDInterrupt(interrupts.STATMode1)
 
 				p.Interrupts.Request(interrupts.VBlank)
+				if p.OnVBlank != nil {
+					p.OnVBlank()
+				}
 			} else {
 				// Prepare to go back to OAM search state.
 				p.OAM.Start()
@@ -354,6 +432,63 @@ func (p *PPU) Tick() {
 	}
 }
 
+// TickN advances the PPU n dots, with the same result as calling Tick n times.
+// Dots where the PPU is only waiting (HBlank, VBlank or while the LCD is off)
+// are skipped in one go instead of being ticked one at a time.
+func (p *PPU) TickN(n int) {
+	for n > 0 {
+		if idle := p.idleTicks(); idle > 0 {
+			if idle > n {
+				idle = n
+			}
+			p.Cycle += idle
+			p.ticks += idle
+			n -= idle
+			continue
+		}
+		p.Tick()
+		n--
+	}
+}
+
+// Returns how many ticks would have no other effect than counting dots, given
+// that registers don't change in the meantime.
+func (p *PPU) idleTicks() int {
+	enabled := p.LCDC&LCDCDisplayEnable != 0
+	if !p.LCD.Enabled() {
+		if enabled {
+			return 0 // Turning on at next tick.
+		}
+		// Waiting for the next disabled screen refresh.
+		const refresh = 456 * 153
+		return refresh - p.ticks%refresh - 1
+	}
+	if !enabled {
+		return 0 // Turning off at next tick.
+	}
+
+	// Waiting for the end of the line, or LY wrapping back to 0 on line 153.
+	end := 456
+	switch p.state {
+	case states.HBlank:
+	case states.VBlank:
+		if p.LY == 153 && p.ticks < 4 {
+			end = 4
+		}
+	default:
+		return 0
+	}
+	if idle := end - p.ticks - 1; idle > 0 {
+		return idle
+	}
+	return 0
+}
+
+// OBJ returns a typed view over the OAM entry at the given index (0-39).
+func (p *PPU) OBJ(index int) OBJ {
+	return OBJ{Address: AddrOAM + uint16(index)*objSize, mem: p.oamRAM}
+}
+
 // mapAddress returns the base address for BG or Window map according to LCDC.
 func (p *PPU) mapAddress(bit uint8) uint16 {
 	if p.LCDC&bit != 0 {
@@ -372,12 +507,14 @@ func (p *PPU) WindowMap() uint16 {
 	return p.mapAddress(LCDCWindowTileMapDisplayeSelect)
 }
 
-// TileData returns the base address of the background or window tile data in VRAM.
-func (p *PPU) TileData() (addr uint16, signedID bool) {
-	if (p.LCDC & LCDCBGWindowTileDataSelect) != 0 {
-		return 0x8000, false
+// TileAddress returns the address in VRAM of a background or window tile's
+// data given its ID and the LCDC value at the time it's fetched. Depending on
+// LCDC bit 4, IDs are either unsigned from 0x8000 or signed from 0x9000.
+func TileAddress(lcdc, tileID uint8) uint16 {
+	if lcdc&LCDCBGWindowTileDataSelect != 0 {
+		return 0x8000 + uint16(tileID)*16
 	}
-	return 0x9000, true
+	return uint16(0x9000 + int(int8(tileID))*16)
 }
 
 // Pop tries shifting a pixel out of the FIFO to the LCD and returns the
@@ -398,7 +535,8 @@ func (p *PPU) pop(drop bool) uint8 {
 			palette := *p.palettes[pixel.Palette]
 			// This was shamefully taken from coffee-gb.
 			color := (palette >> (pixel.Color << 1)) & 3
-			p.LCD.Write(color)
+			p.LCD.Write(p.visible(pixel, color))
+			p.applyPalette()
 		}
 		return 1
 	}
@@ -412,3 +550,15 @@ func (p *PPU) RequestLCDInterrupt(interrupt uint8) {
 		p.Interrupts.Request(interrupts.LCDStat)
 	}
 }
+
+// [PANDOCS] On DMG, writing to STAT briefly acts as if all STAT interrupt
+// sources were enabled, which triggers an interrupt during HBlank, VBlank or
+// when LY=LYC, whatever value is actually written.
+func (p *PPU) statWriteBug() {
+	if !p.LCD.Enabled() {
+		return
+	}
+	if p.state == states.HBlank || p.state == states.VBlank || p.LY == p.LYC {
+		p.Interrupts.Request(interrupts.LCDStat)
+	}
+}
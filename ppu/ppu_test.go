@@ -0,0 +1,783 @@
+package ppu
+
+import (
+	"bytes"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazy-stripes/goholint/clock"
+	"github.com/lazy-stripes/goholint/interrupts"
+	"github.com/lazy-stripes/goholint/ppu/states"
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// Display keeping track of what the PPU sent it.
+type testDisplay struct {
+	enabled bool
+	pixels  int
+	hblanks int
+	vblanks int
+}
+
+func (d *testDisplay) Enable()                                                 { d.enabled = true }
+func (d *testDisplay) Enabled() bool                                           { return d.enabled }
+func (d *testDisplay) Disable()                                                { d.enabled = false }
+func (d *testDisplay) Close()                                                  {}
+func (d *testDisplay) Write(colorIndex uint8)                                  { d.pixels++ }
+func (d *testDisplay) HBlank()                                                 { d.hblanks++ }
+func (d *testDisplay) VBlank()                                                 { d.vblanks++ }
+func (d *testDisplay) Text(text string)                                        {}
+func (d *testDisplay) Message(text string, t time.Duration, p screen.Priority) {}
+func (d *testDisplay) ToggleUI()                                               {}
+func (d *testDisplay) ToggleFullscreen()                                       {}
+func (d *testDisplay) Resize()                                                 {}
+func (d *testDisplay) Screenshot(filename string)                              {}
+func (d *testDisplay) Record(filename string)                                  {}
+func (d *testDisplay) StopRecord()                                             {}
+
+// Returns a PPU with the LCD turned on, rendering to a test display.
+func newTestPPU() (*PPU, *testDisplay) {
+	var regIF, regIE uint8
+	display := &testDisplay{}
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay
+	return p, display
+}
+
+func TestFrame(t *testing.T) {
+	p, display := newTestPPU()
+
+	for i := 0; i < 154*456; i++ {
+		p.Tick()
+	}
+
+	if display.vblanks != 1 {
+		t.Errorf("%d VBlanks in one frame, want 1", display.vblanks)
+	}
+	if display.hblanks != 144 {
+		t.Errorf("%d HBlanks in one frame, want 144", display.hblanks)
+	}
+	if display.pixels != 160*144 {
+		t.Errorf("%d pixels in one frame, want %d", display.pixels, 160*144)
+	}
+}
+
+func TestModeAndDot(t *testing.T) {
+	p, _ := newTestPPU()
+	if p.Dot() != 0 {
+		t.Errorf("dot is %d with the LCD off, want 0", p.Dot())
+	}
+
+	// The LCD is turned on by the first tick, which is also the first dot.
+	for i := 1; i <= 3*456; i++ {
+		p.Tick()
+		if want := i % 456; p.Dot() != want {
+			t.Fatalf("dot is %d after %d ticks, want %d", p.Dot(), i, want)
+		}
+		if want := uint8(i / 456); p.LY != want {
+			t.Fatalf("LY is %d at dot %d, want %d", p.LY, p.Dot(), want)
+		}
+		if p.Dot() > 0 && p.Dot() < 80 && p.Mode() != states.OAMSearch {
+			t.Fatalf("mode is %d at dot %d, want OAM search", p.Mode(), p.Dot())
+		}
+	}
+
+	// VBlank starts at line 144.
+	for p.LY != 144 {
+		p.Tick()
+	}
+	if p.Mode() != states.VBlank {
+		t.Errorf("mode is %d on line 144, want VBlank", p.Mode())
+	}
+}
+
+func TestVBlankTiming(t *testing.T) {
+	p, display := newTestPPU()
+	for display.vblanks == 0 {
+		p.Tick()
+	}
+
+	// VBlank lasts 10 lines of 456 dots, with LY going from 144 to 153. LY
+	// already reads 0 a few dots into line 153.
+	for n := 1; n < 10*456; n++ {
+		p.Tick()
+		want := uint8(144 + n/456)
+		if want == 153 && n%456 >= 4 {
+			want = 0
+		}
+		if p.LY != want || p.Mode() != states.VBlank {
+			t.Fatalf("LY=%d in mode %d at dot %d of VBlank, want LY=%d in VBlank",
+				p.LY, p.Mode(), n, want)
+		}
+	}
+	p.Tick()
+	if p.LY != 0 || p.Mode() != states.OAMSearch {
+		t.Fatalf("LY=%d in mode %d after VBlank, want LY=0 in OAM search",
+			p.LY, p.Mode())
+	}
+
+	// The next frame starts exactly 70224 dots after the previous one.
+	dots := 10 * 456
+	for display.vblanks == 1 {
+		p.Tick()
+		dots++
+	}
+	if dots != 70224 {
+		t.Errorf("frame took %d dots, want 70224", dots)
+	}
+}
+
+func TestOAMBug(t *testing.T) {
+	p, _ := newTestPPU()
+	for i := range p.oamRAM.Bytes {
+		p.oamRAM.Bytes[i] = uint8(i * 7)
+	}
+	original := append([]uint8(nil), p.oamRAM.Bytes...)
+
+	// Get to the third OAM row (sprites 4 and 5).
+	for p.state != states.OAMSearch || p.OAM.index < 4 {
+		p.Tick()
+	}
+
+	// Disabled by default, and never triggered outside OAM.
+	p.CorruptOAM(0xfe00)
+	p.OAMBug = true
+	p.CorruptOAM(0xc000)
+	if !bytes.Equal(p.oamRAM.Bytes, original) {
+		t.Fatal("OAM corrupted without a valid trigger")
+	}
+
+	p.CorruptOAM(0xfe00)
+
+	// Row 2 starts at 16. a=0x7770 (bytes 112,119), b=0x3f38 (56,63),
+	// c=0x5b54 (84,91): ((a^c)&(b^c))^c = 0x7f70.
+	expected := append([]uint8(nil), original...)
+	expected[16], expected[17] = 0x70, 0x7f
+	copy(expected[18:24], original[10:16])
+	if !bytes.Equal(p.oamRAM.Bytes, expected) {
+		t.Errorf("OAM after corruption:\n%v\nwant:\n%v", p.oamRAM.Bytes[:32], expected[:32])
+	}
+}
+
+func TestSTATBug(t *testing.T) {
+	p, _ := newTestPPU()
+	p.LYC = 0xff // No coincidence.
+
+	pending := func() bool {
+		return p.Interrupts.Read(interrupts.AddrIF)&interrupts.LCDStat != 0
+	}
+
+	for p.state != states.PixelTransfer {
+		p.Tick()
+	}
+	p.STATBug = true
+	p.Write(AddrSTAT, 0)
+	if pending() {
+		t.Fatal("STAT interrupt requested by a write during mode 3")
+	}
+
+	for p.state != states.HBlank {
+		p.Tick()
+	}
+	p.STATBug = false
+	p.Write(AddrSTAT, 0)
+	if pending() {
+		t.Fatal("STAT interrupt requested with the bug disabled")
+	}
+
+	p.STATBug = true
+	p.Write(AddrSTAT, 0)
+	if !pending() {
+		t.Error("no STAT interrupt requested by a write during mode 0")
+	}
+}
+
+func TestGIFPalette(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fade.gif")
+
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay
+
+	frame := func() {
+		for i := 0; i < 154*456; i++ {
+			p.Tick()
+		}
+	}
+
+	// Record one frame in white, then one in black, whatever VRAM contains.
+	p.BGP = 0x00
+	display.Record(path)
+	frame() // Recording starts at the end of this one.
+	frame()
+	p.BGP = 0xff
+	frame()
+	display.StopRecord()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recorded, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded.Image) < 2 {
+		t.Fatalf("%d frames recorded, want at least 2", len(recorded.Image))
+	}
+
+	before, after := recorded.Image[0], recorded.Image[1]
+	if before.Pix[0] != 0 || after.Pix[0] != 3 {
+		t.Errorf("shades before and after BGP change are %d and %d, want 0 and 3",
+			before.Pix[0], after.Pix[0])
+	}
+	if bytes.Equal(before.Pix, after.Pix) {
+		t.Error("frames before and after BGP change are identical")
+	}
+}
+
+func TestSCXLatching(t *testing.T) {
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect
+	p.BGP = 0xe4
+
+	// Tile 1 is solid color 3, tile 0 is blank. The map's first row starts
+	// with tiles 1, 0, 1.
+	for addr := uint16(0x8000); addr < 0xa000; addr++ {
+		p.Write(addr, 0)
+	}
+	for addr := uint16(0x8010); addr < 0x8020; addr++ {
+		p.Write(addr, 0xff)
+	}
+	p.Write(0x9800, 1)
+	p.Write(0x9802, 1)
+
+	// Scroll one tile right during line 0's HBlank.
+	for p.state != states.HBlank {
+		p.Tick()
+	}
+	p.SCX = 8
+	for display.Frames == 0 {
+		p.Tick()
+	}
+
+	line := func(ly, x int) uint8 { return display.Frame[ly*screen.ScreenWidth+x] }
+	for x, want := range []uint8{3, 0, 3} {
+		if got := line(0, x*8); got != want {
+			t.Errorf("line 0, tile %d has color %d, want %d", x, got, want)
+		}
+	}
+	for x, want := range []uint8{0, 3, 0} {
+		if got := line(1, x*8); got != want {
+			t.Errorf("line 1, tile %d has color %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestPaletteWrites(t *testing.T) {
+	cases := []struct {
+		mode  PaletteWrites
+		first int   // First pixel of line 0 drawn with the new palette
+		after uint8 // Color of the lines after that
+	}{
+		{PaletteWritesLatch, 41, 0},
+		{PaletteWritesInstant, 40, 0},
+		{PaletteWritesIgnore, 160, 3},
+	}
+
+	for _, c := range cases {
+		var regIF, regIE uint8
+		display := screen.NewHeadless()
+		p := New(display, nil)
+		p.Interrupts = interrupts.New(&regIF, &regIE)
+		p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect
+		p.BGP = 0xe4
+		p.PaletteWrites = c.mode
+
+		// The whole background is tile 0, solid color 3.
+		for addr := uint16(0x8000); addr < 0xa000; addr++ {
+			p.Write(addr, 0)
+		}
+		for addr := uint16(0x8000); addr < 0x8010; addr++ {
+			p.Write(addr, 0xff)
+		}
+
+		// Map color 3 to shade 0 once 40 pixels were shifted out on line 0.
+		for p.state != states.PixelTransfer || p.x < 40 {
+			p.Tick()
+		}
+		p.Write(AddrBGP, 0x24)
+		for display.Frames == 0 {
+			p.Tick()
+		}
+
+		for x := 0; x < screen.ScreenWidth; x++ {
+			want := uint8(3)
+			if x >= c.first {
+				want = 0
+			}
+			if got := display.Frame[x]; got != want {
+				t.Errorf("mode %d: pixel %d has color %d, want %d", c.mode, x, got, want)
+			}
+		}
+		if got := display.Frame[screen.ScreenWidth]; got != c.after {
+			t.Errorf("mode %d: line 1 has color %d, want %d", c.mode, got, c.after)
+		}
+	}
+
+	if _, err := ParsePaletteWrites("later"); err == nil {
+		t.Error("no error for unknown palette write mode")
+	}
+}
+
+func TestTileDataSelectChanges(t *testing.T) {
+	cases := []struct {
+		lcdc, tileID uint8
+		want         uint16
+	}{
+		{LCDCBGWindowTileDataSelect, 0x00, 0x8000},
+		{LCDCBGWindowTileDataSelect, 0x80, 0x8800},
+		{0, 0x00, 0x9000},
+		{0, 0x7f, 0x97f0},
+		{0, 0x80, 0x8800},
+		{0, 0xff, 0x8ff0},
+	}
+	for _, c := range cases {
+		if got := TileAddress(c.lcdc, c.tileID); got != c.want {
+			t.Errorf("TileAddress(0x%02x, 0x%02x) == 0x%04x, want 0x%04x",
+				c.lcdc, c.tileID, got, c.want)
+		}
+	}
+
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect
+	p.BGP = 0xe4
+
+	// The whole map uses tile 0, which is solid color 3 at 0x8000 and blank
+	// at 0x9000.
+	for addr := uint16(0x8000); addr < 0xa000; addr++ {
+		p.Write(addr, 0)
+	}
+	for addr := uint16(0x8000); addr < 0x8010; addr++ {
+		p.Write(addr, 0xff)
+	}
+
+	// Switch to signed tile IDs for lines 2 and 3, then back.
+	for _, ly := range []uint8{1, 3} {
+		for p.LY != ly || p.state != states.HBlank {
+			p.Tick()
+		}
+		p.LCDC ^= LCDCBGWindowTileDataSelect
+	}
+
+	// Tiles fetched after switching in the middle of line 5 are blank.
+	for p.LY != 5 || p.state != states.PixelTransfer || p.x < screen.ScreenWidth/2 {
+		p.Tick()
+	}
+	p.LCDC ^= LCDCBGWindowTileDataSelect
+	for display.Frames == 0 {
+		p.Tick()
+	}
+
+	for ly, want := range [][2]uint8{{3, 3}, {3, 3}, {0, 0}, {0, 0}, {3, 3}, {3, 0}} {
+		for i, x := range []int{0, screen.ScreenWidth - 1} {
+			if got := display.Frame[ly*screen.ScreenWidth+x]; got != want[i] {
+				t.Errorf("line %d, pixel %d has color %d, want %d", ly, x, got, want[i])
+			}
+		}
+	}
+}
+
+func TestWXChanges(t *testing.T) {
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect |
+		LCDCWindowDisplayEnable | LCDCWindowTileMapDisplayeSelect
+	p.BGP = 0xe4
+
+	// Background is blank. The window is made of tile 1, whose top half is
+	// color 3 and bottom half color 1.
+	for addr := uint16(0x8000); addr < 0x9c00; addr++ {
+		p.Write(addr, 0)
+	}
+	for addr := uint16(0x9c00); addr < 0xa000; addr++ {
+		p.Write(addr, 1)
+	}
+	for row := uint16(0); row < 8; row++ {
+		p.Write(0x8010+row*2, 0xff)
+		if row < 4 {
+			p.Write(0x8011+row*2, 0xff)
+		}
+	}
+
+	hblank := func(ly uint8) {
+		for p.state != states.HBlank || p.LY != ly {
+			p.Tick()
+		}
+	}
+
+	p.WX = 7 + 80
+	hblank(0)
+	p.WX = 7 + 40
+	hblank(1)
+	p.WX = 167 // Off-screen for a few lines.
+	hblank(5)
+	p.WX = 3 // Partly off-screen.
+	for display.Frames == 0 {
+		p.Tick()
+	}
+
+	cases := []struct {
+		ly, x int
+		want  uint8
+	}{
+		{0, 79, 0}, {0, 80, 3}, // Earlier line untouched.
+		{1, 39, 0}, {1, 40, 3},
+		{2, 159, 0}, {5, 159, 0},
+		{6, 0, 3}, // Window resumes at its third line...
+		{8, 0, 1}, // ...and reaches the bottom half of its tiles 2 lines later.
+	}
+	for _, c := range cases {
+		if got := display.Frame[c.ly*screen.ScreenWidth+c.x]; got != c.want {
+			t.Errorf("pixel (%d, %d) has color %d, want %d", c.x, c.ly, got, c.want)
+		}
+	}
+}
+
+func TestTallSpriteTileIndex(t *testing.T) {
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect |
+		LCDCSpriteDisplayEnable | LCDCSpriteSize
+	p.BGP = 0xe4
+	p.OBP0 = 0xe4
+
+	// Tile 2 is solid color 1, tile 3 solid color 2 and tile 4 (which an
+	// unmasked index would reach) solid color 3.
+	// Background and OAM are blank.
+	for addr := uint16(0x8000); addr < 0xa000; addr++ {
+		p.Write(addr, 0)
+	}
+	for addr := uint16(AddrOAM); addr < AddrOAM+0xa0; addr++ {
+		p.Write(addr, 0)
+	}
+	for row := uint16(0); row < 8; row++ {
+		p.Write(0x8020+row*2, 0xff)
+		p.Write(0x8031+row*2, 0xff)
+		p.Write(0x8040+row*2, 0xff)
+		p.Write(0x8041+row*2, 0xff)
+	}
+
+	// A sprite at the top left corner using odd tile index 3. Another one
+	// using the same index, flipped vertically.
+	for i, sprite := range [][4]uint8{{16, 8, 3, 0}, {16, 16, 3, SpriteFlipY}} {
+		for j, value := range sprite {
+			p.Write(AddrOAM+uint16(i*4+j), value)
+		}
+	}
+	for display.Frames == 0 {
+		p.Tick()
+	}
+
+	cases := []struct {
+		x, ly int
+		want  uint8
+	}{
+		{0, 0, 1}, {0, 7, 1}, // Top half from tile 2...
+		{0, 8, 2}, {0, 15, 2}, // ...bottom half from tile 3.
+		{8, 0, 2}, {8, 15, 1}, // Swapped halves when flipped.
+		{0, 16, 0},
+	}
+	for _, c := range cases {
+		if got := display.Frame[c.ly*screen.ScreenWidth+c.x]; got != c.want {
+			t.Errorf("pixel (%d, %d) has color %d, want %d", c.x, c.ly, got, c.want)
+		}
+	}
+}
+
+func TestObjectPriority(t *testing.T) {
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.BGP = 0xe4
+	p.OBP0 = 0xe4
+
+	// Tile 1 is solid color 1 and tile 2 solid color 2, background is blank.
+	// Sprite 0 (tile 2) overlaps sprite 1 (tile 1) from the right, on screen
+	// pixels 8 to 11.
+	p.LoadTiles(0x8000, make([]byte, 0x1800))
+	p.LoadMap(0x9800, make([]byte, 0x400))
+	for row := uint16(0); row < 8; row++ {
+		p.Write(0x8010+row*2, 0xff)
+		p.Write(0x8021+row*2, 0xff)
+	}
+	oam := []byte{16, 16, 2, 0, 16, 12, 1, 0}
+	for addr := uint16(AddrOAM); addr < AddrOAM+0xa0; addr++ {
+		value := uint8(0)
+		if i := int(addr - AddrOAM); i < len(oam) {
+			value = oam[i]
+		}
+		p.Write(addr, value)
+	}
+
+	cases := []struct {
+		opri uint8
+		want uint8
+	}{
+		{0x01, 1}, // By coordinate: sprite 1 is further left and wins.
+		{0x00, 2}, // By OAM index: sprite 0 wins.
+	}
+	for _, c := range cases {
+		p.Priority.Write(AddrOPRI, c.opri)
+		p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect |
+			LCDCSpriteDisplayEnable
+		frames := display.Frames
+		for display.Frames == frames {
+			p.Tick()
+		}
+
+		line := display.Frame[:screen.ScreenWidth]
+		if line[4] != 1 || line[12] != 2 {
+			t.Errorf("OPRI=%d: sprites not drawn (colors %v)", c.opri, line[:16])
+		}
+		for x := 8; x < 12; x++ {
+			if line[x] != c.want {
+				t.Errorf("OPRI=%d: overlapping pixel %d has color %d, want %d",
+					c.opri, x, line[x], c.want)
+			}
+		}
+	}
+
+	if value := p.Priority.Read(AddrOPRI); value != 0xfe {
+		t.Errorf("OPRI reads as 0x%02x, want 0xfe", value)
+	}
+}
+
+func TestSpritePalettes(t *testing.T) {
+	var regIF, regIE uint8
+	display := screen.NewHeadless()
+	p := New(display, nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.BGP = 0xe4
+	p.OBP0 = 0xe4 // Color 1 shows as shade 1
+	p.OBP1 = 0x0c // Color 1 shows as shade 3
+
+	// Tile 1 is solid color 1, background is blank. Both sprites use it, on
+	// the first line, one on each object palette.
+	p.LoadTiles(0x8000, make([]byte, 0x1800))
+	p.LoadMap(0x9800, make([]byte, 0x400))
+	for row := uint16(0); row < 8; row++ {
+		p.Write(0x8010+row*2, 0xff)
+	}
+	for i := 0; i < OBJCount; i++ {
+		p.OBJ(i).SetY(0) // Off-screen
+	}
+	for i, flags := range []uint8{0, SpritePalette} {
+		obj := p.OBJ(i)
+		obj.SetY(16)
+		obj.SetX(uint8(8 + i*32))
+		obj.SetTile(1)
+		obj.SetFlags(flags)
+	}
+
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect |
+		LCDCSpriteDisplayEnable
+	for display.Frames == 0 {
+		p.Tick()
+	}
+
+	line := display.Frame[:screen.ScreenWidth]
+	for x := 0; x < 8; x++ {
+		if line[x] != 1 {
+			t.Errorf("OBP0 sprite pixel %d has shade %d, want 1", x, line[x])
+		}
+		if line[32+x] != 3 {
+			t.Errorf("OBP1 sprite pixel %d has shade %d, want 3", x, line[32+x])
+		}
+	}
+}
+
+func TestTickN(t *testing.T) {
+	var singleIF, batchedIF, regIE uint8
+	newPPU := func(regIF *uint8) (*PPU, *screen.Headless) {
+		display := screen.NewHeadless()
+		p := New(display, nil)
+		p.Interrupts = interrupts.New(regIF, &regIE)
+		p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCSpriteDisplayEnable
+		p.BGP = 0xe4
+		p.OBP0 = 0x1b
+		p.Write(AddrLYC, 100)
+		p.Write(AddrSTAT, interrupts.STATLYCLY|interrupts.STATMode0)
+		for addr := uint16(0x8000); addr < 0xa000; addr++ {
+			p.Write(addr, uint8(addr*7))
+		}
+		for i := 0; i < 10; i++ {
+			p.Write(AddrOAM+uint16(i*4), uint8(16+i*12))  // Y
+			p.Write(AddrOAM+uint16(i*4)+1, uint8(8+i*15)) // X
+			p.Write(AddrOAM+uint16(i*4)+2, uint8(i))      // Tile
+		}
+		return p, display
+	}
+	single, singleDisplay := newPPU(&singleIF)
+	batched, batchedDisplay := newPPU(&batchedIF)
+
+	// Uneven batch sizes over a few frames, with the LCD off for a while.
+	for i := 0; i < 3000; i++ {
+		if i == 1000 || i == 1500 {
+			single.LCDC ^= LCDCDisplayEnable
+			batched.LCDC ^= LCDCDisplayEnable
+		}
+
+		n := 1 + i*53%500
+		for j := 0; j < n; j++ {
+			single.Tick()
+		}
+		batched.TickN(n)
+
+		if single.Mode() != batched.Mode() || single.Dot() != batched.Dot() ||
+			single.LY != batched.LY || single.Read(AddrSTAT) != batched.Read(AddrSTAT) ||
+			single.Cycle != batched.Cycle || singleIF != batchedIF {
+			t.Fatalf("batch %d: TickN state differs (mode %d, dot %d, LY %d, IF 0x%02x), Tick has (mode %d, dot %d, LY %d, IF 0x%02x)",
+				i, batched.Mode(), batched.Dot(), batched.LY, batchedIF,
+				single.Mode(), single.Dot(), single.LY, singleIF)
+		}
+		if singleDisplay.Frames != batchedDisplay.Frames || singleDisplay.Frame != batchedDisplay.Frame {
+			t.Fatalf("batch %d: frames differ", i)
+		}
+	}
+	if singleDisplay.Frames < 5 {
+		t.Errorf("only %d frames rendered", singleDisplay.Frames)
+	}
+}
+
+func BenchmarkTick(b *testing.B) {
+	p, _ := newTestPPU()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 456; j++ {
+			p.Tick()
+		}
+	}
+}
+
+func BenchmarkTickN(b *testing.B) {
+	p, _ := newTestPPU()
+	for i := 0; i < b.N; i++ {
+		p.TickN(456)
+	}
+}
+
+func TestTileWriteWatch(t *testing.T) {
+	var regIF, regIE uint8
+	p := New(screen.NewHeadless(), nil)
+	p.Interrupts = interrupts.New(&regIF, &regIE)
+	p.LCDC = LCDCDisplayEnable | LCDCBGDisplay | LCDCBGWindowTileDataSelect
+	for addr := uint16(0x8000); addr < 0xa000; addr++ {
+		p.Write(addr, 0)
+	}
+	p.WatchTileWrites(true)
+
+	type write struct {
+		addr uint16
+		ly   uint8
+	}
+	var writes []write
+	p.OnTileWrite = func(addr uint16, ly uint8) {
+		writes = append(writes, write{addr, ly})
+	}
+
+	// Stop a few tiles into line 10, which shows line 2 of tile 0 everywhere.
+	for p.LY != 10 || p.Mode() != states.PixelTransfer || p.x < 32 {
+		p.Tick()
+	}
+	p.Write(0x8100, 0xff) // Not fetched
+	p.Write(0x8004, 0xff) // Fetched for this line
+	if len(writes) != 1 || writes[0] != (write{0x8004, 10}) {
+		t.Fatalf("tile writes %v during pixel transfer, want [{0x8004 10}]", writes)
+	}
+
+	// Writing after the line is done is fine.
+	for p.Mode() != states.HBlank {
+		p.Tick()
+	}
+	p.Write(0x8005, 0xff)
+	if len(writes) != 1 {
+		t.Errorf("tile writes %v, want none during HBlank", writes[1:])
+	}
+}
+
+func TestOnHBlank(t *testing.T) {
+	p, _ := newTestPPU()
+
+	var lines []uint8
+	p.OnHBlank = func(ly uint8) { lines = append(lines, ly) }
+	for i := 0; i < 154*456; i++ {
+		p.Tick()
+	}
+
+	if len(lines) != 144 {
+		t.Fatalf("%d HBlank calls in one frame, want 144", len(lines))
+	}
+	for i, ly := range lines {
+		if ly != uint8(i) {
+			t.Fatalf("HBlank call %d for LY=%d, want %d", i, ly, i)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	p, _ := newTestPPU()
+	clk := clock.NewManual(p)
+
+	// 10 full lines of 456 dots, then 80 dots of OAM search.
+	clk.AdvanceTo(10*456 + 79)
+	if p.LY != 10 || p.Mode() != states.OAMSearch {
+		t.Errorf("LY=%d in mode %d at tick %d, want LY=10 in mode %d", p.LY,
+			p.Mode(), clk.Ticks(), states.OAMSearch)
+	}
+	clk.Advance(1)
+	if p.LY != 10 || p.Mode() != states.PixelTransfer {
+		t.Errorf("LY=%d in mode %d at tick %d, want LY=10 in mode %d", p.LY,
+			p.Mode(), clk.Ticks(), states.PixelTransfer)
+	}
+
+	// VBlank starts at line 144.
+	clk.AdvanceTo(144*456 - 1)
+	if p.LY != 143 {
+		t.Errorf("LY=%d at tick %d, want 143", p.LY, clk.Ticks())
+	}
+	clk.Advance(1)
+	if p.LY != 144 || p.Mode() != states.VBlank {
+		t.Errorf("LY=%d in mode %d at tick %d, want LY=144 in VBlank", p.LY,
+			p.Mode(), clk.Ticks())
+	}
+
+	// Going back in time does nothing.
+	clk.AdvanceTo(0)
+	if clk.Ticks() != 144*456 {
+		t.Errorf("clock went back to tick %d", clk.Ticks())
+	}
+}
@@ -0,0 +1,37 @@
+package ppu
+
+// CGB object priority mode. Source:
+// [PANCGB] https://gbdev.io/pandocs/CGB_Registers.html#ff6c---opri---cgb-mode-only---object-priority-mode
+
+// AddrOPRI is the address of the CGB object priority mode register.
+const AddrOPRI = 0xff6c
+
+// ObjectPriority address space for the OPRI register, deciding which of two
+// overlapping sprites is drawn on top. It should only be mapped in CGB mode,
+// where it starts out in OAM order. Otherwise, sprites are ordered by X
+// coordinate as on DMG.
+type ObjectPriority struct {
+	// OAMOrder is set when the sprite with the lowest OAM index wins. When not
+	// set, the sprite with the lowest X coordinate wins, then the one with the
+	// lowest OAM index.
+	OAMOrder bool
+}
+
+// Contains returns true if the requested address is the OPRI register.
+func (o *ObjectPriority) Contains(addr uint16) bool {
+	return addr == AddrOPRI
+}
+
+// Read returns the value of OPRI with unused bits set. Bit 0 is clear in OAM
+// order mode.
+func (o *ObjectPriority) Read(addr uint16) uint8 {
+	if o.OAMOrder {
+		return 0xfe
+	}
+	return 0xff
+}
+
+// Write selects the priority mode from bit 0.
+func (o *ObjectPriority) Write(addr uint16, value uint8) {
+	o.OAMOrder = value&1 == 0
+}
@@ -1,5 +1,7 @@
 package ppu
 
+import "github.com/lazy-stripes/goholint/memory"
+
 // Sprite flags
 const (
 	SpritePalette = 1 << (iota + 4)
@@ -15,3 +17,77 @@ type Sprite struct {
 	Address uint16
 	Fetched bool // Set to true after this sprite was treated for a given line.
 }
+
+// OBJCount is the number of sprite entries in OAM.
+const OBJCount = 40
+
+// Offsets of sprite attributes in OAM entries.
+const (
+	objY = iota
+	objX
+	objTile
+	objFlags
+	objSize
+)
+
+// OBJ is a typed view over one of the 40 four-byte entries in OAM, reading and
+// writing the underlying bytes directly.
+type OBJ struct {
+	Address uint16 // Address of the entry in OAM
+	mem     memory.Addressable
+}
+
+// OBJ returns a view over the sprite's OAM entry in the given address space.
+func (s Sprite) OBJ(mem memory.Addressable) OBJ {
+	return OBJ{Address: s.Address, mem: mem}
+}
+
+// Index returns the entry's position in OAM, from 0 to 39.
+func (o OBJ) Index() int {
+	return int(o.Address-AddrOAM) / objSize
+}
+
+// Y returns the sprite's vertical position on screen plus 16.
+func (o OBJ) Y() uint8 {
+	return o.mem.Read(o.Address + objY)
+}
+
+// SetY sets the sprite's vertical position on screen plus 16.
+func (o OBJ) SetY(value uint8) {
+	o.mem.Write(o.Address+objY, value)
+}
+
+// X returns the sprite's horizontal position on screen plus 8.
+func (o OBJ) X() uint8 {
+	return o.mem.Read(o.Address + objX)
+}
+
+// SetX sets the sprite's horizontal position on screen plus 8.
+func (o OBJ) SetX(value uint8) {
+	o.mem.Write(o.Address+objX, value)
+}
+
+// Tile returns the sprite's tile index from 0x8000.
+func (o OBJ) Tile() uint8 {
+	return o.mem.Read(o.Address + objTile)
+}
+
+// SetTile sets the sprite's tile index from 0x8000.
+func (o OBJ) SetTile(value uint8) {
+	o.mem.Write(o.Address+objTile, value)
+}
+
+// Flags returns the sprite's attributes (see SpritePalette and others).
+func (o OBJ) Flags() uint8 {
+	return o.mem.Read(o.Address + objFlags)
+}
+
+// SetFlags sets the sprite's attributes (see SpritePalette and others).
+func (o OBJ) SetFlags(value uint8) {
+	o.mem.Write(o.Address+objFlags, value)
+}
+
+// Sprite returns the sprite's position and address as used during OAM search.
+func (o OBJ) Sprite() Sprite {
+	return Sprite{X: o.X(), Y: o.Y(), Address: o.Address}
+}
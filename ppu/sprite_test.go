@@ -0,0 +1,35 @@
+package ppu
+
+import "testing"
+
+func TestOBJ(t *testing.T) {
+	p, _ := newTestPPU()
+
+	// Raw bytes show through the typed view.
+	p.Write(AddrOAM+5*4, 0x20)
+	p.Write(AddrOAM+5*4+1, 0x18)
+	p.Write(AddrOAM+5*4+2, 0x42)
+	p.Write(AddrOAM+5*4+3, SpriteFlipX|SpritePalette)
+	obj := p.OBJ(5)
+	if obj.Index() != 5 || obj.Y() != 0x20 || obj.X() != 0x18 || obj.Tile() != 0x42 ||
+		obj.Flags() != SpriteFlipX|SpritePalette {
+		t.Errorf("OBJ(5) is #%d Y=0x%02x X=0x%02x tile=0x%02x flags=0x%02x",
+			obj.Index(), obj.Y(), obj.X(), obj.Tile(), obj.Flags())
+	}
+
+	// And the other way around.
+	obj = p.OBJ(39)
+	obj.SetY(0x90)
+	obj.SetX(0xa8)
+	obj.SetTile(0x7f)
+	obj.SetFlags(SpritePriority)
+	for i, want := range []uint8{0x90, 0xa8, 0x7f, SpritePriority} {
+		if got := p.Read(0xfe9c + uint16(i)); got != want {
+			t.Errorf("OAM byte 0x%04x is 0x%02x, want 0x%02x", 0xfe9c+i, got, want)
+		}
+	}
+
+	if sprite := obj.Sprite(); sprite.X != 0xa8 || sprite.Y != 0x90 || sprite.OBJ(p).Tile() != 0x7f {
+		t.Errorf("OBJ(39).Sprite() is %+v", sprite)
+	}
+}
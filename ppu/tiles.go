@@ -0,0 +1,153 @@
+package ppu
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+
+	"github.com/lazy-stripes/goholint/screen"
+)
+
+// TileSize is the size in bytes of a tile in 2bpp format: 8 lines of two
+// bytes, the first one holding the low bit of each pixel's color index and
+// the second one the high bit, leftmost pixel first.
+const TileSize = 16
+
+// DecodeTile returns color indices for the 8×8 pixels of the 2bpp tile at the
+// start of the given data, indexed by line then column.
+func DecodeTile(data []byte) (pixels [8][8]uint8) {
+	for row := range pixels {
+		low, high := data[row*2], data[row*2+1]
+		for col := range pixels[row] {
+			bit := uint(7 - col)
+			pixels[row][col] = (low>>bit)&1 | ((high>>bit)&1)<<1
+		}
+	}
+	return pixels
+}
+
+// EncodeTile returns 2bpp data for the given 8×8 color indices, indexed by
+// line then column. This is the reverse of DecodeTile.
+func EncodeTile(pixels [8][8]uint8) (data [TileSize]byte) {
+	for row := range pixels {
+		for col, colorIndex := range pixels[row] {
+			bit := uint(7 - col)
+			data[row*2] |= (colorIndex & 1) << bit
+			data[row*2+1] |= ((colorIndex >> 1) & 1) << bit
+		}
+	}
+	return data
+}
+
+// LoadTiles writes raw 2bpp tile data to VRAM from the given address (e.g.
+// 0x8000 for tile 0, 0x8010 for tile 1...). Mostly meant to set up tests.
+func (p *PPU) LoadTiles(addr uint16, data []byte) {
+	for i, value := range data {
+		p.Write(addr+uint16(i), value)
+	}
+}
+
+// LoadTileFile writes the tiles from a .2bpp file (raw tile data, as produced
+// by tools like rgbgfx) to VRAM from the given address.
+func (p *PPU) LoadTileFile(addr uint16, filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if len(data)%TileSize != 0 {
+		return fmt.Errorf("%s is not 2bpp data (%d bytes)", filename, len(data))
+	}
+	if int(addr)+len(data) > 0x9800 {
+		return fmt.Errorf("%s doesn't fit in VRAM from 0x%04x", filename, addr)
+	}
+	p.LoadTiles(addr, data)
+	return nil
+}
+
+// TileCount is the number of tiles in VRAM tile data (0x8000-0x97ff).
+const TileCount = 0x1800 / TileSize
+
+// Returns the address of the tile at the given index in tile data, counting
+// from 0x8000.
+func tileAddr(index uint) (uint16, error) {
+	if index >= TileCount {
+		return 0, fmt.Errorf("no tile %d in VRAM (0-%d)", index, TileCount-1)
+	}
+	return 0x8000 + uint16(index)*TileSize, nil
+}
+
+// ExportTile saves the tile at the given index in tile data (counting from
+// 0x8000, so tiles 256 and up are at 0x9000) as an 8×8 PNG file. Pixels keep
+// their raw color index, shown with the default shades rather than through
+// BGP, so the file can be edited and imported back as is (see ImportTile).
+func (p *PPU) ExportTile(index uint, path string) error {
+	addr, err := tileAddr(index)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, TileSize)
+	for i := range data {
+		data[i] = p.Read(addr + uint16(i))
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, 8, 8), screen.DefaultPalette)
+	for row, line := range DecodeTile(data) {
+		copy(img.Pix[row*img.Stride:], line[:])
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ImportTile loads an 8×8 PNG file into the tile at the given index in tile
+// data (see ExportTile). Colors are quantized to the 4 shades by brightness,
+// from lightest (color 0) to darkest (color 3), whatever the image's palette.
+func (p *PPU) ImportTile(index uint, path string) error {
+	addr, err := tileAddr(index)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != 8 || bounds.Dy() != 8 {
+		return fmt.Errorf("%s is %dx%d, tiles are 8x8", path, bounds.Dx(), bounds.Dy())
+	}
+
+	var pixels [8][8]uint8
+	for row := range pixels {
+		for col := range pixels[row] {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+col, bounds.Min.Y+row)).(color.Gray)
+			pixels[row][col] = 3 - gray.Y/64
+		}
+	}
+	data := EncodeTile(pixels)
+	p.LoadTiles(addr, data[:])
+	return nil
+}
+
+// LoadMap writes tile IDs to the tile map at the given address (0x9800 or
+// 0x9c00), 32 per row. Fewer IDs than the full map's 1024 only update the
+// first entries.
+func (p *PPU) LoadMap(addr uint16, ids []uint8) {
+	for i, id := range ids {
+		p.Write(addr+uint16(i), id)
+	}
+}
@@ -0,0 +1,137 @@
+package ppu
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Two tiles: a gradient going through all 4 colors twice per line, and a
+// frame of color 3 around color 1.
+var testTiles = []byte{
+	0x33, 0x55, 0x33, 0x55, 0x33, 0x55, 0x33, 0x55,
+	0x33, 0x55, 0x33, 0x55, 0x33, 0x55, 0x33, 0x55,
+	0xff, 0xff, 0xff, 0x81, 0xff, 0x81, 0xff, 0x81,
+	0xff, 0x81, 0xff, 0x81, 0xff, 0x81, 0xff, 0xff,
+}
+
+func TestDecodeTile(t *testing.T) {
+	pixels := DecodeTile(testTiles)
+	for col, want := range []uint8{0, 2, 1, 3, 0, 2, 1, 3} {
+		if pixels[7][col] != want {
+			t.Errorf("pixel (%d, 7) is %d, want %d", col, pixels[7][col], want)
+		}
+	}
+
+	for i := 0; i < len(testTiles); i += TileSize {
+		tile := testTiles[i : i+TileSize]
+		if data := EncodeTile(DecodeTile(tile)); !bytes.Equal(data[:], tile) {
+			t.Errorf("tile %d encoded back as % x, want % x", i/TileSize, data, tile)
+		}
+	}
+}
+
+func TestLoadTiles(t *testing.T) {
+	p, _ := newTestPPU()
+	p.BGP = 0xe4 // Identity palette.
+	p.LCDC |= LCDCBGWindowTileDataSelect
+
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tiles.2bpp")
+	if err := ioutil.WriteFile(path, testTiles, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Load both tiles as tiles 1 and 2, and use tile 2 at the top-left corner
+	// of the background.
+	p.LoadTiles(0x8000, make([]byte, TileSize))
+	if err := p.LoadTileFile(0x8010, path); err != nil {
+		t.Fatal(err)
+	}
+	p.LoadMap(0x9800, []uint8{2, 1})
+
+	// What the PPU renders should decode back to the original tiles.
+	background := p.DumpBackground(0x9800, testShades)
+	for i := 0; i < 2; i++ {
+		want := DecodeTile(testTiles[(1-i)*TileSize:])
+		for row := 0; row < 8; row++ {
+			for col := 0; col < 8; col++ {
+				if got := background.Pix[row*MapSize+i*8+col]; got != want[row][col] {
+					t.Errorf("tile %d: pixel (%d, %d) is %d, want %d",
+						i, col, row, got, want[row][col])
+				}
+			}
+		}
+	}
+
+	if err := ioutil.WriteFile(path, testTiles[:15], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LoadTileFile(0x8000, path); err == nil {
+		t.Error("no error loading truncated tile data")
+	}
+}
+
+func TestExportImportTile(t *testing.T) {
+	p, _ := newTestPPU()
+
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tile.png")
+
+	// Round-trip tile 300 (at 0x92c0) through PNG, using all 4 colors.
+	p.LoadTiles(0x92c0, testTiles[:TileSize])
+	if err := p.ExportTile(300, path); err != nil {
+		t.Fatal(err)
+	}
+	p.LoadTiles(0x92c0, make([]byte, TileSize))
+	if err := p.ImportTile(300, path); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range testTiles[:TileSize] {
+		if got := p.Read(0x92c0 + uint16(i)); got != want {
+			t.Errorf("byte %d is 0x%02x after import, want 0x%02x", i, got, want)
+		}
+	}
+
+	// Other colors are quantized to the nearest shade.
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0xe0, 0xf8, 0xd0, 0xff}}, image.ZP, draw.Src)
+	img.Set(1, 0, color.RGBA{0x88, 0xc0, 0x70, 0xff})
+	img.Set(2, 0, color.RGBA{0x34, 0x68, 0x56, 0xff})
+	img.Set(3, 0, color.RGBA{0x08, 0x18, 0x20, 0xff})
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+	if err := p.ImportTile(0, path); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, TileSize)
+	for i := range data {
+		data[i] = p.Read(0x8000 + uint16(i))
+	}
+	line := DecodeTile(data)[0]
+	if got := [4]uint8{line[0], line[1], line[2], line[3]}; got != [4]uint8{0, 1, 2, 3} {
+		t.Errorf("quantized first pixels to %v, want [0 1 2 3]", got)
+	}
+
+	if err := p.ExportTile(TileCount, path); err == nil {
+		t.Error("no error exporting a tile past the end of VRAM")
+	}
+}
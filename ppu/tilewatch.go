@@ -0,0 +1,48 @@
+package ppu
+
+import "github.com/lazy-stripes/goholint/ppu/states"
+
+// Tile data area in VRAM, for all addressing modes.
+const (
+	tileDataStart = 0x8000
+	tileDataSize  = 0x1800
+)
+
+// WatchTileWrites turns on (or off) detection of CPU writes to tile data that
+// the fetcher already read for the line being drawn. Real hardware locks VRAM
+// during pixel transfer so this can't happen there, but seeing it here points
+// at CPU/PPU timing bugs. Each occurrence is logged as a warning and passed to
+// OnTileWrite if set.
+func (p *PPU) WatchTileWrites(on bool) {
+	if on {
+		p.Fetcher.fetched = make([]uint32, tileDataSize)
+	} else {
+		p.Fetcher.fetched = nil
+	}
+}
+
+// Checks a VRAM write against tile data fetched on the current line.
+func (p *PPU) checkTileWrite(addr uint16) {
+	if addr < tileDataStart || addr >= tileDataStart+tileDataSize {
+		return
+	}
+	if !p.LCD.Enabled() || p.state != states.PixelTransfer {
+		return
+	}
+	if p.Fetcher.fetched[addr-tileDataStart] != p.Fetcher.line {
+		return
+	}
+
+	log.Warningf("tile data at 0x%04x written after being fetched for LY=%d", addr, p.LY)
+	if p.OnTileWrite != nil {
+		p.OnTileWrite(addr, p.LY)
+	}
+}
+
+// Records a tile data address read by the fetcher on the current line, if
+// watching for writes.
+func (f *Fetcher) fetchedTileData(addr uint16) {
+	if f.fetched != nil && addr >= tileDataStart && addr < tileDataStart+tileDataSize {
+		f.fetched[addr-tileDataStart] = f.line
+	}
+}
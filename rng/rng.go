@@ -0,0 +1,12 @@
+// Package rng is the single source of randomness for the emulator, so that a
+// run can be reproduced exactly given the same seed (see -seed).
+package rng
+
+import "math/rand"
+
+// New returns a random generator starting from the given seed. Each caller
+// gets its own generator so that what one of them draws doesn't depend on how
+// much the others did.
+func New(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
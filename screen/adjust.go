@@ -0,0 +1,72 @@
+package screen
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Adjust holds color corrections applied to the final output (screen,
+// screenshots and recordings), e.g. to match the look of a given hardware
+// revision. As all pixels are drawn from a 4-color palette, corrections are
+// applied to the palette itself. The zero value changes nothing.
+type Adjust struct {
+	Brightness int64      // Percentage of full intensity added to each channel, from -100 to 100
+	Contrast   int64      // Percentage added to the distance from mid-gray, from -100 (flat gray)
+	Tint       color.RGBA // Multiplies each channel by the tint's, unless fully transparent
+}
+
+// IsZero returns whether the adjustments leave colors unchanged.
+func (a Adjust) IsZero() bool {
+	return a.Brightness == 0 && a.Contrast == 0 && a.Tint.A == 0
+}
+
+// Color returns the given color with contrast, brightness and tint applied in
+// that order. Channels are clamped to 0-255 before the tint is applied, and
+// alpha is left alone.
+func (a Adjust) Color(c color.Color) color.RGBA {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	contrast := math.Max(0, float64(100+a.Contrast)) / 100
+	brightness := float64(a.Brightness) * 255 / 100
+
+	channel := func(value, tint uint8) uint8 {
+		v := (float64(value)-128)*contrast + 128 + brightness
+		v = math.Max(0, math.Min(v, 255))
+		if a.Tint.A != 0 {
+			v = v * float64(tint) / 255
+		}
+		return uint8(math.Round(v))
+	}
+	return color.RGBA{
+		channel(rgba.R, a.Tint.R),
+		channel(rgba.G, a.Tint.G),
+		channel(rgba.B, a.Tint.B),
+		rgba.A,
+	}
+}
+
+// Palette returns a copy of the given palette with adjustments applied to
+// every color.
+func (a Adjust) Palette(palette color.Palette) color.Palette {
+	adjusted := make(color.Palette, len(palette))
+	for i, c := range palette {
+		adjusted[i] = a.Color(c)
+	}
+	return adjusted
+}
+
+// ParseTint returns the opaque color given as six hexadecimal digits, with or
+// without a leading '#' (e.g. ffe0c0). An empty string means no tint.
+func ParseTint(s string) (color.RGBA, error) {
+	if s == "" {
+		return color.RGBA{}, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid tint %q, expected RRGGBB", s)
+	}
+	return color.RGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 0xff}, nil
+}
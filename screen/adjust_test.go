@@ -0,0 +1,43 @@
+package screen
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestAdjust(t *testing.T) {
+	tint, err := ParseTint("#ff8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adjust := Adjust{Brightness: 10, Contrast: 50, Tint: tint}
+
+	// Red goes over 255 and is clamped before the tint. Green ends up at
+	// (100-128)*1.5+128+25.5 = 111.5, then half of that. Blue goes below 0.
+	got := adjust.Color(color.RGBA{200, 100, 0, 0xff})
+	if want := (color.RGBA{255, 56, 0, 0xff}); got != want {
+		t.Errorf("adjusted color is %v, want %v", got, want)
+	}
+
+	// Flat gray with the lowest contrast.
+	got = Adjust{Contrast: -100}.Color(ColorBlack)
+	if want := (color.RGBA{128, 128, 128, 0xff}); got != want {
+		t.Errorf("no contrast color is %v, want %v", got, want)
+	}
+
+	// The zero value changes nothing.
+	if !(Adjust{}).IsZero() {
+		t.Error("zero adjustment isn't zero")
+	}
+	for i, c := range (Adjust{}).Palette(DefaultPalette) {
+		if c != DefaultPalette[i] {
+			t.Errorf("color %d changed to %v without adjustments", i, c)
+		}
+	}
+
+	for _, invalid := range []string{"fff", "12345g", "#1234567"} {
+		if _, err := ParseTint(invalid); err == nil {
+			t.Errorf("no error for tint %q", invalid)
+		}
+	}
+}
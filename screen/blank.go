@@ -0,0 +1,88 @@
+package screen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strconv"
+
+	// Decoders for custom blank screen images.
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// BlankFrame fills a frame (one color index per pixel, line by line) with
+// what the display shows while the LCD is off. It's given the last frame that
+// was drawn before the screen got disabled.
+type BlankFrame func(frame, last []uint8)
+
+// SolidBlank returns a blank frame generator filling the screen with a single
+// shade, like real hardware does with the lightest one.
+func SolidBlank(colorIndex uint8) BlankFrame {
+	return func(frame, last []uint8) {
+		for i := range frame {
+			frame[i] = colorIndex
+		}
+	}
+}
+
+// BandBlank fills the screen with the lightest shade and draws a line of the
+// darkest one across the middle, making it obvious that the LCD is off.
+func BandBlank(frame, last []uint8) {
+	SolidBlank(0)(frame, last)
+	middle := frame[ScreenHeight/2*ScreenWidth:]
+	for i := 0; i < ScreenWidth; i++ {
+		middle[i] = 3
+	}
+}
+
+// FrozenBlank keeps showing the last frame drawn before the LCD was turned
+// off.
+func FrozenBlank(frame, last []uint8) {
+	copy(frame, last)
+}
+
+// ImageBlank returns a blank frame generator showing the given image, each
+// pixel converted to the closest shade in the palette. Images larger than the
+// screen are cropped, smaller ones are padded with the lightest shade.
+func ImageBlank(img image.Image, palette color.Palette) BlankFrame {
+	pixels := make([]uint8, ScreenWidth*ScreenHeight)
+	bounds := img.Bounds()
+	for y := 0; y < ScreenHeight && bounds.Min.Y+y < bounds.Max.Y; y++ {
+		for x := 0; x < ScreenWidth && bounds.Min.X+x < bounds.Max.X; x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			pixels[y*ScreenWidth+x] = uint8(palette.Index(c))
+		}
+	}
+	return func(frame, last []uint8) {
+		copy(frame, pixels)
+	}
+}
+
+// ParseBlank returns the blank frame generator described by the given
+// setting: "band", "frozen", a shade from 0 (lightest) to 3 (darkest), or the
+// path to a PNG or JPEG image whose colors are matched against the palette.
+func ParseBlank(setting string, palette color.Palette) (BlankFrame, error) {
+	switch setting {
+	case "band":
+		return BandBlank, nil
+	case "frozen":
+		return FrozenBlank, nil
+	case "0", "1", "2", "3":
+		colorIndex, _ := strconv.Atoi(setting)
+		return SolidBlank(uint8(colorIndex)), nil
+	}
+
+	f, err := os.Open(setting)
+	if err != nil {
+		return nil, fmt.Errorf("unknown blank screen %q (expected band, frozen, 0-3 or an image): %v",
+			setting, err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode blank screen image %s: %v", setting, err)
+	}
+	return ImageBlank(img, palette), nil
+}
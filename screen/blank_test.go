@@ -0,0 +1,131 @@
+package screen
+
+import (
+	"image"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSolidBlank(t *testing.T) {
+	blank, err := ParseBlank("2", DefaultPalette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHeadless()
+	h.SetBlank(blank)
+
+	// Draw one frame, then turn the screen off.
+	h.Enable()
+	for i := 0; i < ScreenWidth*ScreenHeight; i++ {
+		h.Write(1)
+	}
+	h.VBlank()
+	h.Disable()
+	h.VBlank()
+	for i, colorIndex := range h.Frame {
+		if colorIndex != 2 {
+			t.Fatalf("pixel %d is shade %d while disabled, want 2", i, colorIndex)
+		}
+	}
+
+	// GIF recordings use the same disabled frame.
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "blank.gif")
+	h.Record(path)
+	h.VBlank() // Starts recording
+	h.VBlank()
+	h.StopRecord()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) == 0 {
+		t.Fatal("no frame recorded")
+	}
+	frame := anim.Image[0]
+	if frame.At(0, 0) != frame.Palette[2] || frame.At(ScreenWidth/2, ScreenHeight/2) != frame.Palette[2] {
+		t.Error("recorded disabled frame isn't solid shade 2")
+	}
+}
+
+func TestFrozenBlank(t *testing.T) {
+	last := make([]uint8, ScreenWidth*ScreenHeight)
+	last[42] = 3
+	frame := make([]uint8, len(last))
+	FrozenBlank(frame, last)
+	if frame[42] != 3 {
+		t.Error("frozen frame doesn't match the last one drawn")
+	}
+
+	BandBlank(frame, last)
+	if frame[0] != 0 || frame[ScreenHeight/2*ScreenWidth] != 3 {
+		t.Error("unexpected band frame")
+	}
+}
+
+func TestImageBlank(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, ColorBlack)
+	img.Set(1, 0, ColorLightGray)
+
+	frame := make([]uint8, ScreenWidth*ScreenHeight)
+	ImageBlank(img, DefaultPalette)(frame, nil)
+	if frame[0] != 3 || frame[1] != 1 || frame[2] != 0 || frame[ScreenWidth] != 0 {
+		t.Errorf("image converted to shades %v", frame[:3])
+	}
+
+	if _, err := ParseBlank("nope.png", DefaultPalette); err == nil {
+		t.Error("no error for a missing image")
+	}
+}
+
+func TestBlankOnce(t *testing.T) {
+	calls := 0
+	h := NewHeadless()
+	h.SetBlank(func(frame, last []uint8) {
+		calls++
+		FrozenBlank(frame, last)
+	})
+
+	// The blank frame is generated once while the display stays off.
+	h.Enable()
+	h.Write(3)
+	h.VBlank()
+	h.Disable()
+	for i := 0; i < 10; i++ {
+		h.VBlank()
+		if h.Frame[0] != 3 {
+			t.Fatalf("frozen frame %d lost the last frame drawn", i)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("blank frame generated %d times while disabled, want 1", calls)
+	}
+
+	// And again the next time it's turned off.
+	h.Enable()
+	h.Write(2)
+	h.VBlank()
+	h.Disable()
+	h.VBlank()
+	h.VBlank()
+	if calls != 2 || h.Frame[0] != 2 {
+		t.Errorf("blank frame generated %d times, showing shade %d, want 2 and 2",
+			calls, h.Frame[0])
+	}
+}
@@ -0,0 +1,147 @@
+//go:build js && wasm
+// +build js,wasm
+
+package screen
+
+import (
+	"image/color"
+	"syscall/js"
+	"time"
+)
+
+// Canvas display drawing frames to an HTML canvas through an ImageData buffer,
+// for running the emulator in a browser when built with GOOS=js GOARCH=wasm.
+// Text, screenshots and recordings aren't supported yet.
+type Canvas struct {
+	Palette color.Palette
+
+	// Blank generates what's shown while the LCD is off (the lightest shade
+	// by default).
+	Blank BlankFrame
+
+	canvas    js.Value
+	context   js.Value
+	imageData js.Value
+	data      js.Value // ImageData's Uint8ClampedArray
+
+	rgba       []byte
+	pixels     []uint8 // Color indices for the frame being drawn
+	blankFrame []uint8 // Color indices for the disabled screen
+	blanked    bool    // Whether blankFrame is up to date
+	offset     int
+	enabled    bool
+	flip       Flip
+}
+
+// NewCanvas returns a display drawing to the given canvas element, which is
+// resized to the Game Boy's screen. Zooming is best left to CSS.
+func NewCanvas(canvas js.Value) *Canvas {
+	canvas.Set("width", ScreenWidth)
+	canvas.Set("height", ScreenHeight)
+	context := canvas.Call("getContext", "2d")
+	imageData := context.Call("createImageData", ScreenWidth, ScreenHeight)
+	return &Canvas{
+		Palette:    DefaultPalette,
+		Blank:      SolidBlank(0),
+		canvas:     canvas,
+		context:    context,
+		imageData:  imageData,
+		data:       imageData.Get("data"),
+		rgba:       make([]byte, ScreenWidth*ScreenHeight*4),
+		pixels:     make([]uint8, ScreenWidth*ScreenHeight),
+		blankFrame: make([]uint8, ScreenWidth*ScreenHeight),
+	}
+}
+
+// SetFlip mirrors output on the canvas.
+func (c *Canvas) SetFlip(flip Flip) {
+	c.flip = flip
+}
+
+// Enable turns on the display.
+func (c *Canvas) Enable() {
+	c.enabled = true
+}
+
+// Enabled returns whether the display is enabled or not.
+func (c *Canvas) Enabled() bool {
+	return c.enabled
+}
+
+// Disable turns off the display. Disabled frames are generated by Blank, once
+// for the whole time the display is off.
+func (c *Canvas) Disable() {
+	c.offset = 0
+	c.enabled = false
+	c.blanked = false
+}
+
+// Close does nothing, the canvas belongs to the page.
+func (c *Canvas) Close() {}
+
+// Write adds a new pixel (a mere index into the palette) to the frame.
+func (c *Canvas) Write(colorIndex uint8) {
+	if c.offset < len(c.pixels) {
+		c.pixels[c.offset] = colorIndex
+		c.offset++
+	}
+}
+
+// HBlank is not needed for this display.
+func (c *Canvas) HBlank() {}
+
+// VBlank converts the frame to RGBA and copies it to the canvas.
+func (c *Canvas) VBlank() {
+	frame := c.pixels
+	if !c.enabled {
+		if !c.blanked {
+			c.Blank(c.blankFrame, c.pixels)
+			c.blanked = true
+		}
+		frame = c.blankFrame
+	}
+
+	for i, colorIndex := range frame {
+		r, g, b, a := c.Palette[colorIndex].RGBA()
+		c.rgba[i*4] = uint8(r >> 8)
+		c.rgba[i*4+1] = uint8(g >> 8)
+		c.rgba[i*4+2] = uint8(b >> 8)
+		c.rgba[i*4+3] = uint8(a >> 8)
+	}
+	FlipFrame(c.rgba, 4, c.flip)
+
+	js.CopyBytesToJS(c.data, c.rgba)
+	c.context.Call("putImageData", c.imageData, 0, 0)
+	c.offset = 0
+}
+
+// Text is not supported yet.
+func (c *Canvas) Text(text string) {}
+
+// Message is not supported yet.
+func (c *Canvas) Message(text string, duration time.Duration, priority Priority) {}
+
+// ToggleUI is not supported yet.
+func (c *Canvas) ToggleUI() {}
+
+// ToggleFullscreen switches the canvas to full screen and back.
+func (c *Canvas) ToggleFullscreen() {
+	document := js.Global().Get("document")
+	if document.Get("fullscreenElement").IsNull() {
+		c.canvas.Call("requestFullscreen")
+	} else {
+		document.Call("exitFullscreen")
+	}
+}
+
+// Resize does nothing, the canvas is scaled by the page.
+func (c *Canvas) Resize() {}
+
+// Screenshot is not supported yet.
+func (c *Canvas) Screenshot(filename string) {}
+
+// Record is not supported yet.
+func (c *Canvas) Record(filename string) {}
+
+// StopRecord is not supported yet.
+func (c *Canvas) StopRecord() {}
@@ -0,0 +1,53 @@
+package screen
+
+import "fmt"
+
+// Flip holds the axes along which output is mirrored, after rendering.
+type Flip uint8
+
+// Flip axes, which can be combined.
+const (
+	FlipHorizontal Flip = 1 << iota // Mirror left and right
+	FlipVertical                    // Mirror top and bottom
+)
+
+// ParseFlip returns the flip setting with the given name: none, h
+// (horizontal), v (vertical) or hv (both).
+func ParseFlip(name string) (Flip, error) {
+	switch name {
+	case "", "none":
+		return 0, nil
+	case "h":
+		return FlipHorizontal, nil
+	case "v":
+		return FlipVertical, nil
+	case "hv", "vh":
+		return FlipHorizontal | FlipVertical, nil
+	}
+	return 0, fmt.Errorf("unknown flip %q (expected none, h, v or hv)", name)
+}
+
+// FlipFrame mirrors a full screen frame in place, given its pixel data and
+// the number of bytes per pixel (1 for color indices, 4 for RGBA).
+func FlipFrame(pix []uint8, bytesPerPixel int, flip Flip) {
+	stride := ScreenWidth * bytesPerPixel
+	if flip&FlipHorizontal != 0 {
+		for y := 0; y < ScreenHeight; y++ {
+			line := pix[y*stride : (y+1)*stride]
+			for l, r := 0, stride-bytesPerPixel; l < r; l, r = l+bytesPerPixel, r-bytesPerPixel {
+				for i := 0; i < bytesPerPixel; i++ {
+					line[l+i], line[r+i] = line[r+i], line[l+i]
+				}
+			}
+		}
+	}
+	if flip&FlipVertical != 0 {
+		for top, bottom := 0, ScreenHeight-1; top < bottom; top, bottom = top+1, bottom-1 {
+			t := pix[top*stride : (top+1)*stride]
+			b := pix[bottom*stride : (bottom+1)*stride]
+			for i := range t {
+				t[i], b[i] = b[i], t[i]
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package screen
+
+import "testing"
+
+func TestFlip(t *testing.T) {
+	// Source frame with a unique shade at the top-left pixel and another one at
+	// the start of the second line.
+	source := func(x, y int) uint8 {
+		switch {
+		case x == 0 && y == 0:
+			return 3
+		case x == 0 && y == 1:
+			return 2
+		}
+		return 0
+	}
+
+	for name, flip := range map[string]Flip{
+		"h":  FlipHorizontal,
+		"v":  FlipVertical,
+		"hv": FlipHorizontal | FlipVertical,
+	} {
+		parsed, err := ParseFlip(name)
+		if err != nil || parsed != flip {
+			t.Fatalf("ParseFlip(%q) = %v, %v", name, parsed, err)
+		}
+
+		h := NewHeadless()
+		h.SetFlip(flip)
+		h.Enable()
+		for y := 0; y < ScreenHeight; y++ {
+			for x := 0; x < ScreenWidth; x++ {
+				h.Write(source(x, y))
+			}
+		}
+		h.VBlank()
+
+		for y := 0; y < ScreenHeight; y++ {
+			for x := 0; x < ScreenWidth; x++ {
+				srcX, srcY := x, y
+				if flip&FlipHorizontal != 0 {
+					srcX = ScreenWidth - 1 - x
+				}
+				if flip&FlipVertical != 0 {
+					srcY = ScreenHeight - 1 - y
+				}
+				if got, want := h.Frame[y*ScreenWidth+x], source(srcX, srcY); got != want {
+					t.Fatalf("flip %s: pixel (%d,%d) is shade %d, want %d", name, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	if _, err := ParseFlip("diagonal"); err == nil {
+		t.Error("no error for an unknown flip")
+	}
+}
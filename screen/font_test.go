@@ -0,0 +1,39 @@
+package screen
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestBitmapText(t *testing.T) {
+	fg := color.RGBA{A: 0xff}
+	bg := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	img := RenderBitmapText("I!", 2, fg, bg)
+
+	// Two glyphs and their outline, zoomed in.
+	if w, h := img.Rect.Dx(), img.Rect.Dy(); w != (2*6+1)*2 || h != 9*2 {
+		t.Fatalf("text image is %dx%d", w, h)
+	}
+
+	// The I's top bar starts one (zoomed) pixel in, with outline around it.
+	if img.RGBAAt(4, 2) != fg || img.RGBAAt(5, 3) != fg {
+		t.Error("glyph pixels not drawn in the foreground color")
+	}
+	if img.RGBAAt(2, 2) != bg || img.RGBAAt(4, 0) != bg {
+		t.Error("outline not drawn in the background color")
+	}
+	if img.RGBAAt(0, 17).A != 0 {
+		t.Error("pixels away from the text aren't transparent")
+	}
+
+	// The exclamation mark has a gap above its dot.
+	x := (6 + 1 + 2) * 2
+	if img.RGBAAt(x, (1+5)*2) == fg || img.RGBAAt(x, (1+6)*2) != fg {
+		t.Error("unexpected exclamation mark")
+	}
+
+	// Unknown characters are drawn as question marks.
+	if glyph('é') != glyph('?') || glyph('•') == glyph('?') {
+		t.Error("unexpected fallback glyphs")
+	}
+}
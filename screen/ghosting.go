@@ -0,0 +1,74 @@
+package screen
+
+import (
+	"image/color"
+	"math"
+)
+
+// GhostSteps is the number of colors used from one shade to the next in
+// ghosted frames, so that fading pixels can be told apart from actual shades.
+const GhostSteps = 8
+
+// Ghosting emulates the slow pixel response of DMG LCDs by blending each frame
+// with what was shown before, which leaves fading trails behind moving
+// objects. Blended frames use the colors from GhostPalette.
+type Ghosting struct {
+	// Decay is the fraction of the previous frame kept in the next one, from
+	// 0 (no ghosting) to 1 (the first frame stays forever).
+	Decay float64
+
+	levels []float64 // Blended shade of each pixel in the last frame
+}
+
+// NewGhosting returns a frame blender keeping the given fraction of each
+// frame in the next one. Values outside 0-1 are clamped.
+func NewGhosting(decay float64) *Ghosting {
+	return &Ghosting{Decay: math.Max(0, math.Min(decay, 1))}
+}
+
+// Reset forgets previous frames, so that the next one is used as is.
+func (g *Ghosting) Reset() {
+	g.levels = nil
+}
+
+// Blend mixes a frame of shades 0 to 3 with the decayed previous frame and
+// returns the result as indices in GhostPalette. The first frame after a
+// reset is only converted.
+func (g *Ghosting) Blend(frame []uint8) []uint8 {
+	blended := make([]uint8, len(frame))
+	if len(g.levels) != len(frame) {
+		g.levels = make([]float64, len(frame))
+		for i, shade := range frame {
+			g.levels[i] = float64(shade)
+		}
+	} else {
+		for i, shade := range frame {
+			g.levels[i] = float64(shade)*(1-g.Decay) + g.levels[i]*g.Decay
+		}
+	}
+	for i, level := range g.levels {
+		blended[i] = uint8(math.Round(level * GhostSteps))
+	}
+	return blended
+}
+
+// GhostPalette returns the colors for blended frames given the colors for
+// shades 0 to 3: each shade followed by GhostSteps-1 colors fading into the
+// next one.
+func GhostPalette(palette color.Palette) color.Palette {
+	ghost := make(color.Palette, 0, (len(palette)-1)*GhostSteps+1)
+	for shade := 0; shade < len(palette)-1; shade++ {
+		from := color.RGBAModel.Convert(palette[shade]).(color.RGBA)
+		to := color.RGBAModel.Convert(palette[shade+1]).(color.RGBA)
+		for step := 0; step < GhostSteps; step++ {
+			t := float64(step) / GhostSteps
+			mix := func(a, b uint8) uint8 {
+				return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+			}
+			ghost = append(ghost, color.RGBA{
+				mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), mix(from.A, to.A),
+			})
+		}
+	}
+	return append(ghost, palette[len(palette)-1])
+}
@@ -0,0 +1,71 @@
+package screen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGhostingTrail(t *testing.T) {
+	g := NewGhosting(0.5)
+
+	// A black pixel moving right by one pixel per frame.
+	var blended []uint8
+	for x := 0; x < 3; x++ {
+		frame := make([]uint8, 4)
+		frame[x] = 3
+		blended = g.Blend(frame)
+	}
+
+	// The first frame is used as is, then each frame halves what's left at
+	// previous positions (0.75 in shades), while the new one only gets halfway
+	// to black (1.5).
+	want := []uint8{3 * GhostSteps / 4, 3 * GhostSteps / 4, 3 * GhostSteps / 2, 0}
+	for x := range want {
+		if blended[x] != want[x] {
+			t.Errorf("pixel %d has ghost level %d, want %d", x, blended[x], want[x])
+		}
+	}
+
+	palette := GhostPalette(DefaultPalette)
+	if len(palette) != 3*GhostSteps+1 {
+		t.Fatalf("ghost palette has %d colors, want %d", len(palette), 3*GhostSteps+1)
+	}
+	for shade, c := range DefaultPalette {
+		if palette[shade*GhostSteps] != c {
+			t.Errorf("ghost palette doesn't have shade %d at index %d", shade, shade*GhostSteps)
+		}
+	}
+}
+
+func TestGhostingGIF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := NewGIF(1)
+	g.Ghosting = NewGhosting(0.5)
+	g.Open(filepath.Join(dir, "ghost.gif"))
+	defer g.Close()
+
+	// A black frame, then white ones fading out.
+	for frame := 0; frame < 3; frame++ {
+		shade := uint8(0)
+		if frame == 0 {
+			shade = 3
+		}
+		for i := 0; i < ScreenWidth*ScreenHeight; i++ {
+			g.Write(shade)
+		}
+		g.SaveFrame()
+	}
+
+	for i, want := range []uint8{3 * GhostSteps, 3 * GhostSteps / 2, 3 * GhostSteps / 4} {
+		if level := g.Image[i].Pix[0]; level != want {
+			t.Errorf("frame #%d has ghost level %d, want %d", i, level, want)
+		}
+	}
+}
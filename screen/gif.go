@@ -3,7 +3,7 @@ package screen
 import (
 	"bytes"
 	"image"
-	"image/draw"
+	"image/color"
 	"image/gif"
 	"os"
 )
@@ -19,7 +19,9 @@ const FrameDelay = (1 / 59.7) * 100
 var FrameBounds = image.Rectangle{Min: image.Point{0, 0},
 	Max: image.Point{X: ScreenWidth, Y: ScreenHeight}}
 
-// GIF recorder generating animated images on the fly.
+// GIF recorder generating animated images on the fly. Pixels are expected to
+// be shades that already went through the DMG palette registers (BGP, OBP0 or
+// OBP1), so that palette effects are recorded as seen on screen.
 type GIF struct {
 	gif.GIF
 
@@ -33,33 +35,44 @@ type GIF struct {
 	delay     float32         // Current frame's delay
 	offset    uint            // Current frame's current pixel offset
 
-	disabled *image.Paletted // Disabled screen frame
+	// Skip is the number of frames dropped after Open before recording
+	// actually starts, e.g. to trim the boot logo animation.
+	Skip    uint
+	skipped uint
+
+	// Flip mirrors frames before they're added to the recording.
+	Flip Flip
+
+	// Blank generates disabled screen frames. Defaults to BandBlank so that
+	// those frames stand out in recordings.
+	Blank BlankFrame
+
+	// Ghosting, if set, blends each frame with the previous ones before it's
+	// added to the recording, which then uses colors from GhostPalette.
+	Ghosting *Ghosting
+
+	drawn   []uint8       // Color indices for the last frame actually drawn
+	palette color.Palette // Colors for shades 0-3
 }
 
 // NewGIF instantiates a GIF recorder that will buffer frames and then output a
 // GIF file when required.
 func NewGIF(zoomFactor uint) *GIF {
 	// TODO: check file access, (pre-create it?)
+	g := &GIF{Blank: BandBlank, drawn: make([]uint8, ScreenWidth*ScreenHeight)}
+	g.SetPalette(DefaultPalette)
+	return g
+}
 
-	// Pre-instantiate disabled screen frame.
-	disabled := image.NewPaletted(FrameBounds, DefaultPalette)
-	draw.Draw(disabled, disabled.Bounds(), &image.Uniform{DefaultPalette[0]}, image.Point{}, draw.Src)
-	middle := disabled.Bounds()
-	middle.Min.Y /= 2
-	middle.Max.Y = (middle.Max.Y / 2) + 1
-	draw.Draw(disabled, middle, &image.Uniform{DefaultPalette[3]}, image.Point{}, draw.Src)
-
-	config := image.Config{
-		ColorModel: disabled.ColorModel(),
+// SetPalette sets the colors used for shades 0 to 3 in GIF files created from
+// now on, to match the display's.
+func (g *GIF) SetPalette(palette color.Palette) {
+	g.config = image.Config{
+		ColorModel: palette,
 		Width:      ScreenWidth,
 		Height:     ScreenHeight,
 	}
-
-	return &GIF{
-		disabled:  disabled,
-		config:    config,
-		lastFrame: disabled, // Acceptable zero value to avoid a nil check later
-	}
+	g.palette = palette
 }
 
 // Write adds a new pixel to the current GIF frame.
@@ -70,15 +83,30 @@ func (g *GIF) Write(colorIndex uint8) {
 
 // SaveFrame adds the current frame to GIF slice and pre-instantiate next. We
 // detect if the display was disabled. If so, save a "disabled screen" frame
-// instead.
+// (see GIF.Blank) instead.
 func (g *GIF) SaveFrame() {
+	// Drop frames until the delayed start.
+	if g.skipped < g.Skip {
+		g.skipped++
+		g.offset = 0
+		return
+	}
+
 	// Pixel offset should be at the very end of the frame. If not, screen was
 	// off and we save the "disabled" frame instead.
 	var currentFrame *image.Paletted
 	if g.offset == 0 {
-		currentFrame = g.disabled
+		currentFrame = image.NewPaletted(FrameBounds, g.palette)
+		g.Blank(currentFrame.Pix, g.drawn)
 	} else {
 		currentFrame = g.frame
+		copy(g.drawn, g.frame.Pix)
+	}
+	FlipFrame(currentFrame.Pix, 1, g.Flip)
+	if g.Ghosting != nil {
+		ghosted := image.NewPaletted(FrameBounds, g.GIF.Config.ColorModel.(color.Palette))
+		ghosted.Pix = g.Ghosting.Blend(currentFrame.Pix)
+		currentFrame = ghosted
 	}
 
 	// If current frame is the same as the previous one, only update delay of
@@ -89,9 +117,9 @@ func (g *GIF) SaveFrame() {
 	} else {
 		g.delay = FrameDelay
 		g.lastFrame = currentFrame
-		g.GIF.Image = append(g.GIF.Image, g.frame)
+		g.GIF.Image = append(g.GIF.Image, currentFrame)
 		g.GIF.Delay = append(g.GIF.Delay, 2) // GIF players poorly handle 10ms frames delay
-		g.frame = image.NewPaletted(FrameBounds, DefaultPalette)
+		g.frame = image.NewPaletted(FrameBounds, g.palette)
 	}
 
 	g.offset = 0
@@ -120,11 +148,16 @@ func (g *GIF) Open(filename string) {
 	log.Sub("gif").Infof("recording to %s", filename)
 
 	g.GIF = gif.GIF{Config: g.config}
-	g.frame = image.NewPaletted(FrameBounds, DefaultPalette)
+	if g.Ghosting != nil {
+		g.GIF.Config.ColorModel = GhostPalette(g.palette)
+		g.Ghosting.Reset()
+	}
+	g.frame = image.NewPaletted(FrameBounds, g.palette)
 	g.lastFrame = nil
 	g.Filename = filename
 	g.fd = fd
 	g.offset = 0
+	g.skipped = 0
 
 	// TODO: create file here, store descriptor for later. Better yet: stream frames to disk.
 }
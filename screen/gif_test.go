@@ -0,0 +1,39 @@
+package screen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGIFSkip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := NewGIF(1)
+	g.Skip = 3
+	g.Open(filepath.Join(dir, "skip.gif"))
+	defer g.Close()
+
+	// Each frame is filled with its number as a shade, so the first three
+	// frames (shades 0, 1 and 2) should be dropped.
+	for frame := 0; frame < 5; frame++ {
+		for i := 0; i < ScreenWidth*ScreenHeight; i++ {
+			g.Write(uint8(frame % 4))
+		}
+		g.SaveFrame()
+	}
+
+	if len(g.Image) != 2 {
+		t.Fatalf("recorded %d frames, want 2", len(g.Image))
+	}
+	for i, want := range []uint8{3, 0} {
+		if shade := g.Image[i].Pix[0]; shade != want {
+			t.Errorf("frame #%d has shade %d, want %d", i, shade, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package screen
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// Headless display keeping the last full frame in memory instead of showing
+// it anywhere. Useful for tests and running the emulator without a window.
+type Headless struct {
+	// Frame holds color indices for the last frame completed at VBlank time.
+	Frame [ScreenWidth * ScreenHeight]uint8
+
+	// Frames counts how many VBlanks occurred so far.
+	Frames uint
+
+	// Blank generates what's in Frame while the LCD is off (the lightest
+	// shade by default). Use SetBlank to also apply it to GIF recordings.
+	Blank BlankFrame
+
+	buffer  [ScreenWidth * ScreenHeight]uint8
+	blank   [ScreenWidth * ScreenHeight]uint8 // Frame generated by Blank
+	blanked bool                              // Whether blank is up to date
+	flip    Flip
+	offset  int
+	enabled bool
+
+	gif        *GIF
+	recordPath string // Set to start recording at next VBlank
+}
+
+// NewHeadless returns a display that renders to memory only.
+func NewHeadless() *Headless {
+	return &Headless{gif: NewGIF(1), Blank: SolidBlank(0)}
+}
+
+// SetFlip mirrors frames, including those recorded to GIF files.
+func (h *Headless) SetFlip(flip Flip) {
+	h.flip = flip
+	h.gif.Flip = flip
+}
+
+// SetBlank sets what's shown while the LCD is off, in frames and GIF files.
+func (h *Headless) SetBlank(blank BlankFrame) {
+	h.Blank = blank
+	h.gif.Blank = blank
+	h.blanked = false
+}
+
+// Enable turns on the display.
+func (h *Headless) Enable() {
+	h.enabled = true
+}
+
+// Enabled returns whether the display is enabled or not.
+func (h *Headless) Enabled() bool {
+	return h.enabled
+}
+
+// Disable turns off the display. Disabled frames are generated by Blank, once
+// for the whole time the display is off.
+func (h *Headless) Disable() {
+	h.offset = 0
+	h.enabled = false
+	h.blanked = false
+}
+
+// Close writes the GIF being recorded, if any.
+func (h *Headless) Close() {
+	h.StopRecord()
+}
+
+// Write adds a new pixel to the frame being drawn.
+func (h *Headless) Write(colorIndex uint8) {
+	if h.enabled && h.offset < len(h.buffer) {
+		h.buffer[h.offset] = colorIndex
+		h.offset++
+
+		if h.gif.IsOpen() {
+			h.gif.Write(colorIndex)
+		}
+	}
+}
+
+// HBlank does nothing, as part of the Display interface.
+func (h *Headless) HBlank() {}
+
+// VBlank copies the frame that was just drawn so it can be examined.
+func (h *Headless) VBlank() {
+	if h.enabled {
+		h.Frame = h.buffer
+	} else {
+		if !h.blanked {
+			// The buffer still holds the last frame drawn.
+			h.Blank(h.blank[:], h.buffer[:])
+			h.blanked = true
+		}
+		h.Frame = h.blank
+	}
+	FlipFrame(h.Frame[:], 1, h.flip)
+	h.offset = 0
+	h.Frames++
+
+	if h.gif.IsOpen() {
+		h.gif.SaveFrame()
+	}
+	if h.recordPath != "" {
+		h.gif.Open(h.recordPath)
+		h.recordPath = ""
+	}
+}
+
+// Hash returns a 64-bit FNV-1a hash of the last complete frame, for quick
+// comparison against known-good output.
+func (h *Headless) Hash() uint64 {
+	hash := fnv.New64a()
+	hash.Write(h.Frame[:])
+	return hash.Sum64()
+}
+
+// Text does nothing, there is no UI to display it on.
+func (h *Headless) Text(text string) {}
+
+// Message does nothing, there is no UI to display it on.
+func (h *Headless) Message(text string, duration time.Duration, priority Priority) {}
+
+// ToggleUI does nothing, there is no UI.
+func (h *Headless) ToggleUI() {}
+
+// ToggleFullscreen does nothing, there is no window.
+func (h *Headless) ToggleFullscreen() {}
+
+// Resize does nothing, there is no window.
+func (h *Headless) Resize() {}
+
+// Screenshot is not supported without a window.
+func (h *Headless) Screenshot(filename string) {}
+
+// Record starts recording frames to a GIF file from the next VBlank.
+func (h *Headless) Record(filename string) {
+	h.recordPath = filename
+}
+
+// StopRecord writes the GIF being recorded, if any.
+func (h *Headless) StopRecord() {
+	h.recordPath = ""
+	if h.gif.IsOpen() {
+		h.gif.Close()
+	}
+}
+
+// SetGhosting blends each frame with the given fraction of the previous ones
+// in GIF recordings (see Ghosting), or disables ghosting if 0.
+func (h *Headless) SetGhosting(decay float64) {
+	h.gif.Ghosting = nil
+	if decay > 0 {
+		h.gif.Ghosting = NewGhosting(decay)
+	}
+}
+
+// SetRecordSkip sets how many frames are dropped at the start of each GIF
+// recording (see GIF.Skip).
+func (h *Headless) SetRecordSkip(frames uint) {
+	h.gif.Skip = frames
+}
@@ -0,0 +1,113 @@
+package screen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImageEncoder writes an image to w in a given file format.
+type ImageEncoder func(w io.Writer, img image.Image) error
+
+// EncoderFor returns the image encoder to use for the given file, based on its
+// extension (.png, .bmp, .jpg or .jpeg). Quality only applies to JPEG and
+// ranges from 1 to 100 (zero meaning jpeg.DefaultQuality).
+func EncoderFor(filename string, quality int) (ImageEncoder, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".png":
+		return png.Encode, nil
+	case ".bmp":
+		return encodeBMP, nil
+	case ".jpg", ".jpeg":
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported image format %q", ext)
+}
+
+// SaveImage writes an image to the given file, in the format matching its
+// extension (see EncoderFor).
+func SaveImage(filename string, img image.Image, quality int) error {
+	encode, err := EncoderFor(filename, quality)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Writes an image as an uncompressed 24-bit BMP file. The standard library
+// doesn't come with a BMP encoder, but the format is simple enough.
+func encodeBMP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	rowSize := (width*3 + 3) &^ 3 // Rows are padded to 4 bytes.
+	const headerSize = 14 + 40
+
+	header := struct {
+		// File header.
+		Magic      [2]byte
+		FileSize   uint32
+		Reserved   uint32
+		DataOffset uint32
+
+		// BITMAPINFOHEADER.
+		InfoSize      uint32
+		Width, Height int32
+		Planes        uint16
+		BitsPerPixel  uint16
+		Compression   uint32
+		ImageSize     uint32
+		XPPM, YPPM    int32
+		Colors        uint32
+		Important     uint32
+	}{
+		Magic:        [2]byte{'B', 'M'},
+		FileSize:     uint32(headerSize + rowSize*height),
+		DataOffset:   headerSize,
+		InfoSize:     40,
+		Width:        int32(width),
+		Height:       int32(height),
+		Planes:       1,
+		BitsPerPixel: 24,
+		ImageSize:    uint32(rowSize * height),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	// Pixels are stored bottom-up, in BGR order.
+	row := make([]byte, rowSize)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, y).RGBA()
+			row[x*3+0] = uint8(b >> 8)
+			row[x*3+1] = uint8(g >> 8)
+			row[x*3+2] = uint8(r >> 8)
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package screen
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestEncoderFor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
+
+	cases := map[string][]byte{
+		"shot.png":  []byte("\x89PNG"),
+		"shot.BMP":  []byte("BM"),
+		"shot.jpg":  {0xff, 0xd8},
+		"shot.jpeg": {0xff, 0xd8},
+	}
+	for filename, magic := range cases {
+		encode, err := EncoderFor(filename, 0)
+		if err != nil {
+			t.Errorf("no encoder for %s: %v", filename, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := encode(&buf, img); err != nil {
+			t.Errorf("encoding %s: %v", filename, err)
+			continue
+		}
+		if !bytes.HasPrefix(buf.Bytes(), magic) {
+			t.Errorf("%s starts with % x, want % x", filename, buf.Bytes()[:4], magic)
+		}
+	}
+
+	if _, err := EncoderFor("shot.gif", 0); err == nil {
+		t.Error("no error for unsupported format")
+	}
+}
+
+func TestEncodeBMP(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 1, ColorBlack) // Bottom-left pixel comes first.
+
+	var buf bytes.Buffer
+	if err := encodeBMP(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	rowSize := 12 // 3 pixels × 3 bytes, padded to 4.
+	if buf.Len() != 54+rowSize*2 {
+		t.Fatalf("BMP is %d bytes, want %d", buf.Len(), 54+rowSize*2)
+	}
+	pixel := buf.Bytes()[54:57]
+	if want := []byte{ColorBlackB, ColorBlackG, ColorBlackR}; !bytes.Equal(pixel, want) {
+		t.Errorf("first pixel is % x, want % x", pixel, want)
+	}
+}
@@ -0,0 +1,125 @@
+package screen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Header of palette files in JASC-PAL (Paint Shop Pro) format.
+const jascHeader = "JASC-PAL"
+
+// LoadPalette reads a palette file and returns its first four colors, to be
+// used as the DMG shades from lightest to darkest. See ParsePalette for the
+// supported formats.
+func LoadPalette(filename string) (color.Palette, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePalette(data)
+}
+
+// ParsePalette decodes a palette in either JASC-PAL text format or raw binary
+// format made of 4-byte entries (red, green, blue, padding) like the 56-byte
+// .pal files some emulators export. Only the first four colors are kept.
+func ParsePalette(data []byte) (color.Palette, error) {
+	if bytes.HasPrefix(data, []byte(jascHeader)) {
+		return parseJASC(data)
+	}
+	return parseRawPalette(data)
+}
+
+// Parses a JASC-PAL file, i.e. a header, version, color count and one line per
+// color with space-separated decimal RGB values.
+func parseJASC(data []byte) (color.Palette, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 3 {
+		return nil, fmt.Errorf("truncated JASC palette")
+	}
+
+	count, err := strconv.Atoi(lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid JASC color count %q", lines[2])
+	}
+	if count < 4 || len(lines)-3 < 4 {
+		return nil, fmt.Errorf("JASC palette has fewer than 4 colors")
+	}
+
+	palette := make(color.Palette, 4)
+	for i, line := range lines[3:7] {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid JASC color %q", line)
+		}
+		var rgb [3]uint8
+		for j, field := range fields {
+			value, err := strconv.ParseUint(field, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JASC color %q", line)
+			}
+			rgb[j] = uint8(value)
+		}
+		palette[i] = color.RGBA{rgb[0], rgb[1], rgb[2], 0xff}
+	}
+	return palette, nil
+}
+
+// Parses a binary palette made of 4-byte color entries.
+func parseRawPalette(data []byte) (color.Palette, error) {
+	if len(data) < 16 || len(data)%4 != 0 {
+		return nil, fmt.Errorf("unsupported palette format (%d bytes)", len(data))
+	}
+
+	palette := make(color.Palette, 4)
+	for i := range palette {
+		entry := data[i*4:]
+		palette[i] = color.RGBA{entry[0], entry[1], entry[2], 0xff}
+	}
+	return palette, nil
+}
+
+// PalettePreset is a named set of colors for the four DMG shades.
+type PalettePreset struct {
+	Name    string
+	Palette color.Palette
+}
+
+// PalettePresets lists the built-in palettes, the default one first.
+var PalettePresets = []PalettePreset{
+	{"Default", DefaultPalette},
+	{"Grayscale", color.Palette{
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+		color.RGBA{0xaa, 0xaa, 0xaa, 0xff},
+		color.RGBA{0x55, 0x55, 0x55, 0xff},
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+	}},
+	{"Green", color.Palette{
+		color.RGBA{0x9b, 0xbc, 0x0f, 0xff},
+		color.RGBA{0x8b, 0xac, 0x0f, 0xff},
+		color.RGBA{0x30, 0x62, 0x30, 0xff},
+		color.RGBA{0x0f, 0x38, 0x0f, 0xff},
+	}},
+	{"Amber", color.Palette{
+		color.RGBA{0xff, 0xc8, 0x5a, 0xff},
+		color.RGBA{0xc8, 0x8c, 0x28, 0xff},
+		color.RGBA{0x7a, 0x4a, 0x0c, 0xff},
+		color.RGBA{0x2a, 0x16, 0x00, 0xff},
+	}},
+	{"BGB", color.Palette{
+		color.RGBA{0xe0, 0xf8, 0xd0, 0xff},
+		color.RGBA{0x88, 0xc0, 0x70, 0xff},
+		color.RGBA{0x34, 0x68, 0x56, 0xff},
+		color.RGBA{0x08, 0x18, 0x20, 0xff},
+	}},
+}
@@ -0,0 +1,43 @@
+package screen
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParsePaletteJASC(t *testing.T) {
+	data := []byte("JASC-PAL\r\n0100\r\n4\r\n224 248 208\r\n136 192 112\r\n52 104 86\r\n8 24 32\r\n")
+	expected := color.Palette{
+		color.RGBA{224, 248, 208, 0xff},
+		color.RGBA{136, 192, 112, 0xff},
+		color.RGBA{52, 104, 86, 0xff},
+		color.RGBA{8, 24, 32, 0xff},
+	}
+
+	palette, err := ParsePalette(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range expected {
+		if palette[i] != expected[i] {
+			t.Errorf("color %d is %v, want %v", i, palette[i], expected[i])
+		}
+	}
+}
+
+func TestParsePaletteRaw(t *testing.T) {
+	data := make([]byte, 56)
+	copy(data, []byte{0xff, 0xef, 0xce, 0, 0xde, 0x94, 0x4a, 0, 0xad, 0x29, 0x21, 0, 0x31, 0x18, 0x52, 0})
+
+	palette, err := ParsePalette(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if palette[1] != (color.RGBA{0xde, 0x94, 0x4a, 0xff}) {
+		t.Errorf("color 1 is %v", palette[1])
+	}
+
+	if _, err := ParsePalette([]byte("JASC-PAL\n0100\n2\n0 0 0\n1 1 1\n")); err == nil {
+		t.Error("no error for JASC palette with 2 colors")
+	}
+}
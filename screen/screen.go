@@ -1,6 +1,7 @@
 package screen
 
 import (
+	"image"
 	"image/color"
 	"time"
 
@@ -17,6 +18,17 @@ func init() {
 // ColorIndex into a display-defined 4-color palette.
 type ColorIndex uint8
 
+// Priority of a UI message. A message doesn't replace one with a higher
+// priority, it waits for that one to expire instead.
+type Priority uint8
+
+// Message priorities.
+const (
+	PriorityInfo    Priority = iota // Routine notifications
+	PriorityWarning                 // Something didn't go as planned
+	PriorityError                   // Something failed
+)
+
 // Palette containing 4 indexed colors.
 type Palette [4]color.NRGBA
 
@@ -31,7 +43,10 @@ type Display interface {
 	VBlank()
 
 	Text(text string)
-	Message(text string, duration time.Duration)
+	Message(text string, duration time.Duration, priority Priority)
+	ToggleUI()
+	ToggleFullscreen()
+	Resize()
 
 	Screenshot(filename string)
 
@@ -45,6 +60,39 @@ const (
 	ScreenHeight = 144
 )
 
+// Viewport returns the largest area of a width×height output where the screen
+// fits using an integer scale factor, centered so that the remaining space is
+// left as black borders (letterboxing). Scale never goes below 1.
+func Viewport(width, height int) image.Rectangle {
+	scale := width / ScreenWidth
+	if s := height / ScreenHeight; s < scale {
+		scale = s
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	w, h := ScreenWidth*scale, ScreenHeight*scale
+	x, y := (width-w)/2, (height-h)/2
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// FitViewport returns the largest area of a width×height output where the
+// screen fits while keeping its 10:9 aspect ratio, centered so that the
+// remaining space is left as black borders (letterboxing). Unlike Viewport,
+// the scale factor doesn't have to be an integer.
+func FitViewport(width, height int) image.Rectangle {
+	w, h := width, height
+	if width*ScreenHeight > height*ScreenWidth {
+		w = height * ScreenWidth / ScreenHeight
+	} else {
+		h = width * ScreenHeight / ScreenWidth
+	}
+
+	x, y := (width-w)/2, (height-h)/2
+	return image.Rect(x, y, x+w, y+h)
+}
+
 // Default palette colors with separate RGB components for easier use with SDL
 // API. Kinda greenish.
 const (
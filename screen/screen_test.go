@@ -0,0 +1,44 @@
+package screen
+
+import (
+	"image"
+	"testing"
+)
+
+func TestViewport(t *testing.T) {
+	cases := []struct {
+		w, h int
+		want image.Rectangle
+	}{
+		{160, 144, image.Rect(0, 0, 160, 144)},
+		{320, 288, image.Rect(0, 0, 320, 288)},
+		{1920, 1080, image.Rect(400, 36, 1520, 1044)}, // 7x, pillarboxed
+		{640, 1024, image.Rect(0, 224, 640, 800)},     // 4x, letterboxed
+		{100, 100, image.Rect(-30, -22, 130, 122)},    // Never below 1x
+	}
+
+	for _, c := range cases {
+		if got := Viewport(c.w, c.h); got != c.want {
+			t.Errorf("Viewport(%d, %d) == %v, want %v", c.w, c.h, got, c.want)
+		}
+	}
+}
+
+func TestFitViewport(t *testing.T) {
+	cases := []struct {
+		w, h int
+		want image.Rectangle
+	}{
+		{160, 144, image.Rect(0, 0, 160, 144)},
+		{400, 360, image.Rect(0, 0, 400, 360)},    // 2.5x
+		{800, 400, image.Rect(178, 0, 622, 400)},  // Pillarboxed
+		{500, 1000, image.Rect(0, 275, 500, 725)}, // Letterboxed
+		{1920, 1080, image.Rect(360, 0, 1560, 1080)},
+	}
+
+	for _, c := range cases {
+		if got := FitViewport(c.w, c.h); got != c.want {
+			t.Errorf("FitViewport(%d, %d) == %v, want %v", c.w, c.h, got, c.want)
+		}
+	}
+}
@@ -1,10 +1,12 @@
+//go:build !js
+// +build !js
+
 package screen
 
 import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
 	"io/ioutil"
 	"os"
 	"time"
@@ -16,16 +18,29 @@ import (
 // SDL display shifting pixels out to a single texture.
 type SDL struct {
 	*UI
-	Palette    color.Palette
-	enabled    bool
-	window     *sdl.Window
-	renderer   *sdl.Renderer
-	texture    *sdl.Texture
-	blank      *sdl.Texture
-	buffer     []byte
-	offset     int
-	zoom       int // Zoom factor applied to the 144×160 screen.
-	screenRect image.Rectangle
+	Palette     color.Palette
+	JPEGQuality int // Quality of JPEG screenshots (1-100).
+	enabled     bool
+	window      *sdl.Window
+	renderer    *sdl.Renderer
+	texture     *sdl.Texture
+	blank       *sdl.Texture
+	buffer      []byte
+	pixels      []uint8 // Color indices for the last frame drawn
+	blankFrame  []uint8 // Color indices for the disabled screen
+	blanked     bool    // Whether the blank texture is up to date
+	offset      int
+	zoom        int // Zoom factor applied to the 144×160 screen.
+	screenRect  image.Rectangle
+	viewport    sdl.Rect // Where the screen is drawn in the window.
+	fullscreen  bool
+
+	// Mirrors output on screen, in screenshots and GIF files (see SetFlip).
+	flip Flip
+
+	// Blank generates what's shown while the LCD is off (the lightest shade
+	// by default). Use SetBlank to also apply it to GIF recordings.
+	Blank BlankFrame
 
 	// Set this to non-empty to save the next frame. Will be reset at VBlank.
 	screenshotPath string
@@ -51,11 +66,15 @@ func NewSDL(zoomFactor uint, vSync bool) *SDL {
 	window, err := sdl.CreateWindow("Goholint",
 		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
 		ScreenWidth*int32(zoomFactor), ScreenHeight*int32(zoomFactor),
-		sdl.WINDOW_SHOWN)
+		sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create window: %s\n", err)
 		return nil // TODO: result, err
 	}
+	window.SetMinimumSize(ScreenWidth, ScreenHeight)
+
+	// Keep pixels sharp when scaling the screen to the window size.
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest")
 
 	// FIXME: embed assets.
 	icon, err := img.Load("assets/icon.png")
@@ -103,10 +122,10 @@ func NewSDL(zoomFactor uint, vSync bool) *SDL {
 		return nil // TODO: result, err
 	}
 
-	// Also pre-instantiate disabled screen texture as it'll be used a lot.
+	// Disabled screen texture, filled at VBlank time (see SDL.Blank).
 	blank, err := renderer.CreateTexture(
-		sdl.PIXELFORMAT_RGBA8888,
-		sdl.TEXTUREACCESS_TARGET,
+		sdl.PIXELFORMAT_ABGR8888,
+		sdl.TEXTUREACCESS_STATIC,
 		ScreenWidth,
 		ScreenHeight)
 	if err != nil {
@@ -116,10 +135,6 @@ func NewSDL(zoomFactor uint, vSync bool) *SDL {
 		fmt.Fprintf(os.Stderr, "Failed to create blank texture: %s\n", err)
 		return nil // TODO: result, err
 	}
-	renderer.SetRenderTarget(blank)
-	renderer.SetDrawColor(ColorWhiteR, ColorWhiteG, ColorWhiteB, sdl.ALPHA_OPAQUE)
-	renderer.Clear()
-	renderer.SetRenderTarget(nil)
 
 	// Go bindings use byte slices but SDL thinks in terms of uint32
 	screenLen := ScreenWidth * ScreenHeight * 4
@@ -131,29 +146,69 @@ func NewSDL(zoomFactor uint, vSync bool) *SDL {
 		image.Point{ScreenWidth * int(zoomFactor), ScreenHeight * int(zoomFactor)},
 	}
 
-	// Create UI with actual screen size.
-	ui := NewUI(renderer, zoomFactor)
+	// Create UI with actual screen size. Run without overlay if that fails.
+	ui, err := NewUI(renderer, UIFont, zoomFactor)
+	if err != nil {
+		log.Warningf("UI overlay disabled: %v", err)
+	}
 
 	sdl := SDL{
 		UI:         ui,
 		Palette:    DefaultPalette,
+		window:     window,
 		renderer:   renderer,
 		texture:    texture,
 		blank:      blank,
 		buffer:     buffer,
+		pixels:     make([]uint8, ScreenWidth*ScreenHeight),
+		blankFrame: make([]uint8, ScreenWidth*ScreenHeight),
+		Blank:      SolidBlank(0),
 		zoom:       int(zoomFactor),
 		screenRect: screenRect,
+		viewport:   sdlRect(screenRect),
 		gif:        NewGIF(zoomFactor),
 	}
 
-	// Init texture and trigger stuff usually happening at VBlank.
-	sdl.VBlank() // XXX: is this needed?
+	// Init texture and trigger stuff usually happening at VBlank. We're
+	// already in the main thread, so don't go through sdl.Do().
+	sdl.vblank() // XXX: is this needed?
 
 	return &sdl
 }
 
-// Close frees all resources created by SDL.
+// SetPalette sets the colors for shades 0 to 3, on screen and in GIF files.
+func (s *SDL) SetPalette(palette color.Palette) {
+	s.Palette = palette
+	s.gif.SetPalette(palette)
+	s.blanked = false
+}
+
+// SetFlip mirrors output on screen, in screenshots and in GIF files.
+func (s *SDL) SetFlip(flip Flip) {
+	s.flip = flip
+	s.gif.Flip = flip
+	s.blanked = false
+}
+
+// SetBlank sets what's shown while the LCD is off, on screen and in GIF files.
+func (s *SDL) SetBlank(blank BlankFrame) {
+	s.Blank = blank
+	s.gif.Blank = blank
+	s.blanked = false
+}
+
+// Close writes the GIF being recorded, if any, and frees all resources created
+// by SDL in the main thread.
 func (s *SDL) Close() {
+	sdl.Do(s.close)
+}
+
+// Actual cleanup, to be executed in the main thread.
+func (s *SDL) close() {
+	if s.gif.IsOpen() {
+		s.gif.Close()
+	}
+
 	s.texture.Destroy()
 	s.blank.Destroy()
 	s.renderer.Destroy()
@@ -170,10 +225,12 @@ func (s *SDL) Enabled() bool {
 	return s.enabled
 }
 
-// Disable turns off the display. A disabled GB screen will be drawn at VBlank time.
+// Disable turns off the display. A disabled GB screen will be drawn at VBlank
+// time, generated once for the whole time the display is off.
 func (s *SDL) Disable() {
 	s.offset = 0
 	s.enabled = false
+	s.blanked = false
 }
 
 // Write adds a new pixel (a mere index into a palette) to the texture buffer.
@@ -183,6 +240,7 @@ func (s *SDL) Write(colorIndex uint8) {
 		s.buffer[s.offset+1] = s.Palette[colorIndex].(color.RGBA).G
 		s.buffer[s.offset+2] = s.Palette[colorIndex].(color.RGBA).B
 		s.buffer[s.offset+3] = s.Palette[colorIndex].(color.RGBA).A
+		s.pixels[s.offset/4] = colorIndex
 		s.offset += 4
 
 		if s.gif.IsOpen() {
@@ -196,18 +254,39 @@ func (s *SDL) Write(colorIndex uint8) {
 func (s *SDL) HBlank() {}
 
 // VBlank is called when the PPU reaches VBlank state. At this point, our SDL
-// buffer should be ready to display.
+// buffer should be ready to display. Rendering is done in the main thread.
 func (s *SDL) VBlank() {
+	sdl.Do(s.vblank)
+}
+
+// Actual VBlank processing, to be executed in the main thread.
+func (s *SDL) vblank() {
+	// Clear letterboxing borders, if any.
+	s.renderer.SetDrawColor(0, 0, 0, sdl.ALPHA_OPAQUE)
+	s.renderer.Clear()
+
 	if s.enabled {
+		FlipFrame(s.buffer, 4, s.flip)
 		s.texture.Update(nil, s.buffer, ScreenWidth*4)
-		s.renderer.Copy(s.texture, nil, nil)
+		s.renderer.Copy(s.texture, nil, &s.viewport)
 
 		if s.offset != ScreenWidth*ScreenHeight*4 {
 			log.Warning("MISSING PIXELS!")
 		}
 		s.offset = 0
 	} else {
-		s.renderer.Copy(s.blank, nil, nil)
+		if !s.blanked {
+			s.Blank(s.blankFrame, s.pixels)
+			rgba := make([]byte, len(s.blankFrame)*4)
+			for i, colorIndex := range s.blankFrame {
+				c := s.Palette[colorIndex].(color.RGBA)
+				rgba[i*4+0], rgba[i*4+1], rgba[i*4+2], rgba[i*4+3] = c.R, c.G, c.B, c.A
+			}
+			FlipFrame(rgba, 4, s.flip)
+			s.blank.Update(nil, rgba, ScreenWidth*4)
+			s.blanked = true
+		}
+		s.renderer.Copy(s.blank, nil, &s.viewport)
 	}
 
 	// Update GIF frame if recording.
@@ -235,9 +314,9 @@ func (s *SDL) VBlank() {
 	}
 
 	// UI overlay.
-	if s.UI.Enabled {
+	if s.UI.Visible() {
 		//s.UI.texture.SetBlendMode(sdl.BLENDMODE_ADD)
-		s.renderer.Copy(s.UI.texture, nil, nil)
+		s.renderer.Copy(s.UI.texture, nil, &s.viewport)
 	}
 
 	s.renderer.Present()
@@ -264,21 +343,13 @@ func (s *SDL) VBlank() {
 			}
 		}
 
-		f, err := os.Create(path)
-
-		if err != nil {
-			log.Warningf("creating screenshot file failed: %v", err)
-			return
-		}
-		defer f.Close()
-
-		if err := png.Encode(f, img); err != nil {
+		if err := SaveImage(path, img, s.JPEGQuality); err != nil {
 			log.Warningf("saving screenshot failed: %v", err)
 			return
 		}
 
-		s.Message("Screenshot saved", 2)
-		fmt.Printf("screenshot saved to %s\n", path)
+		s.Message("Screenshot saved", 2, PriorityInfo)
+		log.Infof("screenshot saved to %s", path)
 	}
 }
 
@@ -287,6 +358,55 @@ func (s *SDL) Dump() {
 	ioutil.WriteFile("lcd-buffer-dump.bin", s.buffer, 0644)
 }
 
+// ToggleUI hides or shows the UI overlay (as part of the Display interface).
+func (s *SDL) ToggleUI() {
+	s.UI.Toggle()
+}
+
+// ToggleFullscreen switches the window between windowed and desktop-fullscreen
+// modes, then recomputes where the screen should be drawn so that it keeps its
+// aspect ratio (as part of the Display interface).
+func (s *SDL) ToggleFullscreen() {
+	var flags uint32
+	if !s.fullscreen {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	if err := s.window.SetFullscreen(flags); err != nil {
+		log.Warningf("can't toggle fullscreen: %s", err)
+		return
+	}
+	s.fullscreen = !s.fullscreen
+	s.Resize()
+}
+
+// Resize updates the screen viewport to fit the current renderer output size
+// after the window was resized (as part of the Display interface). Fullscreen
+// mode sticks to integer scaling, while a window is filled as much as the
+// aspect ratio allows. The UI overlay is drawn over the same viewport.
+// Must be called from the main thread, i.e. while handling SDL events.
+func (s *SDL) Resize() {
+	w, h, err := s.renderer.GetOutputSize()
+	if err != nil {
+		log.Warningf("can't get renderer output size: %s", err)
+		return
+	}
+	if s.fullscreen {
+		s.viewport = sdlRect(Viewport(int(w), int(h)))
+	} else {
+		s.viewport = sdlRect(FitViewport(int(w), int(h)))
+	}
+}
+
+// Convert an image.Rectangle to its SDL equivalent.
+func sdlRect(r image.Rectangle) sdl.Rect {
+	return sdl.Rect{
+		X: int32(r.Min.X),
+		Y: int32(r.Min.Y),
+		W: int32(r.Dx()),
+		H: int32(r.Dy()),
+	}
+}
+
 // Screenshot will make the display dump the next frame to file.
 func (s *SDL) Screenshot(filename string) {
 	s.screenshotPath = filename
@@ -309,3 +429,18 @@ func (s *SDL) Record(filename string) {
 func (s *SDL) StopRecord() {
 	s.stopRecording = true
 }
+
+// SetGhosting blends each frame with the given fraction of the previous ones
+// in GIF recordings (see Ghosting), or disables ghosting if 0.
+func (s *SDL) SetGhosting(decay float64) {
+	s.gif.Ghosting = nil
+	if decay > 0 {
+		s.gif.Ghosting = NewGhosting(decay)
+	}
+}
+
+// SetRecordSkip sets how many frames are dropped at the start of each GIF
+// recording (see GIF.Skip).
+func (s *SDL) SetRecordSkip(frames uint) {
+	s.gif.Skip = frames
+}
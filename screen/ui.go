@@ -1,8 +1,12 @@
+//go:build !js
+// +build !js
+
 package screen
 
 import (
 	"fmt"
-	"os"
+	"image/color"
+	"strings"
 	"time"
 
 	"github.com/veandco/go-sdl2/sdl"
@@ -12,19 +16,25 @@ import (
 const (
 	// UIMargin is the space in pixels between screen border and UI text.
 	UIMargin = 2
+
+	// UIFont is the path to the default TTF font used for the UI overlay.
+	UIFont = "assets/ui.ttf"
 )
 
 // UI structure to manage user commands and overlay.
 type UI struct {
 	Enabled bool
+	hidden  bool // Set by Toggle to keep the overlay off regardless of text
 
-	message string // Temporary test on timer
-	text    string // Permanent text
+	message  string   // Temporary test on timer
+	priority Priority // Current message's priority
+	queue    []uiMessage
+	text     string // Permanent text
 
 	texture  *sdl.Texture
 	renderer *sdl.Renderer
 
-	font     *ttf.Font
+	font     *ttf.Font // Nil to use the built-in font (see RenderBitmapText)
 	fontZoom uint
 
 	fg sdl.Color // TODO: make it configurable
@@ -33,13 +43,11 @@ type UI struct {
 	msgTimer *time.Timer
 }
 
-// Return a UI instance given a renderer to create the overlay texture.
-func NewUI(renderer *sdl.Renderer, zoom uint) *UI {
-	font, err := ttf.OpenFont("assets/ui.ttf", int(8*zoom)) // FIXME: make zoom configurable
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open font: %s\n", err)
-		return nil // TODO: result, err
-	}
+// NewUI returns a UI instance given a renderer to create the overlay texture
+// and the path to a TTF font file to render text with. If the font can't be
+// loaded, text is rendered with a built-in bitmap font instead.
+func NewUI(renderer *sdl.Renderer, fontPath string, zoom uint) (*UI, error) {
+	font := openUIFont(fontPath, zoom)
 
 	texture, err := renderer.CreateTexture(
 		sdl.PIXELFORMAT_RGBA8888,
@@ -47,9 +55,10 @@ func NewUI(renderer *sdl.Renderer, zoom uint) *UI {
 		ScreenWidth*int32(zoom),
 		ScreenHeight*int32(zoom))
 	if err != nil {
-		font.Close()
-		fmt.Fprintf(os.Stderr, "Failed to create UI texture: %s\n", err)
-		return nil // TODO: result, err
+		if font != nil {
+			font.Close()
+		}
+		return nil, fmt.Errorf("failed to create UI texture: %v", err)
 	}
 
 	// Scale font up with screen size.
@@ -67,17 +76,51 @@ func NewUI(renderer *sdl.Renderer, zoom uint) *UI {
 		fg:       sdl.Color{R: 0, G: 0, B: 0, A: 0xff},
 		bg:       sdl.Color{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
 	}
-	return &ui
+	return &ui, nil
 }
 
+// Returns the TTF font at the given path sized for the zoom factor, or nil if
+// it can't be opened.
+func openUIFont(fontPath string, zoom uint) *ttf.Font {
+	font, err := ttf.OpenFont(fontPath, int(8*zoom)) // FIXME: make zoom configurable
+	if err != nil {
+		log.Warningf("failed to open UI font %s (%v), using built-in font", fontPath, err)
+		return nil
+	}
+	return font
+}
+
+// A nil UI is valid and does nothing, so the emulator can run without overlay
+// if the UI couldn't be created.
+
 // Enable turns on the UI overlay.
 func (u *UI) Enable() {
-	u.Enabled = true
+	if u != nil {
+		u.Enabled = true
+	}
 }
 
 // Disable turns off the UI overlay.
 func (u *UI) Disable() {
-	u.Enabled = false
+	if u != nil {
+		u.Enabled = false
+	}
+}
+
+// Visible returns whether the UI overlay should be drawn.
+func (u *UI) Visible() bool {
+	return u != nil && u.Enabled
+}
+
+// Toggle hides the UI overlay if it's visible, or shows it again otherwise.
+// Permanent text and messages are kept as they are while hidden, so they are
+// restored as soon as the overlay is shown again.
+func (u *UI) Toggle() {
+	if u == nil {
+		return
+	}
+	u.hidden = !u.hidden
+	u.Enabled = !u.hidden && (u.text != "" || u.message != "")
 }
 
 // Refresh UI texture with permanent text and current message (if any).
@@ -87,10 +130,14 @@ func (u *UI) repaint() {
 	u.renderer.SetDrawColor(0, 0, 0, 0)
 	u.renderer.Clear()
 
+	// Permanent text may span several lines, drawn bottom-up.
 	row := 1
 	if u.text != "" {
-		u.renderText(u.text, row)
-		row++
+		lines := strings.Split(u.text, "\n")
+		for i := len(lines) - 1; i >= 0; i-- {
+			u.renderText(lines[i], row)
+			row++
+		}
 	}
 
 	// TODO: stack messages
@@ -98,14 +145,19 @@ func (u *UI) repaint() {
 		u.renderText(u.message, row)
 	}
 
-	// Disable if there's nothing to display.
-	u.Enabled = u.text != "" || u.message != ""
+	// Disable if there's nothing to display (or if the user hid the UI).
+	u.Enabled = !u.hidden && (u.text != "" || u.message != "")
 
 	u.renderer.SetRenderTarget(nil)
 }
 
 // Refresh UI texture with permanent text and current message (if any).
 func (u *UI) renderText(text string, row int) {
+	if u.font == nil {
+		u.renderBitmapText(text, row)
+		return
+	}
+
 	// Instantiate text with an outline effect. There's probably an easier way.
 	u.font.SetOutline(int(u.fontZoom))
 	outline, _ := u.font.RenderUTF8Solid(text, u.bg)
@@ -123,31 +175,102 @@ func (u *UI) renderText(text string, row int) {
 	u.renderer.Copy(msgTexture, nil, &sdl.Rect{X: UIMargin + int32(u.fontZoom), Y: y, W: msg.W, H: msg.H})
 }
 
-// Set permanent text (useful for persistent UI). Call with empty string to
-// clear.
+// Set permanent text (useful for persistent UI), possibly on several lines.
+// Call with empty string to clear.
 func (u *UI) Text(text string) {
+	if u == nil {
+		return
+	}
 	u.text = text
 	u.repaint()
 }
 
-// Clear temporary message and repaint texture.
+// Temporary message waiting for its turn.
+type uiMessage struct {
+	text     string
+	duration time.Duration
+	priority Priority
+}
+
+// Clear temporary message and repaint texture, or show the next one queued.
 func (u *UI) clearMessage() {
-	// Make sure to execute in the UI thread in case we were called from a
-	// timer thread. TODO: sdl.Do()
-	u.message = ""
-	sdl.Do(u.repaint)
+	// Make sure to execute in the UI thread since we're called from a timer
+	// thread.
+	sdl.Do(func() {
+		if next, ok := u.pop(); ok {
+			u.show(next)
+		} else {
+			u.repaint()
+		}
+	})
 }
 
 // Message creates a new UI texture with the given message, enables UI and
-// starts a timer that will hide the UI when it's done. Takes a text string and
-// a duration (in seconds).
-func (u *UI) Message(text string, duration time.Duration) {
+// starts a timer that will hide the UI when it's done. Takes a text string, a
+// duration (in seconds) and a priority. While a message with a higher priority
+// is showing, the new one is queued until it expires.
+func (u *UI) Message(text string, duration time.Duration, priority Priority) {
+	if u == nil {
+		return
+	}
+	m := uiMessage{text, duration, priority}
+	if u.push(m) {
+		u.show(m)
+	}
+}
+
+// Returns true if the message should be shown right away, or queues it if a
+// message with a higher priority is showing.
+func (u *UI) push(m uiMessage) bool {
+	if u.message != "" && m.priority < u.priority {
+		u.queue = append(u.queue, m)
+		return false
+	}
+	u.message, u.priority = m.text, m.priority
+	return true
+}
+
+// Clears the current message and returns the next one queued, if any.
+func (u *UI) pop() (next uiMessage, ok bool) {
+	u.message = ""
+	if len(u.queue) == 0 {
+		return
+	}
+	next, u.queue = u.queue[0], u.queue[1:]
+	u.message, u.priority = next.text, next.priority
+	return next, true
+}
+
+// Repaints the UI with the given (current) message and starts its timer.
+func (u *UI) show(m uiMessage) {
 	// Stop reset timer, a new one will be started.
-	// TODO: stack messages
 	if u.msgTimer != nil {
 		u.msgTimer.Stop()
 	}
-	u.message = text
-	u.msgTimer = time.AfterFunc(time.Second*duration, u.clearMessage)
+	u.msgTimer = time.AfterFunc(time.Second*m.duration, u.clearMessage)
 	u.repaint()
 }
+
+// Same as renderText, using the built-in font.
+func (u *UI) renderBitmapText(text string, row int) {
+	zoom := int32(u.fontZoom)
+	fg := color.RGBA{R: u.fg.R, G: u.fg.G, B: u.fg.B, A: u.fg.A}
+	bg := color.RGBA{R: u.bg.R, G: u.bg.G, B: u.bg.B, A: u.bg.A}
+	img := RenderBitmapText(text, int(zoom), fg, bg)
+	w, h := int32(img.Rect.Dx()), int32(img.Rect.Dy())
+
+	texture, err := u.renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888,
+		sdl.TEXTUREACCESS_STATIC, w, h)
+	if err != nil {
+		log.Warningf("failed to create text texture: %v", err)
+		return
+	}
+	defer texture.Destroy()
+	texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+	texture.Update(nil, img.Pix, img.Stride)
+
+	// Position vertically like TTF text, the outline adds a pixel all around.
+	_, _, _, th, _ := u.texture.Query()
+	y := th - BitmapLineHeight*zoom*int32(row) - UIMargin
+	u.renderer.Copy(texture, nil, &sdl.Rect{X: UIMargin, Y: y - zoom, W: w, H: h})
+}
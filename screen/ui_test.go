@@ -0,0 +1,73 @@
+//go:build !js
+// +build !js
+
+package screen
+
+import "testing"
+
+func TestUIToggle(t *testing.T) {
+	u := UI{Enabled: true, text: "•REC [00:00]"}
+
+	u.Toggle()
+	if u.Enabled {
+		t.Error("UI still enabled after first Toggle()")
+	}
+	if u.text != "•REC [00:00]" {
+		t.Errorf("permanent text lost while hidden, got %q", u.text)
+	}
+
+	u.Toggle()
+	if !u.Enabled {
+		t.Error("UI not enabled after second Toggle()")
+	}
+	if u.text != "•REC [00:00]" {
+		t.Errorf("permanent text not restored, got %q", u.text)
+	}
+}
+
+func TestNewUIBadFont(t *testing.T) {
+	// A missing font falls back to the built-in one.
+	if font := openUIFont("does/not/exist.ttf", 1); font != nil {
+		t.Fatal("openUIFont() returned a font for a missing file")
+	}
+
+	// Running without UI shouldn't crash.
+	var ui *UI
+	ui.Text("text")
+	ui.Toggle()
+	if ui.Visible() {
+		t.Error("nil UI is visible")
+	}
+}
+
+func TestMessagePriority(t *testing.T) {
+	var u UI
+
+	if !u.push(uiMessage{"Save failed", 2, PriorityError}) {
+		t.Fatal("first message not shown")
+	}
+
+	// A lower priority message waits for the error to expire.
+	if u.push(uiMessage{"Screenshot saved", 2, PriorityInfo}) {
+		t.Error("info message shown over an error")
+	}
+	if u.message != "Save failed" {
+		t.Errorf("showing %q, want the error", u.message)
+	}
+
+	next, ok := u.pop()
+	if !ok || next.text != "Screenshot saved" || u.message != "Screenshot saved" {
+		t.Errorf("showing %q after the error expired, want the info message", u.message)
+	}
+
+	// Equal or higher priorities replace the current message right away.
+	if !u.push(uiMessage{"Speed: 2x", 2, PriorityInfo}) || u.message != "Speed: 2x" {
+		t.Error("info message didn't replace another one")
+	}
+	if !u.push(uiMessage{"No ROM", 2, PriorityWarning}) || u.message != "No ROM" {
+		t.Error("warning didn't replace an info message")
+	}
+	if _, ok := u.pop(); ok || u.message != "" {
+		t.Errorf("showing %q with nothing queued", u.message)
+	}
+}
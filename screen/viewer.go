@@ -0,0 +1,124 @@
+//go:build !js
+// +build !js
+
+package screen
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Viewer is a secondary SDL window showing debug images updated every frame,
+// such as VRAM contents. Except for Update and Close, its methods must be
+// called from the main thread (e.g. while handling SDL events).
+type Viewer struct {
+	window   *sdl.Window
+	renderer *sdl.Renderer
+	texture  *sdl.Texture
+	buffer   []byte
+	width    int
+	visible  bool
+}
+
+// NewViewer creates and shows a resizable window for images of the given
+// size, initially scaled by the given zoom factor.
+func NewViewer(title string, width, height int, zoomFactor uint) (*Viewer, error) {
+	window, err := sdl.CreateWindow(title,
+		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		int32(width)*int32(zoomFactor), int32(height)*int32(zoomFactor),
+		sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window: %s", err)
+	}
+
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		window.Destroy()
+		return nil, fmt.Errorf("failed to create renderer: %s", err)
+	}
+
+	// Same pixel format as the main display.
+	texture, err := renderer.CreateTexture(
+		sdl.PIXELFORMAT_ABGR8888,
+		sdl.TEXTUREACCESS_STATIC,
+		int32(width),
+		int32(height))
+	if err != nil {
+		renderer.Destroy()
+		window.Destroy()
+		return nil, fmt.Errorf("failed to create texture: %s", err)
+	}
+
+	v := Viewer{
+		window:   window,
+		renderer: renderer,
+		texture:  texture,
+		buffer:   make([]byte, width*height*4),
+		width:    width,
+		visible:  true,
+	}
+	return &v, nil
+}
+
+// ID returns the SDL window ID, to tell which window events are meant for.
+func (v *Viewer) ID() uint32 {
+	id, _ := v.window.GetID()
+	return id
+}
+
+// Toggle hides or shows the window.
+func (v *Viewer) Toggle() {
+	if v.visible {
+		v.Hide()
+	} else {
+		v.window.Show()
+		v.visible = true
+	}
+}
+
+// Hide hides the window until the next Toggle call.
+func (v *Viewer) Hide() {
+	v.window.Hide()
+	v.visible = false
+}
+
+// Visible returns whether the window is currently shown.
+func (v *Viewer) Visible() bool {
+	return v.visible
+}
+
+// Update displays the given image, which must have the size the viewer was
+// created with. Rendering is done in the main thread.
+func (v *Viewer) Update(img *image.Paletted) {
+	if !v.visible {
+		return
+	}
+
+	for i, colorIndex := range img.Pix {
+		r, g, b, a := img.Palette[colorIndex].RGBA()
+		v.buffer[i*4+0] = uint8(r >> 8)
+		v.buffer[i*4+1] = uint8(g >> 8)
+		v.buffer[i*4+2] = uint8(b >> 8)
+		v.buffer[i*4+3] = uint8(a >> 8)
+	}
+	sdl.Do(v.render)
+}
+
+// Actual rendering, to be executed in the main thread.
+func (v *Viewer) render() {
+	v.texture.Update(nil, v.buffer, v.width*4)
+	v.renderer.Clear()
+	v.renderer.Copy(v.texture, nil, nil)
+	v.renderer.Present()
+}
+
+// Close frees all resources created by SDL in the main thread.
+func (v *Viewer) Close() {
+	sdl.Do(func() {
+		v.texture.Destroy()
+		v.renderer.Destroy()
+		v.window.Destroy()
+	})
+}
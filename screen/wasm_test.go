@@ -0,0 +1,47 @@
+package screen
+
+import (
+	"go/build"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Makes sure the emulation core, options and non-SDL displays and inputs still
+// build for WebAssembly, by walking their imports as seen with GOOS=js
+// GOARCH=wasm.
+func TestCoreWithoutSDL(t *testing.T) {
+	const module = "github.com/lazy-stripes/goholint/"
+
+	ctx := build.Default
+	ctx.GOOS = "js"
+	ctx.GOARCH = "wasm"
+	ctx.CgoEnabled = false
+
+	seen := map[string]bool{}
+	var walk func(dir, from string)
+	walk = func(dir, from string) {
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+
+		pkg, err := ctx.ImportDir(filepath.Join("..", dir), 0)
+		if err != nil {
+			t.Fatalf("%s (imported from %s): %v", dir, from, err)
+		}
+		for _, path := range pkg.Imports {
+			switch {
+			case strings.Contains(path, "go-sdl2"):
+				t.Errorf("%s imports %s", dir, path)
+			case strings.HasPrefix(path, module):
+				walk(strings.TrimPrefix(path, module), dir)
+			}
+		}
+	}
+
+	for _, dir := range []string{"apu", "cpu", "gameboy", "joypad", "memory", "options", "ppu",
+		"screen", "serial", "timer"} {
+		walk(dir, "test")
+	}
+}
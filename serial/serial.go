@@ -1,8 +1,10 @@
 package serial
 
 import (
+	"bytes"
 	"fmt"
 
+	"github.com/lazy-stripes/goholint/interrupts"
 	"github.com/lazy-stripes/goholint/logger"
 )
 
@@ -12,9 +14,36 @@ const (
 	AddrSC = 0xff02
 )
 
+// SC register bits.
+const (
+	SCInternalClock = 1 << 0 // Shift clock (0=External, 1=Internal)
+	SCFastClock     = 1 << 1 // Clock speed (CGB only, 1=Fast)
+	SCTransfer      = 1 << 7 // Transfer start flag (1=In progress)
+)
+
+// Machine ticks per transferred bit using the internal clock, at 8192Hz or
+// 262144Hz with the CGB fast clock.
+const (
+	TicksPerBit     = 512
+	FastTicksPerBit = 16
+)
+
 // Serial registers for game link. Used only for debug for now.
 type Serial struct {
 	SB, SC uint8
+
+	Interrupts *interrupts.Interrupts
+
+	// CGB enables the fast clock setting in SC.
+	CGB bool
+
+	// Set Record to true to keep a copy of every transferred byte in Output.
+	// Useful for test ROMs reporting their results through the serial port.
+	Record bool
+	Output bytes.Buffer
+
+	ticks uint // Ticks since the last bit was shifted
+	bits  uint // Bits shifted so far in the current transfer
 }
 
 // New instantiates a Serial addressable mapping to FF01 and FF02.
@@ -41,14 +70,52 @@ func (s *Serial) Write(addr uint16, value uint8) {
 		s.SB = value
 	} else if addr == AddrSC {
 		s.SC = value
-		if value&(1<<7) != 0 {
+		if value&SCTransfer != 0 {
 			// Print characters for now to print GB ROM test results.
 			if logger.Enabled["serial"] {
 				fmt.Printf("%c", s.SB)
 			}
+			if s.Record {
+				s.Output.WriteByte(s.SB)
+			}
 
-			// For now, always assume no connection.
-			s.SB = 0xff
+			// Bits will be shifted out in Tick().
+			s.ticks = 0
+			s.bits = 0
 		}
 	}
 }
+
+// Tick advances an ongoing transfer using the internal clock by one machine
+// tick, shifting one bit out of SB at the expected rate and requesting an
+// interrupt once all 8 bits are sent. Transfers using an external clock never
+// complete, since there's never anything connected.
+func (s *Serial) Tick() {
+	if s.SC&SCTransfer == 0 || s.SC&SCInternalClock == 0 {
+		return
+	}
+
+	s.ticks++
+	if s.ticks < s.ticksPerBit() {
+		return
+	}
+	s.ticks = 0
+
+	// For now, always assume no connection and shift in 1s.
+	s.SB = s.SB<<1 | 1
+	s.bits++
+	if s.bits == 8 {
+		s.SC &^= SCTransfer
+		if s.Interrupts != nil {
+			s.Interrupts.Request(interrupts.Serial)
+		}
+	}
+}
+
+// Return how many ticks a bit takes to be transferred given current settings.
+func (s *Serial) ticksPerBit() uint {
+	if s.CGB && s.SC&SCFastClock != 0 {
+		return FastTicksPerBit
+	}
+	return TicksPerBit
+}
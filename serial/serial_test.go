@@ -0,0 +1,45 @@
+package serial
+
+import (
+	"testing"
+
+	"github.com/lazy-stripes/goholint/interrupts"
+)
+
+func TestTransferTiming(t *testing.T) {
+	testCases := []struct {
+		cgb   bool
+		sc    uint8
+		ticks int
+	}{
+		{false, 0x81, 8 * TicksPerBit},
+		{false, 0x83, 8 * TicksPerBit}, // No fast clock on DMG.
+		{true, 0x83, 8 * FastTicksPerBit},
+	}
+
+	for _, tc := range testCases {
+		var regIF, regIE uint8
+		s := New()
+		s.CGB = tc.cgb
+		s.Interrupts = interrupts.New(&regIF, &regIE)
+		s.Write(AddrSB, 0x42)
+		s.Write(AddrSC, tc.sc)
+
+		ticks := 0
+		for regIF&interrupts.Serial == 0 && ticks <= 8*TicksPerBit {
+			s.Tick()
+			ticks++
+		}
+
+		if ticks != tc.ticks {
+			t.Errorf("SC=0x%02x (CGB=%t): interrupt after %d ticks, want %d",
+				tc.sc, tc.cgb, ticks, tc.ticks)
+		}
+		if s.Read(AddrSC)&SCTransfer != 0 {
+			t.Errorf("SC=0x%02x: transfer flag still set", tc.sc)
+		}
+		if s.SB != 0xff {
+			t.Errorf("SC=0x%02x: SB=0x%02x after transfer, want 0xff", tc.sc, s.SB)
+		}
+	}
+}
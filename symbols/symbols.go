@@ -0,0 +1,153 @@
+// Package symbols reads symbol files as written by rgbds (rgblink -n) or BGB,
+// mapping banked addresses to labels so that debugging output and breakpoints
+// can use names instead of raw addresses.
+//
+// Each line holds a bank and address in hexadecimal, followed by a label:
+//
+//	; Comments start with a semicolon.
+//	00:0150 Main
+//	01:4000 LevelData.start
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Symbol is a label at an address in a given bank.
+type Symbol struct {
+	Bank  uint16
+	Addr  uint16
+	Label string
+}
+
+// String returns the symbol in the same format as symbol files.
+func (s Symbol) String() string {
+	return fmt.Sprintf("%02x:%04x %s", s.Bank, s.Addr, s.Label)
+}
+
+// Banked returns whether the symbol's address is in a switchable ROM bank, in
+// which case it's only valid while that bank is mapped.
+func (s Symbol) Banked() bool {
+	return s.Addr >= 0x4000 && s.Addr <= 0x7fff
+}
+
+// Table holds symbols by label and by location.
+type Table struct {
+	labels    map[string]Symbol
+	locations map[uint32]string // Labels by bank and address
+}
+
+// Returns a key for looking up labels by location.
+func location(bank, addr uint16) uint32 {
+	return uint32(bank)<<16 | uint32(addr)
+}
+
+// Load reads a symbol file.
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads symbols from a symbol file's contents. When a label appears more
+// than once, the first one wins.
+func Parse(r io.Reader) (*Table, error) {
+	t := &Table{
+		labels:    make(map[string]Symbol),
+		locations: make(map[uint32]string),
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, ';'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected bank:address label, got %q",
+				line, scanner.Text())
+		}
+		sym, err := parseSymbol(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if _, ok := t.labels[sym.Label]; !ok {
+			t.labels[sym.Label] = sym
+		}
+		if _, ok := t.locations[location(sym.Bank, sym.Addr)]; !ok {
+			t.locations[location(sym.Bank, sym.Addr)] = sym.Label
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Parses a bank:address pair and label.
+func parseSymbol(where, label string) (sym Symbol, err error) {
+	parts := strings.Split(where, ":")
+	if len(parts) != 2 {
+		return sym, fmt.Errorf("invalid location %q, expected bank:address", where)
+	}
+	bank, err := strconv.ParseUint(parts[0], 16, 16)
+	if err != nil {
+		return sym, fmt.Errorf("invalid bank %q", parts[0])
+	}
+	addr, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return sym, fmt.Errorf("invalid address %q", parts[1])
+	}
+	return Symbol{Bank: uint16(bank), Addr: uint16(addr), Label: label}, nil
+}
+
+// Len returns the number of labels in the table.
+func (t *Table) Len() int {
+	return len(t.labels)
+}
+
+// Lookup returns the symbol with the given label, if any.
+func (t *Table) Lookup(label string) (Symbol, bool) {
+	sym, ok := t.labels[label]
+	return sym, ok
+}
+
+// Label returns the label at the given address, looking in the given ROM bank
+// for switchable bank addresses and in bank 0 for everything else.
+func (t *Table) Label(bank, addr uint16) (string, bool) {
+	if addr < 0x4000 || addr > 0x7fff {
+		bank = 0
+	}
+	label, ok := t.locations[location(bank, addr)]
+	return label, ok
+}
+
+// Resolve returns the symbol for a breakpoint given either as a label, as a
+// bank:address pair, or as an address (decimal, or hexadecimal with a 0x
+// prefix) in the given current bank.
+func (t *Table) Resolve(name string, bank uint16) (Symbol, error) {
+	if t != nil {
+		if sym, ok := t.Lookup(name); ok {
+			return sym, nil
+		}
+	}
+	if strings.Contains(name, ":") {
+		return parseSymbol(name, name)
+	}
+	addr, err := strconv.ParseUint(name, 0, 16)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("unknown label or address %q", name)
+	}
+	return Symbol{Bank: bank, Addr: uint16(addr), Label: name}, nil
+}
@@ -0,0 +1,64 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `; File generated by rgblink
+00:0100 EntryPoint
+00:0150 Main
+00:0150 Main.duplicate
+01:4000 LevelData
+02:4000 Music   ; Same address, other bank
+02:4abc Music.play
+`
+
+func TestParse(t *testing.T) {
+	table, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 6 {
+		t.Errorf("parsed %d labels, want 6", table.Len())
+	}
+
+	sym, ok := table.Lookup("Music.play")
+	if !ok || sym.Bank != 2 || sym.Addr != 0x4abc || !sym.Banked() {
+		t.Errorf("Music.play resolved to %v (%t), want banked 02:4abc", sym, ok)
+	}
+
+	// Labels by location, in the current bank if switchable.
+	for _, c := range []struct {
+		bank, addr uint16
+		label      string
+	}{
+		{1, 0x4000, "LevelData"},
+		{2, 0x4000, "Music"},
+		{5, 0x0150, "Main"},
+		{3, 0x4000, ""},
+	} {
+		if label, _ := table.Label(c.bank, c.addr); label != c.label {
+			t.Errorf("label at %02x:%04x is %q, want %q", c.bank, c.addr, label,
+				c.label)
+		}
+	}
+
+	// Breakpoints can also be set by address.
+	for name, want := range map[string]Symbol{
+		"Main":    {0, 0x0150, "Main"},
+		"02:4001": {2, 0x4001, "02:4001"},
+		"0x4001":  {3, 0x4001, "0x4001"},
+	} {
+		if sym, err := table.Resolve(name, 3); err != nil || sym != want {
+			t.Errorf("%s resolved to %v (%v), want %v", name, sym, err, want)
+		}
+	}
+	if _, err := table.Resolve("Nowhere", 0); err == nil {
+		t.Error("no error for an unknown label")
+	}
+
+	if _, err := Parse(strings.NewReader("0150 Main\n")); err == nil {
+		t.Error("no error for a symbol without a bank")
+	}
+}
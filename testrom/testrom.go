@@ -0,0 +1,157 @@
+// Package testrom runs suites of test ROMs headless and checks their results,
+// either from what they send over the serial port (as Blargg's tests do) or
+// from a hash of their final frame (see screen.Headless.Hash).
+package testrom
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/lazy-stripes/goholint/gameboy"
+	"github.com/lazy-stripes/goholint/options"
+
+	"gopkg.in/ini.v1"
+)
+
+// DefaultCycles is the cycle budget for test cases that don't set one, i.e.
+// about a minute of emulated time.
+const DefaultCycles = 60 * 60 * gameboy.FrameTicks
+
+// Serial output that ends a test early as a failure, in Blargg's tests.
+var failedOutput = []byte("Failed")
+
+// Case describes a test ROM and its expected results.
+type Case struct {
+	Name   string // Name of the test, its ROM path as given in the suite file
+	ROM    string // Path to the ROM file
+	Serial string // Expected serial output, if any (as a substring)
+	Hash   uint64 // Expected final frame hash, 0 if not checked
+	Cycles uint   // Machine ticks to run the ROM for at most
+}
+
+// Result of running a test case.
+type Result struct {
+	Case
+	Passed  bool
+	Skipped bool   // The ROM file is missing
+	Output  string // Serial output
+	Hash    uint64 // Final frame hash
+	Cycles  uint   // Machine ticks actually run
+}
+
+// LoadSuite reads a suite file in INI format, where each section is named
+// after a ROM path (relative to the suite file) and holds the expected
+// results for that ROM:
+//
+//	[cpu_instrs/individual/01-special.gb]
+//	serial = Passed   # Expected serial output
+//	hash   = 0123456789abcdef # Expected final frame hash
+//	cycles = 100000000 # Budget in machine ticks (see DefaultCycles)
+//
+// At least one of serial or hash must be set.
+func LoadSuite(path string) ([]Case, error) {
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []Case
+	dir := filepath.Dir(path)
+	for _, section := range cfg.Sections() {
+		if section.Name() == ini.DefaultSection {
+			continue
+		}
+
+		c := Case{
+			Name:   section.Name(),
+			ROM:    filepath.Join(dir, section.Name()),
+			Serial: section.Key("serial").String(),
+			Cycles: section.Key("cycles").MustUint(DefaultCycles),
+		}
+		if hash := section.Key("hash").String(); hash != "" {
+			c.Hash, err = strconv.ParseUint(hash, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid hash %s", c.Name, hash)
+			}
+		}
+		if c.Serial == "" && c.Hash == 0 {
+			return nil, fmt.Errorf("%s: no expected serial output or hash", c.Name)
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Run executes a test case until its expected serial output is seen, the
+// ROM reports a failure, or its cycle budget runs out. The final frame hash
+// is then checked if needed.
+func Run(c Case) (res Result) {
+	res.Case = c
+	if _, err := os.Stat(c.ROM); err != nil {
+		res.Skipped = true
+		return
+	}
+
+	// VRAM starts out with random values, which would show in frame hashes
+	// unless they're the same for every run. No shutdown, so that saves next
+	// to test ROMs are left alone.
+	g, display := gameboy.NewHeadless(&options.Options{ROMPath: c.ROM, FastBoot: true, Seed: 1})
+
+	serial := []byte(c.Serial)
+	for res.Cycles < c.Cycles {
+		g.Tick()
+		res.Cycles++
+
+		// Only serial checks can end a test early.
+		if c.Hash == 0 && res.Cycles%gameboy.FrameTicks == 0 {
+			output := g.Serial.Output.Bytes()
+			if bytes.Contains(output, serial) || bytes.Contains(output, failedOutput) {
+				break
+			}
+		}
+	}
+
+	res.Output = g.Serial.Output.String()
+	res.Hash = display.Hash()
+	res.Passed = bytes.Contains(g.Serial.Output.Bytes(), serial) &&
+		(c.Hash == 0 || res.Hash == c.Hash)
+	return
+}
+
+// RunSuite runs all test cases in the given suite file (see LoadSuite).
+func RunSuite(path string) ([]Result, error) {
+	cases, err := LoadSuite(path)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(cases))
+	for i, c := range cases {
+		results[i] = Run(c)
+	}
+	return results, nil
+}
+
+// Report writes a table of test results to the given writer.
+func Report(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROM\tResult\tCycles\tFrame hash")
+	passed := 0
+	for _, res := range results {
+		status := "FAIL"
+		switch {
+		case res.Skipped:
+			status = "skipped"
+		case res.Passed:
+			status = "pass"
+			passed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%016x\n", res.Name, status, res.Cycles, res.Hash)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d/%d passed\n", passed, len(results))
+}
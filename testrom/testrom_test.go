@@ -0,0 +1,137 @@
+package testrom
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lazy-stripes/goholint/memory"
+)
+
+// Path to the suite of test ROMs shipped with (or dropped in) the repository.
+const suitePath = "../bin/tests/suite.ini"
+
+// Writes a ROM-only cartridge sending the given text over the serial port to
+// the given folder, and returns its path.
+func writeSerialROM(t *testing.T, dir, text string) string {
+	rom := make([]byte, 0x8000)
+	copy(rom[0x100:], []byte{0xc3, 0x50, 0x01}) // JP $0150
+
+	code := rom[0x150:0x150]
+	for _, c := range []byte(text) {
+		code = append(code,
+			0x3e, c, // LD A,c
+			0xe0, 0x01, // LDH [SB],A
+			0x3e, 0x81, // LD A,$81
+			0xe0, 0x02, // LDH [SC],A
+		)
+	}
+	code = append(code, 0x18, 0xfe) // JR -2
+	rom[memory.AddrCGBFlag] = 0
+
+	path := filepath.Join(dir, strings.ToLower(text)+".gb")
+	if err := ioutil.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunSerial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passed := Run(Case{ROM: writeSerialROM(t, dir, "Passed"), Serial: "Passed", Cycles: DefaultCycles})
+	if !passed.Passed || passed.Output != "Passed" {
+		t.Errorf("passing ROM failed with output %q", passed.Output)
+	}
+	if passed.Cycles > 2*70224 {
+		t.Errorf("passing ROM ran for %d cycles, should stop early", passed.Cycles)
+	}
+
+	failed := Run(Case{ROM: writeSerialROM(t, dir, "Failed"), Serial: "Passed", Cycles: DefaultCycles})
+	if failed.Passed || failed.Cycles > 2*70224 {
+		t.Errorf("failing ROM passed=%t after %d cycles", failed.Passed, failed.Cycles)
+	}
+
+	missing := Run(Case{ROM: filepath.Join(dir, "missing.gb"), Serial: "Passed"})
+	if !missing.Skipped {
+		t.Error("missing ROM not skipped")
+	}
+
+	var report bytes.Buffer
+	Report(&report, []Result{passed, failed, missing})
+	if !strings.HasSuffix(report.String(), "1/3 passed\n") {
+		t.Errorf("unexpected report:\n%s", report.String())
+	}
+}
+
+func TestLoadSuite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goholint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "suite.ini")
+	content := "[a/test.gb]\nserial = Passed\n\n[b/test.gb]\nhash = 00000000000000ff\ncycles = 1000\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadSuite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Case{
+		{Name: "a/test.gb", ROM: filepath.Join(dir, "a/test.gb"), Serial: "Passed", Cycles: DefaultCycles},
+		{Name: "b/test.gb", ROM: filepath.Join(dir, "b/test.gb"), Hash: 0xff, Cycles: 1000},
+	}
+	if len(cases) != len(want) {
+		t.Fatalf("loaded %d cases, want %d", len(cases), len(want))
+	}
+	for i := range want {
+		if cases[i] != want[i] {
+			t.Errorf("case %d is %+v, want %+v", i, cases[i], want[i])
+		}
+	}
+
+	if err := ioutil.WriteFile(path, []byte("[c/test.gb]\ncycles = 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSuite(path); err == nil {
+		t.Error("no error for case without expected results")
+	}
+}
+
+// TestSuite runs every ROM in the repository's suite file as a subtest.
+func TestSuite(t *testing.T) {
+	cases, err := LoadSuite(suitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results []Result
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			res := Run(c)
+			results = append(results, res)
+			switch {
+			case res.Skipped:
+				t.Skipf("%s not found", c.ROM)
+			case !res.Passed:
+				t.Errorf("failed after %d cycles (frame hash %016x, serial output %q)",
+					res.Cycles, res.Hash, res.Output)
+			}
+		})
+	}
+
+	var report bytes.Buffer
+	Report(&report, results)
+	t.Logf("\n%s", report.String())
+}